@@ -0,0 +1,174 @@
+// Package audit records security-relevant events, such as authentication and
+// authorization decisions, separately from diagnostic logs. Events follow a
+// fixed schema, are written to one or more Sinks and are hash-chained, so that
+// removing, reordering or modifying a recorded event can be detected with
+// Verify.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Logger records audit events.
+//
+// Example:
+//
+//	ap, ok := api.(orchestrator.AuditProvider)
+//	if !ok {
+//		return false
+//	}
+//	auditor, err := ap.Audit()
+//	if err != nil {
+//		return false
+//	}
+//	_ = auditor.Record(req.Context(), audit.Event{
+//		Action:   "authz.decision",
+//		Actor:    audit.Actor{ID: email, IP: req.RemoteAddr},
+//		Resource: audit.Resource{Type: "url", ID: req.URL.Path},
+//		Decision: audit.DecisionAllow,
+//		Reason:   "policy " + policyID,
+//	})
+type Logger interface {
+	// Record records an event. The ID, Time and chain fields of the event are set
+	// by the Logger. An error is returned if the Decision of the event is not one
+	// of the defined decisions, or if the event could not be written to every
+	// Sink.
+	Record(ctx context.Context, e Event) error
+}
+
+// Decision is the outcome of the audited action.
+type Decision int
+
+const (
+	// DecisionAllow records that the action was permitted or succeeded.
+	DecisionAllow Decision = iota + 1
+	// DecisionDeny records that the action was refused or failed.
+	DecisionDeny
+	// DecisionError records that no decision could be made because of an error.
+	DecisionError
+)
+
+// String returns the name of the decision.
+func (d Decision) String() string {
+	switch d {
+	case DecisionAllow:
+		return "allow"
+	case DecisionDeny:
+		return "deny"
+	case DecisionError:
+		return "error"
+	default:
+		return fmt.Sprintf("Decision(%d)", int(d))
+	}
+}
+
+// MarshalText encodes the decision as its name. An error is returned for a
+// decision that is not one of the defined decisions, since it could not be
+// decoded again.
+func (d Decision) MarshalText() ([]byte, error) {
+	if !d.valid() {
+		return nil, fmt.Errorf("unknown decision '%s'", d)
+	}
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a decision from its name.
+func (d *Decision) UnmarshalText(text []byte) error {
+	for _, v := range []Decision{DecisionAllow, DecisionDeny, DecisionError} {
+		if string(text) == v.String() {
+			*d = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown decision '%s'", text)
+}
+
+func (d Decision) valid() bool {
+	return d >= DecisionAllow && d <= DecisionError
+}
+
+// Actor identifies who performed the audited action.
+type Actor struct {
+	// ID identifies the user or client, e.g. an email address or subject.
+	ID string `json:"id,omitempty"`
+
+	// IdentityProvider is the name of the identity provider that authenticated
+	// the actor, if any.
+	IdentityProvider string `json:"idp,omitempty"`
+
+	// IP is the network address the actor connected from.
+	IP string `json:"ip,omitempty"`
+}
+
+// Resource identifies what the audited action was performed on.
+type Resource struct {
+	// Type is the kind of resource, e.g. "app" or "url".
+	Type string `json:"type,omitempty"`
+
+	// ID identifies the resource, e.g. an app name or a URL path.
+	ID string `json:"id,omitempty"`
+}
+
+// Event is an audit event.
+type Event struct {
+	// ID uniquely identifies the event. Set by the Logger.
+	ID string `json:"id"`
+
+	// Time is the time the event was recorded. Set by the Logger.
+	Time time.Time `json:"time"`
+
+	// Action is the audited action, e.g. "authn.login" or "authz.decision".
+	Action string `json:"action"`
+
+	// Actor performed the action.
+	Actor Actor `json:"actor"`
+
+	// Resource the action was performed on.
+	Resource Resource `json:"resource"`
+
+	// Decision is the outcome of the action.
+	Decision Decision `json:"decision"`
+
+	// Reason explains the decision, e.g. the policy that determined it.
+	Reason string `json:"reason,omitempty"`
+
+	// CorrelationID correlates the event with other events and logs, e.g. a
+	// request or trace ID.
+	CorrelationID string `json:"correlationId,omitempty"`
+
+	// SessionID identifies the session of the actor, if any.
+	SessionID string `json:"sessionId,omitempty"`
+
+	// Attributes are additional details of the event.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// Sequence is the position of the event in its chain, starting at 1. Set by
+	// the Logger.
+	Sequence uint64 `json:"seq"`
+
+	// PrevHash is the Hash of the previous event in the chain, or empty for the
+	// first event. Set by the Logger.
+	PrevHash string `json:"prevHash,omitempty"`
+
+	// Hash is the hex-encoded hash of the event, covering all other fields
+	// including PrevHash. Set by the Logger.
+	Hash string `json:"hash"`
+}
+
+// Sink writes audit events to a destination.
+type Sink interface {
+	// Write writes an event. Events are written one at a time, in chain order.
+	Write(e Event) error
+
+	// Close releases the resources held by the Sink.
+	Close() error
+}
+
+// encode returns the canonical encoding of e used to compute its hash.
+func encode(e Event) ([]byte, error) {
+	e.Hash = ""
+	return json.Marshal(e)
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCEFSink(t *testing.T) {
+	var b strings.Builder
+	sink := NewCEFSink(&b, WithDevice("Acme|Corp", `Gate\way`, "2.1"))
+	e := sinkEvent()
+	e.Action = "login|mfa"
+	if err := sink.Write(e); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	out := b.String()
+	header := `CEF:0|Acme\|Corp|Gate\\way|2.1|login\|mfa|login\|mfa deny|6|`
+	if !strings.HasPrefix(out, header) {
+		t.Errorf("line = %q, want prefix %q", out, header)
+	}
+	if strings.Count(out, "\n") != 1 || !strings.HasSuffix(out, "\n") {
+		t.Errorf("line = %q, want a single line", out)
+	}
+	for _, want := range []string{
+		"rt=1767323045000 ",
+		"externalId=evt-1 ",
+		"act=login|mfa ",
+		"outcome=deny ",
+		`reason=bad "password" ] \\ \=|x\nnext `,
+		"suser=alice@example.com ",
+		"src=192.0.2.1 ",
+		"cs1Label=resource cs1=url:/admin ",
+		"cs4Label=hash cs4=h2 ",
+		"cs5Label=prevHash cs5=h1 ",
+		`cs6Label=attributes cs6={"policy":"p1"} `,
+		"cn1Label=sequence cn1=7\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("line = %q, missing %q", out, want)
+		}
+	}
+	if strings.Contains(out, "cs2") || strings.Contains(out, "cs3") {
+		t.Errorf("line = %q, contains empty custom strings", out)
+	}
+}
@@ -0,0 +1,192 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+	"time"
+)
+
+// ErrTampered is returned by Verify when a chain of events has been modified.
+var ErrTampered = errors.New("audit chain has been tampered with")
+
+// Options are the options used to configure a Logger created with New.
+type Options struct {
+	// Key is used to compute the hash of events with HMAC-SHA256. Without a key,
+	// events are hashed with SHA-256, which detects accidental modification and
+	// removal of events but not an attacker who recomputes the chain.
+	Key []byte
+
+	// Head is the last event of an existing chain to continue, e.g. the last
+	// event read from a JSON lines file written before a restart.
+	Head *Event
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Option is an option used to configure a Logger created with New.
+type Option func(*Options)
+
+// WithKey configures the key used to compute the hash of events with
+// HMAC-SHA256. The same key must be passed to Verify.
+func WithKey(key []byte) Option {
+	return func(o *Options) {
+		o.Key = key
+	}
+}
+
+// WithHead configures the last event of an existing chain to continue.
+func WithHead(head Event) Option {
+	return func(o *Options) {
+		o.Head = &head
+	}
+}
+
+// WithClock configures the function used to determine the current time.
+func WithClock(now func() time.Time) Option {
+	return func(o *Options) {
+		o.Now = now
+	}
+}
+
+// New creates a Logger writing hash-chained events to the given sinks. Events
+// are written to all sinks even if writing to one of them fails.
+//
+// The chain only advances once an event has been written to at least one sink.
+// If writing to every sink fails, the next event takes the place of the failed
+// one, so the chain written to the sinks remains unbroken. If writing fails for
+// some sinks only, the chain advances and the failed event is missing from the
+// failing sinks, where Verify reports it as a gap.
+//
+// Example:
+//
+//	sink, err := audit.OpenJSONFile("/var/log/maverics/audit.jsonl")
+//	if err != nil {
+//		return err
+//	}
+//	auditor := audit.New([]audit.Sink{sink}, audit.WithKey(key))
+func New(sinks []Sink, opts ...Option) Logger {
+	o := Options{Now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	l := &chainLogger{sinks: sinks, opts: o}
+	if o.Head != nil {
+		l.seq = o.Head.Sequence
+		l.prevHash = o.Head.Hash
+	}
+	return l
+}
+
+type chainLogger struct {
+	sinks []Sink
+	opts  Options
+
+	mu       sync.Mutex
+	seq      uint64
+	prevHash string
+}
+
+func (l *chainLogger) Record(ctx context.Context, e Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !e.Decision.valid() {
+		return fmt.Errorf("unable to record audit event '%s': unknown decision '%s'", e.Action, e.Decision)
+	}
+	id, err := newEventID()
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e.ID = id
+	e.Time = l.opts.Now().UTC()
+	e.Sequence = l.seq + 1
+	e.PrevHash = l.prevHash
+	if e.Hash, err = hashEvent(e, l.opts.Key); err != nil {
+		return fmt.Errorf("unable to hash audit event: %w", err)
+	}
+
+	var errs []error
+	for _, s := range l.sinks {
+		if err := s.Write(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) < len(l.sinks) || len(l.sinks) == 0 {
+		l.seq = e.Sequence
+		l.prevHash = e.Hash
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("unable to write audit event '%s': %w", e.Action, err)
+	}
+	return nil
+}
+
+// Verify checks that events form an unbroken chain: every event's hash matches
+// its contents and the hash of the previous event, and sequence numbers are
+// consecutive. key must be the key the events were recorded with, if any. The
+// first event may continue an earlier chain. If the chain is broken, an error
+// wrapping ErrTampered that identifies the first offending event is returned.
+//
+// Example:
+//
+//	f, _ := os.Open("/var/log/maverics/audit.jsonl")
+//	events, err := audit.ReadJSONLines(f)
+//	if err == nil {
+//		err = audit.Verify(events, key)
+//	}
+func Verify(events []Event, key []byte) error {
+	for i, e := range events {
+		want, err := hashEvent(e, key)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal([]byte(want), []byte(e.Hash)) {
+			return fmt.Errorf("%w: event %d has an invalid hash", ErrTampered, e.Sequence)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := events[i-1]
+		if e.Sequence != prev.Sequence+1 {
+			return fmt.Errorf("%w: event %d follows event %d", ErrTampered, e.Sequence, prev.Sequence)
+		}
+		if e.PrevHash != prev.Hash {
+			return fmt.Errorf("%w: event %d does not follow the previous event", ErrTampered, e.Sequence)
+		}
+	}
+	return nil
+}
+
+func hashEvent(e Event, key []byte) (string, error) {
+	data, err := encode(e)
+	if err != nil {
+		return "", err
+	}
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate audit event ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,190 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memorySink is a Sink holding events in memory. Writes fail while fail is
+// set.
+type memorySink struct {
+	events []Event
+	fail   bool
+}
+
+func (s *memorySink) Write(e Event) error {
+	if s.fail {
+		return errors.New("sink unavailable")
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func testEvent(action string) Event {
+	return Event{
+		Action:     action,
+		Actor:      Actor{ID: "alice@example.com", IP: "192.0.2.1"},
+		Resource:   Resource{Type: "url", ID: "/admin"},
+		Decision:   DecisionAllow,
+		Attributes: map[string]string{"policy": "p1"},
+	}
+}
+
+func record(t *testing.T, l Logger, actions ...string) {
+	t.Helper()
+	for _, action := range actions {
+		if err := l.Record(context.Background(), testEvent(action)); err != nil {
+			t.Fatalf("Record(%s) error = %v", action, err)
+		}
+	}
+}
+
+func TestChainVerify(t *testing.T) {
+	key := []byte("secret")
+	sink := &memorySink{}
+	record(t, New([]Sink{sink}, WithKey(key)), "a", "b", "c")
+
+	if err := Verify(sink.events, key); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := Verify(sink.events, []byte("other")); !errors.Is(err, ErrTampered) {
+		t.Errorf("Verify() with another key error = %v, want ErrTampered", err)
+	}
+	for i, e := range sink.events {
+		if e.Sequence != uint64(i+1) {
+			t.Errorf("event %d has sequence %d", i, e.Sequence)
+		}
+	}
+}
+
+func TestChainVerifyTampered(t *testing.T) {
+	sink := &memorySink{}
+	record(t, New([]Sink{sink}), "a", "b", "c")
+
+	tests := map[string]func([]Event) []Event{
+		"modified": func(events []Event) []Event {
+			events[1].Decision = DecisionDeny
+			return events
+		},
+		"modified attribute": func(events []Event) []Event {
+			events[1].Attributes = map[string]string{"policy": "p2"}
+			return events
+		},
+		"removed": func(events []Event) []Event {
+			return append(events[:1], events[2:]...)
+		},
+		"reordered": func(events []Event) []Event {
+			events[1], events[2] = events[2], events[1]
+			return events
+		},
+		"rehashed without chain": func(events []Event) []Event {
+			events[1].PrevHash = ""
+			events[1].Hash, _ = hashEvent(events[1], nil)
+			return events
+		},
+	}
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			events := make([]Event, len(sink.events))
+			copy(events, sink.events)
+			if err := Verify(tamper(events), nil); !errors.Is(err, ErrTampered) {
+				t.Errorf("Verify() error = %v, want ErrTampered", err)
+			}
+		})
+	}
+}
+
+func TestChainContinuesHead(t *testing.T) {
+	sink := &memorySink{}
+	record(t, New([]Sink{sink}), "a", "b")
+	record(t, New([]Sink{sink}, WithHead(sink.events[1])), "c")
+	if err := Verify(sink.events, nil); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+}
+
+func TestChainSinkFailure(t *testing.T) {
+	sink := &memorySink{}
+	l := New([]Sink{sink})
+	record(t, l, "a")
+
+	sink.fail = true
+	if err := l.Record(context.Background(), testEvent("lost")); err == nil {
+		t.Fatal("Record() with a failing sink succeeded")
+	}
+	sink.fail = false
+	record(t, l, "b")
+
+	if err := Verify(sink.events, nil); err != nil {
+		t.Fatalf("Verify() after a failed write error = %v", err)
+	}
+	if len(sink.events) != 2 || sink.events[1].Sequence != 2 {
+		t.Errorf("events = %+v, want sequences 1 and 2", sink.events)
+	}
+}
+
+func TestChainPartialSinkFailure(t *testing.T) {
+	ok, failing := &memorySink{}, &memorySink{}
+	l := New([]Sink{ok, failing})
+	record(t, l, "a")
+
+	failing.fail = true
+	if err := l.Record(context.Background(), testEvent("partial")); err == nil {
+		t.Fatal("Record() with a failing sink succeeded")
+	}
+	failing.fail = false
+	record(t, l, "b")
+
+	if err := Verify(ok.events, nil); err != nil {
+		t.Errorf("Verify() of the healthy sink error = %v", err)
+	}
+	if err := Verify(failing.events, nil); !errors.Is(err, ErrTampered) {
+		t.Errorf("Verify() of the failing sink error = %v, want ErrTampered", err)
+	}
+}
+
+func TestRecordRejectsInvalidDecision(t *testing.T) {
+	for _, d := range []Decision{0, DecisionError + 1} {
+		sink := &memorySink{}
+		e := testEvent("a")
+		e.Decision = d
+		if err := New([]Sink{sink}).Record(context.Background(), e); err == nil {
+			t.Errorf("Record() with decision %d succeeded", d)
+		}
+		if len(sink.events) != 0 {
+			t.Errorf("event with decision %d was written", d)
+		}
+	}
+}
+
+func TestJSONLinesRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	l := New([]Sink{NewJSONSink(&buf)}, WithClock(func() time.Time { return now }))
+	for _, d := range []Decision{DecisionAllow, DecisionDeny, DecisionError} {
+		e := testEvent("a")
+		e.Decision = d
+		if err := l.Record(context.Background(), e); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	events, err := ReadJSONLines(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSONLines() error = %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("ReadJSONLines() returned %d events, want 3", len(events))
+	}
+	if err := Verify(events, nil); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if !events[0].Time.Equal(now) || events[2].Decision != DecisionError {
+		t.Errorf("ReadJSONLines() = %+v", events)
+	}
+}
@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// NewJSONSink creates a Sink writing events to w as JSON lines, one JSON object
+// per line. Closing the Sink closes w if it implements io.Closer.
+//
+// Example:
+//
+//	sink := audit.NewJSONSink(os.Stdout)
+func NewJSONSink(w io.Writer) Sink {
+	return &jsonSink{w: w}
+}
+
+// OpenJSONFile opens the file at path for appending, creating it if necessary,
+// and returns a Sink writing events to it as JSON lines. Each event is synced
+// to disk before Write returns.
+//
+// Example:
+//
+//	sink, err := audit.OpenJSONFile("/var/log/maverics/audit.jsonl")
+func OpenJSONFile(path string) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit file '%s': %w", path, err)
+	}
+	return &jsonSink{w: f, sync: f.Sync}, nil
+}
+
+type jsonSink struct {
+	mu   sync.Mutex
+	w    io.Writer
+	sync func() error
+}
+
+func (s *jsonSink) Write(e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("unable to encode audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(line); err != nil {
+		return err
+	}
+	if s.sync != nil {
+		return s.sync()
+	}
+	return nil
+}
+
+func (s *jsonSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ReadJSONLines reads the events written by a JSON lines Sink, e.g. to Verify
+// them or to continue their chain with WithHead.
+//
+// Example:
+//
+//	f, err := os.Open("/var/log/maverics/audit.jsonl")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	events, err := audit.ReadJSONLines(f)
+func ReadJSONLines(r io.Reader) ([]Event, error) {
+	var events []Event
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("unable to decode audit event on line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read audit events: %w", err)
+	}
+	return events, nil
+}
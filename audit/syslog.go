@@ -0,0 +1,224 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FacilityLogAudit is the syslog facility for log audit messages, the default
+// facility of a syslog Sink.
+const FacilityLogAudit = 13
+
+// SyslogOptions are the options used to configure a Sink created with
+// NewSyslogSink.
+type SyslogOptions struct {
+	// Facility is the syslog facility of messages. Defaults to FacilityLogAudit.
+	Facility int
+
+	// Hostname is the HOSTNAME of messages. Defaults to os.Hostname.
+	Hostname string
+
+	// AppName is the APP-NAME of messages. Defaults to "maverics".
+	AppName string
+
+	// StructuredDataID is the SD-ID of the structured data element holding the
+	// event fields. It must consist of 1 to 32 printable ASCII characters other
+	// than space, '=', ']' and '"'. Defaults to "audit@32473", using the private
+	// enterprise number reserved for documentation.
+	StructuredDataID string
+
+	// OctetCounting prefixes each message with its length as described by RFC
+	// 6587, as required when sending messages over TCP or TLS.
+	OctetCounting bool
+}
+
+// SyslogOption is an option used to configure a Sink created with
+// NewSyslogSink.
+type SyslogOption func(*SyslogOptions)
+
+// WithFacility configures the syslog facility of messages.
+func WithFacility(facility int) SyslogOption {
+	return func(o *SyslogOptions) {
+		o.Facility = facility
+	}
+}
+
+// WithHostname configures the HOSTNAME of messages.
+func WithHostname(hostname string) SyslogOption {
+	return func(o *SyslogOptions) {
+		o.Hostname = hostname
+	}
+}
+
+// WithAppName configures the APP-NAME of messages.
+func WithAppName(appName string) SyslogOption {
+	return func(o *SyslogOptions) {
+		o.AppName = appName
+	}
+}
+
+// WithStructuredDataID configures the SD-ID of the structured data element
+// holding the event fields, e.g. "audit@<your enterprise number>".
+func WithStructuredDataID(id string) SyslogOption {
+	return func(o *SyslogOptions) {
+		o.StructuredDataID = id
+	}
+}
+
+// WithOctetCounting configures whether messages are prefixed with their length,
+// as required when sending messages over TCP or TLS.
+func WithOctetCounting(enabled bool) SyslogOption {
+	return func(o *SyslogOptions) {
+		o.OctetCounting = enabled
+	}
+}
+
+// NewSyslogSink creates a Sink writing events to w as RFC 5424 syslog messages,
+// one message per Write. The main event fields are included as structured data
+// and the whole event as a JSON message. Events that are denied are written
+// with severity warning, events that failed with severity error and all other
+// events with severity notice. Closing the Sink closes w if it implements
+// io.Closer. An error is returned if the options are invalid.
+//
+// Example:
+//
+//	conn, err := net.Dial("udp", "siem.example.com:514")
+//	if err != nil {
+//		return err
+//	}
+//	sink, err := audit.NewSyslogSink(conn, audit.WithAppName("maverics-ext"))
+//	if err != nil {
+//		return err
+//	}
+func NewSyslogSink(w io.Writer, opts ...SyslogOption) (Sink, error) {
+	o := SyslogOptions{
+		Facility:         FacilityLogAudit,
+		AppName:          "maverics",
+		StructuredDataID: "audit@32473",
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !validSDID(o.StructuredDataID) {
+		return nil, fmt.Errorf("invalid structured data ID '%s'", o.StructuredDataID)
+	}
+	if o.Hostname == "" {
+		o.Hostname, _ = os.Hostname()
+	}
+	return &syslogSink{w: w, opts: o, procID: strconv.Itoa(os.Getpid())}, nil
+}
+
+type syslogSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	opts   SyslogOptions
+	procID string
+}
+
+func (s *syslogSink) Write(e Event) error {
+	msg, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("unable to encode audit event: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s [%s",
+		s.opts.Facility*8+syslogSeverity(e.Decision),
+		e.Time.UTC().Format(time.RFC3339Nano),
+		syslogHeader(s.opts.Hostname, 255),
+		syslogHeader(s.opts.AppName, 48),
+		syslogHeader(s.procID, 128),
+		syslogHeader(e.Action, 32),
+		s.opts.StructuredDataID,
+	)
+	for _, p := range [][2]string{
+		{"id", e.ID},
+		{"action", e.Action},
+		{"actor", e.Actor.ID},
+		{"idp", e.Actor.IdentityProvider},
+		{"ip", e.Actor.IP},
+		{"resourceType", e.Resource.Type},
+		{"resource", e.Resource.ID},
+		{"decision", e.Decision.String()},
+		{"reason", e.Reason},
+		{"correlationId", e.CorrelationID},
+		{"sessionId", e.SessionID},
+		{"seq", strconv.FormatUint(e.Sequence, 10)},
+		{"prevHash", e.PrevHash},
+		{"hash", e.Hash},
+	} {
+		if p[1] != "" {
+			fmt.Fprintf(&b, ` %s="%s"`, p[0], sdEscaper.Replace(p[1]))
+		}
+	}
+	b.WriteString("] ")
+	b.Write(msg)
+
+	out := b.String()
+	if s.opts.OctetCounting {
+		out = strconv.Itoa(len(out)) + " " + out
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = io.WriteString(s.w, out)
+	return err
+}
+
+func (s *syslogSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// sdEscaper escapes the characters that must be escaped in an SD-PARAM value.
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogSeverity returns the syslog severity of an event with decision d.
+func syslogSeverity(d Decision) int {
+	switch d {
+	case DecisionDeny:
+		return 4
+	case DecisionError:
+		return 3
+	default:
+		return 5
+	}
+}
+
+// validSDID reports whether id is a valid SD-ID as defined by RFC 5424.
+func validSDID(id string) bool {
+	if len(id) == 0 || len(id) > 32 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			return false
+		}
+	}
+	return true
+}
+
+// syslogHeader returns s as a header field of at most max printable ASCII
+// characters, or the NILVALUE if s is empty.
+func syslogHeader(s string, max int) string {
+	b := make([]byte, 0, min(len(s), max))
+	for i := 0; i < len(s) && len(b) < max; i++ {
+		if c := s[i]; c > ' ' && c < 0x7f {
+			b = append(b, c)
+		} else {
+			b = append(b, '_')
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sinkEvent returns an event as written by a Logger, with fields requiring
+// escaping in every output format.
+func sinkEvent() Event {
+	e := testEvent(`login`)
+	e.ID = "evt-1"
+	e.Time = time.Date(2026, 1, 2, 3, 4, 5, 600, time.UTC)
+	e.Decision = DecisionDeny
+	e.Reason = `bad "password" ] \ =|x` + "\nnext"
+	e.Sequence = 7
+	e.Hash = "h2"
+	e.PrevHash = "h1"
+	return e
+}
+
+func TestSyslogSink(t *testing.T) {
+	var b strings.Builder
+	sink, err := NewSyslogSink(&b, WithHostname("host one"), WithAppName("app"))
+	if err != nil {
+		t.Fatalf("NewSyslogSink() error = %v", err)
+	}
+	e := sinkEvent()
+	if err := sink.Write(e); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	out := b.String()
+	// Priority is facility 13 * 8 + severity warning (4).
+	header := "<108>1 2026-01-02T03:04:05.0000006Z host_one app "
+	if !strings.HasPrefix(out, header) {
+		t.Errorf("message = %q, want prefix %q", out, header)
+	}
+	for _, want := range []string{
+		` login [audit@32473 id="evt-1" action="login"`,
+		` decision="deny"`,
+		` reason="bad \"password\" \] \\ =|x` + "\nnext\"",
+		` seq="7" prevHash="h1" hash="h2"]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("message = %q, missing %q", out, want)
+		}
+	}
+
+	_, msg, ok := strings.Cut(out, "] {")
+	if !ok {
+		t.Fatalf("message = %q, missing JSON message", out)
+	}
+	var got Event
+	if err := json.Unmarshal([]byte("{"+msg), &got); err != nil {
+		t.Fatalf("JSON message error = %v", err)
+	}
+	if got.Reason != e.Reason || got.Hash != e.Hash {
+		t.Errorf("JSON message = %+v, want %+v", got, e)
+	}
+}
+
+func TestSyslogSinkOctetCounting(t *testing.T) {
+	var b strings.Builder
+	sink, err := NewSyslogSink(&b, WithOctetCounting(true), WithStructuredDataID("audit@1"))
+	if err != nil {
+		t.Fatalf("NewSyslogSink() error = %v", err)
+	}
+	if err := sink.Write(sinkEvent()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	n, msg, ok := strings.Cut(b.String(), " ")
+	if !ok {
+		t.Fatalf("message = %q, missing length", b.String())
+	}
+	if length, err := strconv.Atoi(n); err != nil || length != len(msg) {
+		t.Errorf("length = %q, want %d", n, len(msg))
+	}
+	if !strings.Contains(msg, "[audit@1 ") {
+		t.Errorf("message = %q, missing structured data ID", msg)
+	}
+}
+
+func TestNewSyslogSinkInvalidStructuredDataID(t *testing.T) {
+	for _, id := range []string{"", "audit 1", "audit=1", "audit]1", `audit"1`, "audit\n1", "aüdit", strings.Repeat("a", 33)} {
+		if _, err := NewSyslogSink(&strings.Builder{}, WithStructuredDataID(id)); err == nil {
+			t.Errorf("NewSyslogSink(WithStructuredDataID(%q)) succeeded", id)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Entry is a key and its value as stored by a Backend.
+type Entry struct {
+	// Key is the key of the entry.
+	Key string
+
+	// Value is the value of the entry.
+	Value []byte
+
+	// Version is assigned by the Backend every time the value of the key is
+	// written. Versions of a key increase strictly and are never reused, even
+	// after the key is deleted or the Backend is flushed.
+	Version uint64
+
+	// TTL is the Time-To-Live (TTL) the entry was last set with. Zero if the entry
+	// does not expire.
+	TTL time.Duration
+
+	// ExpiresAt is the time at which the entry expires. The zero time means the
+	// entry does not expire.
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the entry has expired at the given time.
+func (e Entry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// Size returns the number of bytes the entry counts towards Constraints.MaxBytes.
+func (e Entry) Size() int64 {
+	return int64(len(e.Key) + len(e.Value))
+}
+
+// UpdateAction tells a Backend how to apply the result of an UpdateFunc.
+type UpdateAction int
+
+const (
+	// UpdateNone leaves the entry unchanged.
+	UpdateNone UpdateAction = iota + 1
+	// UpdateSet stores the returned entry under a new Version.
+	UpdateSet
+	// UpdateSetTTL replaces only the TTL and ExpiresAt of the existing entry. The
+	// Value and Version are left unchanged.
+	UpdateSetTTL
+	// UpdateDelete removes the entry.
+	UpdateDelete
+)
+
+// String returns the name of the update action.
+func (a UpdateAction) String() string {
+	switch a {
+	case UpdateNone:
+		return "none"
+	case UpdateSet:
+		return "set"
+	case UpdateSetTTL:
+		return "set-ttl"
+	case UpdateDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("UpdateAction(%d)", int(a))
+	}
+}
+
+// UpdateFunc computes the new entry for a key from its current entry. ok is
+// false if the key does not exist. If an error is returned, the entry is left
+// unchanged and the error is returned by Backend.Update.
+type UpdateFunc func(current Entry, ok bool) (next Entry, action UpdateAction, err error)
+
+// Backend is the storage used by a Cache created with NewLocal. A Backend only
+// stores entries; expiry, versions comparison, counters and notifications are
+// implemented on top of it by NewLocal, so a Backend only has to provide an
+// atomic read-modify-write of a single key.
+//
+// Backends do not interpret ExpiresAt. Expired entries are returned like any
+// other entry and are removed by NewLocal when they are accessed, so Len may
+// include expired entries. Backends may evict entries at any time to stay
+// within their capacity.
+//
+// All methods must be safe for concurrent use.
+type Backend interface {
+	// Get returns the entry for a given key. ok is false if the key does not
+	// exist.
+	Get(ctx context.Context, key string) (e Entry, ok bool, err error)
+
+	// Update atomically applies fn to the entry of a given key. fn must not call
+	// methods of the Backend. If the result would exceed the capacity of a Backend
+	// that does not evict, an error wrapping ErrCacheFull is returned.
+	Update(ctx context.Context, key string, fn UpdateFunc) error
+
+	// Scan returns up to count entries whose keys begin with the given prefix,
+	// starting at the given cursor, with the same semantics as Scanner.Scan.
+	Scan(ctx context.Context, prefix string, cursor string, count int) (entries []Entry, next string, err error)
+
+	// Len returns the number of entries in the Backend.
+	Len(ctx context.Context) (int, error)
+
+	// Flush removes all entries from the Backend.
+	Flush(ctx context.Context) error
+
+	// Close releases the resources held by the Backend. The Backend must not be
+	// used after it is closed.
+	Close() error
+}
+
+// BackendStats are statistics about the usage of a Backend.
+type BackendStats struct {
+	// Entries is the number of entries currently held.
+	Entries int
+
+	// Bytes is the combined size of the entries currently held, as reported by
+	// Entry.Size.
+	Bytes int64
+
+	// Hits is the number of calls to Get for keys that existed.
+	Hits uint64
+
+	// Misses is the number of calls to Get for keys that did not exist.
+	Misses uint64
+
+	// Evictions is the number of entries removed to stay within the capacity of
+	// the Backend.
+	Evictions uint64
+}
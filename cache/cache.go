@@ -2,12 +2,49 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 )
 
+// ErrNotFound is returned when a key does not exist in the cache or has expired.
+// Implementations may wrap ErrNotFound, so callers should use errors.Is to check
+// for it.
+var ErrNotFound = errors.New("key not found")
+
+// ErrNotInteger is returned when a counter operation is performed on a key whose
+// value is not a base-10 encoded 64-bit integer.
+var ErrNotInteger = errors.New("value is not an integer")
+
+// ErrOverflow is returned when a counter operation would overflow a 64-bit
+// integer. The counter is left unchanged.
+var ErrOverflow = errors.New("counter would overflow")
+
+// ErrCacheFull is returned when a write would exceed the capacity of a cache
+// whose eviction policy is EvictNone.
+var ErrCacheFull = errors.New("cache is full")
+
+// Version is an opaque token identifying a specific write of a key. A new
+// Version is assigned every time the value of a key is written. Versions can
+// only be compared for equality.
+type Version string
+
+// Cache stores values shared across service extensions and Orchestrator nodes.
+//
+// Caches returned by the Orchestrator may implement further operations through
+// the optional Store, Atomic, Scanner and PubSub interfaces, or all of them
+// through Extended. Use a type assertion to find out whether an operation is
+// supported.
+//
+// Example:
+//
+//	c, _ := api.Cache("ldap")
+//	if store, ok := c.(cache.Store); ok {
+//		_ = store.Delete(ctx, uid)
+//	}
 type Cache interface {
 	// GetBytes returns the []byte for a given key. If the key does not exist, an
-	// error will be returned.
+	// error wrapping ErrNotFound will be returned.
 	GetBytes(ctx context.Context, key string) ([]byte, error)
 
 	// SetBytes adds a key and the corresponding []byte value the backing store.
@@ -16,6 +53,234 @@ type Cache interface {
 	SetBytes(ctx context.Context, key string, value []byte, opts ...Option) error
 }
 
+// Store is implemented by caches that support deleting keys, inspecting and
+// changing their Time-To-Live (TTL) and batch operations.
+type Store interface {
+	Cache
+
+	// Delete removes a key from the backing store. Deleting a key that does not
+	// exist is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// Exists reports whether a key exists in the backing store.
+	Exists(ctx context.Context, key string) (bool, error)
+
+	// TTL returns the remaining Time-To-Live (TTL) for a given key. A TTL of zero
+	// is returned if the key does not expire. If the key does not exist, an error
+	// wrapping ErrNotFound will be returned.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+
+	// Expire replaces the Time-To-Live (TTL) of a given key. A TTL of zero removes
+	// the expiry so that the key persists until it is deleted. If the key does not
+	// exist, an error wrapping ErrNotFound will be returned.
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+
+	// Touch resets the Time-To-Live (TTL) of a given key to the TTL it was last set
+	// with, e.g. to implement sliding expiration. Touching a key that does not
+	// expire has no effect. If the key does not exist, an error wrapping
+	// ErrNotFound will be returned.
+	Touch(ctx context.Context, key string) error
+
+	// GetMany returns the values for the given keys. Keys that do not exist are
+	// omitted from the returned map.
+	GetMany(ctx context.Context, keys []string) (map[string][]byte, error)
+
+	// SetMany adds the given keys and corresponding values to the backing store.
+	// If options are passed, they will be configured for every key. Any existing
+	// values for the keys will be replaced.
+	SetMany(ctx context.Context, values map[string][]byte, opts ...Option) error
+
+	// DeleteMany removes the given keys from the backing store. Keys that do not
+	// exist are ignored.
+	DeleteMany(ctx context.Context, keys []string) error
+}
+
+// Atomic is implemented by caches that support atomic conditional writes and
+// counters, e.g. to coordinate Orchestrator nodes.
+type Atomic interface {
+	Cache
+
+	// SetIfAbsent atomically adds a key and the corresponding []byte value to the
+	// backing store only if the key does not already exist. True is returned if
+	// the value was set.
+	//
+	// Example (replay protection):
+	//
+	//	ok, err := c.SetIfAbsent(ctx, "nonce:"+nonce, []byte{}, cache.WithTTL(5*time.Minute))
+	//	if err == nil && !ok {
+	//		// The nonce has already been used.
+	//	}
+	SetIfAbsent(ctx context.Context, key string, value []byte, opts ...Option) (bool, error)
+
+	// GetWithVersion returns the []byte for a given key along with the Version of
+	// the value. If the key does not exist, an error wrapping ErrNotFound will be
+	// returned.
+	GetWithVersion(ctx context.Context, key string) ([]byte, Version, error)
+
+	// CompareAndSwap atomically replaces the value of a key only if the current
+	// Version of the key matches the provided version. If the zero Version is
+	// provided, the value is only set if the key does not exist. True is returned
+	// if the value was replaced.
+	//
+	// Example:
+	//
+	//	value, version, err := c.GetWithVersion(ctx, key)
+	//	// ... compute updated value ...
+	//	swapped, err := c.CompareAndSwap(ctx, key, version, updated)
+	CompareAndSwap(ctx context.Context, key string, version Version, value []byte, opts ...Option) (bool, error)
+
+	// Increment atomically adds delta to the counter stored at a given key and
+	// returns the new value. If the key does not exist, it is created with a value
+	// of delta and any options passed are configured for the key; options are
+	// ignored for existing keys so that a TTL marks the end of a fixed window.
+	// Counters are stored as base-10 encoded integers. If the existing value is
+	// not an integer, an error wrapping ErrNotInteger will be returned. If the
+	// new value would overflow, an error wrapping ErrOverflow will be returned.
+	//
+	// Example (lockout counter):
+	//
+	//	attempts, err := c.Increment(ctx, "otp:"+user, 1, cache.WithTTL(15*time.Minute))
+	//	if err == nil && attempts > 5 {
+	//		// Lock the user out.
+	//	}
+	Increment(ctx context.Context, key string, delta int64, opts ...Option) (int64, error)
+
+	// Decrement atomically subtracts delta from the counter stored at a given key
+	// and returns the new value. It otherwise behaves like Increment.
+	Decrement(ctx context.Context, key string, delta int64, opts ...Option) (int64, error)
+}
+
+// Scanner is implemented by caches that support iterating over, counting and
+// flushing their keys.
+type Scanner interface {
+	Cache
+
+	// Scan returns up to count keys of the cache that begin with the given prefix,
+	// starting at the given cursor. An empty cursor starts a new scan. The
+	// returned cursor is passed to the next call to continue the scan; an empty
+	// returned cursor indicates that the scan is complete. Fewer than count keys,
+	// or even none, may be returned before the scan is complete.
+	//
+	// Scans are not point-in-time snapshots. A key that exists for the whole
+	// duration of a complete scan is returned at least once. A key that is added,
+	// deleted or expires during the scan may or may not be returned. A key may be
+	// returned more than once, so callers must tolerate duplicates. Use Keys to
+	// iterate over all keys without handling cursors.
+	Scan(ctx context.Context, prefix string, cursor string, count int) (keys []string, next string, err error)
+
+	// Len returns the number of keys in the cache. The count is approximate: it
+	// may include keys that have expired but have not yet been removed, which Scan
+	// does not return, and it does not reflect concurrent writes.
+	Len(ctx context.Context) (int, error)
+
+	// Flush removes all keys from the cache. Flush only affects the namespace and
+	// name the cache was retrieved with. Flush is not atomic with respect to
+	// concurrent writes: keys written while the flush is in progress may survive
+	// it.
+	Flush(ctx context.Context) error
+}
+
+// PubSub is implemented by caches that support publishing messages and
+// notifying about key changes.
+type PubSub interface {
+	Cache
+
+	// Publish broadcasts a payload on the given topic to all subscribers of the
+	// topic in the same cache namespace and name, on every Orchestrator node,
+	// including the publishing node. Messages are not persisted: subscribers that
+	// are not subscribed at the time a message is published do not receive it.
+	//
+	// Example (cluster-wide logout):
+	//
+	//	_ = c.Publish(ctx, "logout", []byte(uid))
+	Publish(ctx context.Context, topic string, payload []byte) error
+
+	// Subscribe returns a channel receiving the messages published on the given
+	// topic. The channel is closed when ctx is done. Delivery is at most once:
+	// messages are dropped for subscribers that do not keep up with the rate at
+	// which messages are published.
+	//
+	// Example:
+	//
+	//	msgs, _ := c.Subscribe(ctx, "logout")
+	//	go func() {
+	//		for msg := range msgs {
+	//			localState.Forget(string(msg.Payload))
+	//		}
+	//	}()
+	Subscribe(ctx context.Context, topic string) (<-chan Message, error)
+
+	// Watch returns a channel receiving an Event every time a key beginning with
+	// the given prefix is set, deleted or expires, or the cache is flushed. The
+	// channel is closed when ctx is done. Like Subscribe, delivery is at most
+	// once. Expiry events may be delivered some time after the key expired.
+	Watch(ctx context.Context, prefix string) (<-chan Event, error)
+}
+
+// Extended is implemented by caches supporting all optional operations, such
+// as caches created with NewLocal.
+type Extended interface {
+	Store
+	Atomic
+	Scanner
+	PubSub
+}
+
+// ErrUnsupported is returned when an operation requires an optional interface,
+// e.g. Store, that the underlying Cache does not implement.
+var ErrUnsupported = errors.New("operation not supported by cache")
+
+// Message is a message published on a cache topic.
+type Message struct {
+	// Topic is the topic the message was published on.
+	Topic string
+
+	// Payload is the published payload.
+	Payload []byte
+
+	// PublishedAt is the time the message was published.
+	PublishedAt time.Time
+}
+
+// EventType is the kind of change reported by an Event.
+type EventType int
+
+const (
+	// EventSet is reported when the value of a key is written.
+	EventSet EventType = iota + 1
+	// EventDelete is reported when a key is deleted.
+	EventDelete
+	// EventExpire is reported when a key expires.
+	EventExpire
+	// EventFlush is reported when the cache is flushed.
+	EventFlush
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventFlush:
+		return "flush"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+// Event describes a change to a key in the cache.
+type Event struct {
+	// Type is the kind of change.
+	Type EventType
+
+	// Key is the changed key. Key is empty for EventFlush.
+	Key string
+}
+
 // Options contains Options for a given piece of data.
 type Options struct {
 	// Represents the Time-To-Live (TTL) for a given piece of data. When this
@@ -34,10 +299,51 @@ func WithTTL(ttl time.Duration) Option {
 	}
 }
 
+// EvictionPolicy determines which keys are removed when a Cache reaches its
+// capacity.
+type EvictionPolicy int
+
+const (
+	// EvictLRU evicts the least recently used keys first.
+	EvictLRU EvictionPolicy = iota + 1
+	// EvictFIFO evicts the earliest inserted keys first. Overwriting a key or
+	// changing its TTL does not change its position.
+	EvictFIFO
+	// EvictNone never evicts keys. Writes that would exceed the capacity of the
+	// cache fail with an error wrapping ErrCacheFull.
+	EvictNone
+)
+
+// String returns the name of the eviction policy.
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictLRU:
+		return "lru"
+	case EvictFIFO:
+		return "fifo"
+	case EvictNone:
+		return "none"
+	default:
+		return fmt.Sprintf("EvictionPolicy(%d)", int(p))
+	}
+}
+
 // Constraints are the constraints for a Cache.
 type Constraints struct {
 	// Name of the cache.
 	Name string
+
+	// MaxEntries is the maximum number of keys held by the cache. Zero means no
+	// limit.
+	MaxEntries int
+
+	// MaxBytes is the maximum combined size of the keys and values held by the
+	// cache. Zero means no limit.
+	MaxBytes int64
+
+	// Eviction determines which keys are removed when the cache reaches
+	// MaxEntries or MaxBytes. Defaults to EvictLRU.
+	Eviction EvictionPolicy
 }
 
 // Constraint allows for customizing the Cache.
@@ -49,3 +355,33 @@ func WithName(name string) Constraint {
 		do.Name = name
 	}
 }
+
+// WithMaxEntries is an option to limit the number of keys held by the cache.
+func WithMaxEntries(n int) Constraint {
+	return func(do *Constraints) {
+		do.MaxEntries = n
+	}
+}
+
+// WithMaxBytes is an option to limit the combined size of the keys and values
+// held by the cache.
+func WithMaxBytes(n int64) Constraint {
+	return func(do *Constraints) {
+		do.MaxBytes = n
+	}
+}
+
+// WithEviction is an option to specify which keys are removed when the cache
+// reaches its capacity.
+//
+// Example:
+//
+//	c, err := api.Cache("nonces",
+//		cache.WithMaxEntries(100000),
+//		cache.WithEviction(cache.EvictNone),
+//	)
+func WithEviction(policy EvictionPolicy) Constraint {
+	return func(do *Constraints) {
+		do.Eviction = policy
+	}
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Codec encodes and decodes values stored in a Cache by Typed.
+type Codec interface {
+	// Name uniquely identifies the codec. The name is recorded alongside every
+	// encoded value so that values written with a different codec are detected.
+	Name() string
+
+	// Marshal returns the encoding of v.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes data and stores the result in the value pointed to by v.
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes values using encoding/json. It is the default codec used by
+// Typed.
+type JSONCodec struct{}
+
+// Name returns "json".
+func (JSONCodec) Name() string { return "json" }
+
+// Marshal returns the JSON encoding of v.
+func (JSONCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal decodes JSON-encoded data into v.
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// GobCodec encodes values using encoding/gob. Gob is more compact than JSON for
+// large values but is only readable by Go programs.
+type GobCodec struct{}
+
+// Name returns "gob".
+func (GobCodec) Name() string { return "gob" }
+
+// Marshal returns the gob encoding of v.
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes gob-encoded data into v.
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// ProtoMessage is implemented by types that can encode themselves to and decode
+// themselves from the protocol buffers wire format, such as the types generated
+// by gogo/protobuf or the vtprotobuf plugin.
+type ProtoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+// ProtoCodec encodes values that implement ProtoMessage, producing values that
+// are wire compatible with protocol buffers. Generated types usually implement
+// ProtoMessage with pointer receivers; ProtoCodec supports both Typed[Msg] and
+// Typed[*Msg] for such a type Msg.
+type ProtoCodec struct{}
+
+// Name returns "protobuf".
+func (ProtoCodec) Name() string { return "protobuf" }
+
+// Marshal returns the protocol buffers wire encoding of v. If v does not
+// implement ProtoMessage but a pointer to it does, a pointer to a copy of v is
+// used.
+func (ProtoCodec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(ProtoMessage); ok {
+		return m.Marshal()
+	}
+	if v != nil {
+		p := reflect.New(reflect.TypeOf(v))
+		p.Elem().Set(reflect.ValueOf(v))
+		if m, ok := p.Interface().(ProtoMessage); ok {
+			return m.Marshal()
+		}
+	}
+	return nil, fmt.Errorf("type %T does not implement ProtoMessage", v)
+}
+
+// Unmarshal decodes protocol buffers wire encoded data into v. If v is a
+// pointer to a pointer implementing ProtoMessage, e.g. **Msg, a new value is
+// allocated if the pointer is nil.
+func (ProtoCodec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(ProtoMessage); ok {
+		return m.Unmarshal(data)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Pointer {
+		elem := rv.Elem()
+		if _, ok := reflect.Zero(elem.Type()).Interface().(ProtoMessage); ok {
+			if elem.IsNil() {
+				elem.Set(reflect.New(elem.Type().Elem()))
+			}
+			return elem.Interface().(ProtoMessage).Unmarshal(data)
+		}
+	}
+	return fmt.Errorf("type %T does not implement ProtoMessage", v)
+}
+
+// MsgpackCodec encodes values using the MessagePack format
+// (https://msgpack.org). Structs are encoded as maps keyed by field name; the
+// name can be overridden with a `msgpack:"name"` struct tag, and fields tagged
+// `msgpack:"-"` are skipped. time.Time values are encoded with the MessagePack
+// timestamp extension.
+type MsgpackCodec struct{}
+
+// Name returns "msgpack".
+func (MsgpackCodec) Name() string { return "msgpack" }
+
+// Marshal returns the MessagePack encoding of v.
+func (MsgpackCodec) Marshal(v any) ([]byte, error) {
+	var e msgpackEncoder
+	if err := e.encode(v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// Unmarshal decodes MessagePack-encoded data into v.
+func (MsgpackCodec) Unmarshal(data []byte, v any) error {
+	d := msgpackDecoder{data: data}
+	if err := d.decodeInto(v); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("msgpack: %d trailing bytes", len(d.data)-d.pos)
+	}
+	return nil
+}
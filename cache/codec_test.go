@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type codecRecord struct {
+	Name    string            `json:"name" msgpack:"name"`
+	Count   int               `json:"count" msgpack:"count"`
+	Ratio   float64           `json:"ratio" msgpack:"ratio"`
+	Enabled bool              `json:"enabled" msgpack:"enabled"`
+	Tags    []string          `json:"tags" msgpack:"tags"`
+	Attrs   map[string]string `json:"attrs" msgpack:"attrs"`
+	Data    []byte            `json:"data" msgpack:"data"`
+	At      time.Time         `json:"at" msgpack:"at"`
+	Skipped string            `json:"-" msgpack:"-"`
+}
+
+// protoRecord implements ProtoMessage with pointer receivers, like the types
+// generated by gogo/protobuf and vtprotobuf.
+type protoRecord struct {
+	Value string
+}
+
+func (m *protoRecord) Marshal() ([]byte, error) { return []byte(m.Value), nil }
+
+func (m *protoRecord) Unmarshal(data []byte) error {
+	m.Value = string(data)
+	return nil
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	want := codecRecord{
+		Name:    "alice",
+		Count:   -42,
+		Ratio:   0.25,
+		Enabled: true,
+		Tags:    []string{"a", "b"},
+		Attrs:   map[string]string{"k": "v"},
+		Data:    []byte{0, 1, 2},
+		At:      time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC),
+	}
+	for _, codec := range []Codec{JSONCodec{}, GobCodec{}, MsgpackCodec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			data, err := codec.Marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var got codecRecord
+			if err := codec.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !got.At.Equal(want.At) {
+				t.Errorf("At = %v, want %v", got.At, want.At)
+			}
+			got.At = want.At
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMsgpackCodecAny(t *testing.T) {
+	in := map[string]any{"s": "x", "n": int64(-3), "l": []any{"a", true, nil}}
+	data, err := MsgpackCodec{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got any
+	if err := (MsgpackCodec{}).Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("Unmarshal() = %#v, want %#v", got, in)
+	}
+}
+
+func TestMsgpackCodecCorrupt(t *testing.T) {
+	valid, err := MsgpackCodec{}.Marshal(codecRecord{Name: "alice", Tags: []string{"a"}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// Every truncation of a valid encoding must be rejected.
+	for i := range len(valid) {
+		var v codecRecord
+		if err := (MsgpackCodec{}).Unmarshal(valid[:i], &v); err == nil {
+			t.Errorf("Unmarshal(valid[:%d]) succeeded", i)
+		}
+	}
+
+	header := func(b byte, n uint32) []byte {
+		return binary.BigEndian.AppendUint32([]byte{b}, n)
+	}
+	deep := []byte(strings.Repeat("\x91", msgpackMaxDepth+1) + "\xc0")
+	tests := []struct {
+		name string
+		data []byte
+		v    any
+	}{
+		{"array32 length", header(mpArray32, 1<<32-1), new([]string)},
+		{"map32 length", header(mpMap32, 1<<32-1), new(map[string]string)},
+		{"array32 length any", header(mpArray32, 1<<32-1), new(any)},
+		{"map32 length any", header(mpMap32, 1<<32-1), new(any)},
+		{"map length odd", append(header(mpMap32, 2), 0xa1, 'a', 0x01), new(map[string]int)},
+		{"str32 length", header(mpStr32, 1<<32-1), new(string)},
+		{"deep nesting", deep, new(any)},
+		{"trailing bytes", []byte{0x01, 0x02}, new(int)},
+		{"type mismatch", []byte{0xa1, 'a'}, new(int)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (MsgpackCodec{}).Unmarshal(tt.data, tt.v); err == nil {
+				t.Error("Unmarshal() succeeded")
+			}
+		})
+	}
+}
+
+func TestMsgpackCodecCyclic(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	list := &node{Name: "a"}
+	list.Next = list
+	m := map[string]any{}
+	m["self"] = m
+	s := []any{nil}
+	s[0] = s
+
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"pointer", list},
+		{"map", m},
+		{"slice", s},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (MsgpackCodec{}).Marshal(tt.v); err == nil {
+				t.Error("Marshal() succeeded")
+			}
+		})
+	}
+
+	// Deep but acyclic values within the limit are still encoded.
+	var deep any = "leaf"
+	for range msgpackMaxDepth - 1 {
+		deep = []any{deep}
+	}
+	if _, err := (MsgpackCodec{}).Marshal(deep); err != nil {
+		t.Errorf("Marshal() of value nested %d deep error = %v", msgpackMaxDepth, err)
+	}
+}
+
+func TestProtoCodecPointerReceiver(t *testing.T) {
+	t.Run("value", func(t *testing.T) {
+		typed := NewTyped[protoRecord](nil, WithCodec(ProtoCodec{}))
+		data, err := typed.Encode(protoRecord{Value: "alice"})
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		got, err := typed.Decode(data)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if got.Value != "alice" {
+			t.Errorf("Decode() = %q, want %q", got.Value, "alice")
+		}
+	})
+	t.Run("pointer", func(t *testing.T) {
+		typed := NewTyped[*protoRecord](nil, WithCodec(ProtoCodec{}))
+		data, err := typed.Encode(&protoRecord{Value: "alice"})
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		got, err := typed.Decode(data)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if got == nil || got.Value != "alice" {
+			t.Errorf("Decode() = %v, want &{alice}", got)
+		}
+	})
+	t.Run("not a message", func(t *testing.T) {
+		if _, err := (ProtoCodec{}).Marshal("alice"); err == nil {
+			t.Error("Marshal() succeeded")
+		}
+		if err := (ProtoCodec{}).Unmarshal(nil, new(string)); err == nil {
+			t.Error("Unmarshal() succeeded")
+		}
+		if _, err := (ProtoCodec{}).Marshal(nil); err == nil {
+			t.Error("Marshal(nil) succeeded")
+		}
+	})
+}
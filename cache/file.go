@@ -0,0 +1,452 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrCompaction is wrapped by errors returned by FileBackend when its file could
+// not be compacted. If Update returns such an error, the write itself was
+// persisted and does not need to be retried; compaction is attempted again on
+// the next write.
+var ErrCompaction = errors.New("unable to compact cache file")
+
+// fileMagic is the header of every file written by FileBackend.
+const fileMagic = "sxcache1"
+
+// Record types of the FileBackend log.
+const (
+	recordSet    = 1
+	recordDelete = 2
+	// recordSeq records the last assigned version, so that versions are not
+	// reused after compaction removes the records of deleted keys.
+	recordSeq = 3
+)
+
+// compactMinRecords is the number of superseded records in the log of a
+// FileBackend below which the log is never compacted.
+const compactMinRecords = 1024
+
+// maxRecordSize bounds the size of a single log record so that a corrupt length
+// does not cause an arbitrarily large allocation while the log is replayed.
+const maxRecordSize = 1 << 30
+
+// FileBackend is a Backend persisting entries to a single file, so that cached
+// values survive restarts without an external store, e.g. during local
+// development. Entries are held in memory like in a MemoryBackend, including
+// its capacity and eviction behavior, and every write is appended to the file.
+// The file is compacted automatically once most of it consists of superseded
+// writes.
+//
+// Writes are passed to the operating system before they are acknowledged, so
+// they survive the process crashing but not necessarily the host crashing;
+// call Sync to flush them to stable storage. A file must not be opened by more
+// than one FileBackend at a time.
+//
+// Example:
+//
+//	backend, err := cache.OpenFileBackend("/var/lib/extension/cache.db", cache.WithMaxBytes(64<<20))
+//	if err != nil {
+//		return err
+//	}
+//	defer backend.Close()
+//	c := cache.NewLocal(backend)
+type FileBackend struct {
+	path string
+	// mem holds the entries. mem.mu also guards the fields below.
+	mem  *MemoryBackend
+	file *os.File
+	// records is the number of records in the log.
+	records int
+	// evictErr is the first error encountered while logging an eviction.
+	evictErr error
+}
+
+var _ Backend = (*FileBackend)(nil)
+
+// OpenFileBackend opens the FileBackend stored at path, creating the file if it
+// does not exist. The Name constraint is ignored. If the file ends with a
+// partially written record, e.g. because the process crashed while writing it,
+// the record is discarded.
+func OpenFileBackend(path string, constraints ...Constraint) (*FileBackend, error) {
+	b := &FileBackend{
+		path: path,
+		mem:  NewMemoryBackend(constraints...),
+	}
+	if err := b.open(); err != nil {
+		return nil, fmt.Errorf("unable to open cache file '%s': %w", path, err)
+	}
+	b.mem.onEvict = func(e Entry) {
+		if err := b.append(encodeDelete(e.Key)); err != nil && b.evictErr == nil {
+			b.evictErr = err
+		}
+	}
+	return b, nil
+}
+
+// Get returns the entry for a given key.
+func (b *FileBackend) Get(ctx context.Context, key string) (Entry, bool, error) {
+	return b.mem.Get(ctx, key)
+}
+
+// Update atomically applies fn to the entry of a given key and appends the
+// result to the file. If the result was appended but the file could not be
+// compacted afterwards, an error wrapping ErrCompaction is returned.
+func (b *FileBackend) Update(ctx context.Context, key string, fn UpdateFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mem.mu.Lock()
+	defer b.mem.mu.Unlock()
+	if b.file == nil {
+		return errors.New("cache file is closed")
+	}
+
+	current, ok := b.mem.get(key, false)
+	next, action, err := fn(cloneEntry(current), ok)
+	if err != nil {
+		return err
+	}
+	next, err = b.mem.resolve(key, current, ok, next, action)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case UpdateNone:
+		return nil
+	case UpdateDelete:
+		if !ok {
+			return nil
+		}
+		if err := b.append(encodeDelete(key)); err != nil {
+			return err
+		}
+		b.mem.remove(key)
+	default:
+		if err := b.mem.admit(next); err != nil {
+			return err
+		}
+		if err := b.append(encodeSet(next)); err != nil {
+			return err
+		}
+		if err := b.mem.put(next, false); err != nil {
+			return err
+		}
+		if b.evictErr != nil {
+			err := b.evictErr
+			b.evictErr = nil
+			return err
+		}
+	}
+	return b.maybeCompact()
+}
+
+// Scan returns up to count entries whose keys begin with the given prefix, in
+// lexicographical order, starting after the given cursor.
+func (b *FileBackend) Scan(ctx context.Context, prefix string, cursor string, count int) ([]Entry, string, error) {
+	return b.mem.Scan(ctx, prefix, cursor, count)
+}
+
+// Len returns the number of entries in the backend.
+func (b *FileBackend) Len(ctx context.Context) (int, error) {
+	return b.mem.Len(ctx)
+}
+
+// Flush removes all entries from the backend and truncates the file. Unlike
+// for Update, if an error wrapping ErrCompaction is returned the flush was not
+// applied and all entries are retained.
+func (b *FileBackend) Flush(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mem.mu.Lock()
+	defer b.mem.mu.Unlock()
+	if b.file == nil {
+		return errors.New("cache file is closed")
+	}
+	if err := b.rewrite(list.New()); err != nil {
+		return err
+	}
+	b.mem.reset()
+	return nil
+}
+
+// Sync flushes all acknowledged writes to stable storage.
+func (b *FileBackend) Sync() error {
+	b.mem.mu.Lock()
+	defer b.mem.mu.Unlock()
+	if b.file == nil {
+		return errors.New("cache file is closed")
+	}
+	return b.file.Sync()
+}
+
+// Close flushes all acknowledged writes to stable storage and closes the file.
+func (b *FileBackend) Close() error {
+	b.mem.mu.Lock()
+	defer b.mem.mu.Unlock()
+	if b.file == nil {
+		return nil
+	}
+	err := errors.Join(b.file.Sync(), b.file.Close())
+	b.file = nil
+	b.mem.reset()
+	return err
+}
+
+// Stats returns statistics about the usage of the backend.
+func (b *FileBackend) Stats() BackendStats {
+	return b.mem.Stats()
+}
+
+// open opens the file and replays its records.
+func (b *FileBackend) open() error {
+	f, err := os.OpenFile(b.path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	valid, err := b.replay(f)
+	if err == nil {
+		err = f.Truncate(valid)
+	}
+	if err == nil && valid == 0 {
+		_, err = f.WriteAt([]byte(fileMagic), 0)
+		valid = int64(len(fileMagic))
+	}
+	if err == nil {
+		_, err = f.Seek(valid, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	b.file = f
+	return nil
+}
+
+// replay loads the records of f into memory and returns the offset at which the
+// valid records end.
+func (b *FileBackend) replay(f *os.File) (int64, error) {
+	r := bufio.NewReader(f)
+	header := make([]byte, len(fileMagic))
+	if n, err := io.ReadFull(r, header); err != nil {
+		// An empty file, or one whose header was only partially written, is
+		// initialized from scratch.
+		if string(header[:n]) == fileMagic[:n] {
+			return 0, nil
+		}
+		return 0, errors.New("not a cache file")
+	}
+	if string(header) != fileMagic {
+		return 0, errors.New("not a cache file")
+	}
+
+	// Capacity is enforced while replaying, except for EvictNone where entries
+	// that no longer fit are kept rather than failing to open the file.
+	force := b.mem.constraints.Eviction == EvictNone
+	offset := int64(len(fileMagic))
+	for {
+		body, n, err := readRecord(r)
+		if err != nil {
+			// A missing or corrupt record marks the end of the log.
+			return offset, nil
+		}
+		switch body[0] {
+		case recordSet:
+			e, err := decodeSet(body)
+			if err != nil {
+				return offset, nil
+			}
+			_ = b.mem.put(e, force)
+		case recordDelete:
+			b.mem.remove(string(body[1:]))
+		case recordSeq:
+			seq, n := binary.Uvarint(body[1:])
+			if n <= 0 {
+				return offset, nil
+			}
+			b.mem.seq = max(b.mem.seq, seq)
+		default:
+			return offset, nil
+		}
+		offset += n
+		b.records++
+	}
+}
+
+// append writes a record to the end of the file. The caller must hold
+// b.mem.mu.
+func (b *FileBackend) append(body []byte) error {
+	// The record is written with a single call so that a crash leaves at most
+	// one partial record at the end of the file.
+	var rec bytes.Buffer
+	_ = writeRecord(&rec, body)
+	if _, err := b.file.Write(rec.Bytes()); err != nil {
+		return fmt.Errorf("unable to write to cache file '%s': %w", b.path, err)
+	}
+	b.records++
+	return nil
+}
+
+// maybeCompact compacts the file if most of its records have been superseded.
+// The caller must hold b.mem.mu.
+func (b *FileBackend) maybeCompact() error {
+	live := len(b.mem.entries)
+	if b.records-live < compactMinRecords || b.records < 2*live {
+		return nil
+	}
+	return b.compact()
+}
+
+// compact rewrites the file so that it only contains the current entries. The
+// caller must hold b.mem.mu.
+func (b *FileBackend) compact() error {
+	return b.rewrite(b.mem.order)
+}
+
+// rewrite replaces the file with one containing the entries of order, which
+// holds entries from the most to the least recently used. The new file is
+// written next to the old one and renamed over it, so a crash during the
+// rewrite leaves either the old or the new file intact. The caller must hold
+// b.mem.mu.
+func (b *FileBackend) rewrite(order *list.List) error {
+	tmp := b.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("%w '%s': %w", ErrCompaction, b.path, err)
+	}
+	w := bufio.NewWriter(f)
+	_, err = w.WriteString(fileMagic)
+	if err == nil {
+		err = writeRecord(w, binary.AppendUvarint([]byte{recordSeq}, b.mem.seq))
+	}
+	records := 1
+	// Entries are written from the least to the most recently used so that
+	// replaying the file restores the eviction order.
+	for el := order.Back(); el != nil && err == nil; el = el.Prev() {
+		err = writeRecord(w, encodeSet(el.Value.(Entry)))
+		records++
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, b.path)
+	}
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%w '%s': %w", ErrCompaction, b.path, err)
+	}
+	syncDir(filepath.Dir(b.path))
+
+	_ = b.file.Close()
+	b.file = f
+	b.records = records
+	return nil
+}
+
+// syncDir flushes a directory entry to stable storage on a best-effort basis.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+func writeRecord(w io.Writer, body []byte) error {
+	var header [8]byte
+	binary.LittleEndian.PutUint32(header[0:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(body))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// readRecord reads a record and returns its body along with the number of
+// bytes read.
+func readRecord(r io.Reader) ([]byte, int64, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, 0, err
+	}
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size == 0 || size > maxRecordSize {
+		return nil, 0, errors.New("invalid record size")
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, 0, errors.New("record checksum mismatch")
+	}
+	return body, int64(len(header)) + int64(size), nil
+}
+
+func encodeSet(e Entry) []byte {
+	body := make([]byte, 0, 1+4*binary.MaxVarintLen64+len(e.Key)+len(e.Value))
+	body = append(body, recordSet)
+	body = binary.AppendUvarint(body, uint64(len(e.Key)))
+	body = append(body, e.Key...)
+	body = binary.AppendUvarint(body, e.Version)
+	body = binary.AppendVarint(body, int64(e.TTL))
+	var expiresAt int64
+	if !e.ExpiresAt.IsZero() {
+		expiresAt = e.ExpiresAt.UnixNano()
+	}
+	body = binary.AppendVarint(body, expiresAt)
+	return append(body, e.Value...)
+}
+
+func decodeSet(body []byte) (Entry, error) {
+	rest := body[1:]
+	keyLen, n := binary.Uvarint(rest)
+	if n <= 0 || uint64(len(rest)-n) < keyLen {
+		return Entry{}, errors.New("invalid key")
+	}
+	e := Entry{Key: string(rest[n : n+int(keyLen)])}
+	rest = rest[n+int(keyLen):]
+
+	if e.Version, n = binary.Uvarint(rest); n <= 0 {
+		return Entry{}, errors.New("invalid version")
+	}
+	rest = rest[n:]
+	ttl, n := binary.Varint(rest)
+	if n <= 0 {
+		return Entry{}, errors.New("invalid TTL")
+	}
+	e.TTL = time.Duration(ttl)
+	rest = rest[n:]
+	expiresAt, n := binary.Varint(rest)
+	if n <= 0 {
+		return Entry{}, errors.New("invalid expiry")
+	}
+	if expiresAt != 0 {
+		e.ExpiresAt = time.Unix(0, expiresAt)
+	}
+	e.Value = append([]byte{}, rest[n:]...)
+	return e, nil
+}
+
+func encodeDelete(key string) []byte {
+	return append([]byte{recordDelete}, key...)
+}
@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileBackendPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	c := NewLocal(b)
+	for i := range 3 {
+		if err := c.SetBytes(ctx, fmt.Sprintf("k%d", i), []byte(fmt.Sprintf("v%d", i))); err != nil {
+			t.Fatalf("SetBytes() error = %v", err)
+		}
+	}
+	if err := c.Delete(ctx, "k1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	b, err = OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	defer b.Close()
+	c = NewLocal(b)
+	for key, want := range map[string]string{"k0": "v0", "k2": "v2"} {
+		got, err := c.GetBytes(ctx, key)
+		if err != nil || string(got) != want {
+			t.Errorf("GetBytes(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+	if _, err := c.GetBytes(ctx, "k1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetBytes(deleted) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileBackendTornTail(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	c := NewLocal(b)
+	if err := c.SetBytes(ctx, "a", []byte("1")); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetBytes(ctx, "b", []byte("2")); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Simulate a crash while the second record was being written.
+	full, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for size := info.Size(); size < int64(len(full)); size++ {
+		if err := os.WriteFile(path, full[:size], 0o600); err != nil {
+			t.Fatal(err)
+		}
+		b, err := OpenFileBackend(path)
+		if err != nil {
+			t.Fatalf("OpenFileBackend() with %d bytes error = %v", size, err)
+		}
+		c := NewLocal(b)
+		if got, err := c.GetBytes(ctx, "a"); err != nil || string(got) != "1" {
+			t.Errorf("GetBytes(a) with %d bytes = %q, %v", size, got, err)
+		}
+		if _, err := c.GetBytes(ctx, "b"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetBytes(b) with %d bytes error = %v, want ErrNotFound", size, err)
+		}
+		// Writes after the torn record must survive reopening.
+		if err := c.SetBytes(ctx, "c", []byte("3")); err != nil {
+			t.Fatalf("SetBytes() error = %v", err)
+		}
+		_ = b.Close()
+		b, err = OpenFileBackend(path)
+		if err != nil {
+			t.Fatalf("OpenFileBackend() error = %v", err)
+		}
+		if got, err := NewLocal(b).GetBytes(ctx, "c"); err != nil || string(got) != "3" {
+			t.Errorf("GetBytes(c) after reopening = %q, %v", got, err)
+		}
+		_ = b.Close()
+	}
+}
+
+func TestFileBackendCorruptRecord(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	if err := NewLocal(b).SetBytes(ctx, "a", []byte("value")); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	_ = b.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	b, err = OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	defer b.Close()
+	if _, err := NewLocal(b).GetBytes(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetBytes() of corrupt record error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileBackendCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	c := NewLocal(b)
+	for i := range 3 * compactMinRecords {
+		if err := c.SetBytes(ctx, "k", []byte(fmt.Sprint(i))); err != nil {
+			t.Fatalf("SetBytes() error = %v", err)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 64*compactMinRecords {
+		t.Errorf("file size = %d, file was not compacted", info.Size())
+	}
+	_ = b.Close()
+
+	b, err = OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	defer b.Close()
+	want := fmt.Sprint(3*compactMinRecords - 1)
+	if got, err := NewLocal(b).GetBytes(ctx, "k"); err != nil || string(got) != want {
+		t.Errorf("GetBytes() after compaction = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestFileBackendCompactionFailure(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	defer b.Close()
+	// A directory in place of the temporary file makes compaction fail.
+	if err := os.Mkdir(path+".tmp", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewLocal(b)
+	var compactErr error
+	for i := 0; i < 2*compactMinRecords && compactErr == nil; i++ {
+		err := c.SetBytes(ctx, "k", []byte(fmt.Sprint(i)))
+		switch {
+		case errors.Is(err, ErrCompaction):
+			compactErr = err
+		case err != nil:
+			t.Fatalf("SetBytes() error = %v", err)
+		}
+	}
+	if compactErr == nil {
+		t.Fatal("SetBytes() never returned ErrCompaction")
+	}
+	if _, err := c.GetBytes(ctx, "k"); err != nil {
+		t.Errorf("GetBytes() after failed compaction error = %v", err)
+	}
+}
+
+func TestFileBackendCompactionFailureNotifies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	defer b.Close()
+	if err := os.Mkdir(path+".tmp", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	c := NewLocal(b)
+	for i := 0; ; i++ {
+		err := c.SetBytes(ctx, "k", []byte(fmt.Sprint(i)))
+		if errors.Is(err, ErrCompaction) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("SetBytes() error = %v", err)
+		}
+	}
+
+	events, err := c.Watch(ctx, "")
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	err = c.SetMany(ctx, map[string][]byte{"a": nil, "b": nil})
+	if !errors.Is(err, ErrCompaction) {
+		t.Fatalf("SetMany() error = %v, want ErrCompaction", err)
+	}
+	got := map[string]bool{}
+	for range 2 {
+		e := <-events
+		got[e.Key] = e.Type == EventSet
+	}
+	if !got["a"] || !got["b"] {
+		t.Errorf("events = %v, want sets of a and b", got)
+	}
+	if values, err := c.GetMany(ctx, []string{"a", "b"}); err != nil || len(values) != 2 {
+		t.Errorf("GetMany() = %v, %v, want both keys", values, err)
+	}
+}
+
+func TestFileBackendFlushFailure(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	defer b.Close()
+	c := NewLocal(b)
+	if err := c.SetBytes(ctx, "k", []byte("v")); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	if err := os.Mkdir(path+".tmp", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Flush(ctx); !errors.Is(err, ErrCompaction) {
+		t.Fatalf("Flush() error = %v, want ErrCompaction", err)
+	}
+	if got, err := c.GetBytes(ctx, "k"); err != nil || string(got) != "v" {
+		t.Errorf("GetBytes() after failed flush = %q, %v, want %q", got, err, "v")
+	}
+
+	// The entry must also survive a restart, as the file was left unchanged.
+	if err := os.Remove(path + ".tmp"); err != nil {
+		t.Fatal(err)
+	}
+	_ = b.Close()
+	b, err = OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("OpenFileBackend() error = %v", err)
+	}
+	defer b.Close()
+	if got, err := NewLocal(b).GetBytes(ctx, "k"); err != nil || string(got) != "v" {
+		t.Errorf("GetBytes() after reopening = %q, %v, want %q", got, err, "v")
+	}
+}
@@ -0,0 +1,330 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// LoadFunc loads the value for a key from a backing system, such as an LDAP
+// directory. If the value does not exist, LoadFunc should return an error
+// wrapping ErrNotFound so that the absence can be cached.
+type LoadFunc[T any] func(ctx context.Context, key string) (T, error)
+
+// LoaderOptions are the options used to configure a Loader.
+type LoaderOptions struct {
+	// TTL is the duration for which a loaded value is considered fresh. Must be
+	// positive. Defaults to five minutes.
+	TTL time.Duration
+
+	// StaleTTL is the duration after a value stops being fresh during which the
+	// stale value is still returned while it is refreshed in the background. Zero
+	// disables stale-while-revalidate. Must not be negative.
+	StaleTTL time.Duration
+
+	// NegativeTTL is the duration for which the absence of a value is cached.
+	// Zero disables negative caching. Must not be negative.
+	NegativeTTL time.Duration
+
+	// Jitter randomizes TTL and NegativeTTL by up to the given fraction in either
+	// direction, e.g. 0.1 for ±10%, so that entries loaded at the same time do not
+	// expire at the same time. Must be at least 0 and less than 1.
+	Jitter float64
+
+	// LoadTimeout bounds the duration of a single call to the LoadFunc. Loads are
+	// shared between concurrent callers and are therefore not cancelled when an
+	// individual caller's context is cancelled. Zero means no timeout.
+	LoadTimeout time.Duration
+
+	// OnRefreshError is called when a background refresh fails. The stale value
+	// remains cached until it expires.
+	OnRefreshError func(key string, err error)
+
+	// TypedOptions configure the encoding of cached entries.
+	TypedOptions []TypedOption
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// LoaderOption is an option used to configure a Loader.
+type LoaderOption func(*LoaderOptions)
+
+// WithFreshTTL configures the duration for which a loaded value is considered
+// fresh.
+func WithFreshTTL(ttl time.Duration) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.TTL = ttl
+	}
+}
+
+// WithStaleWhileRevalidate configures the duration after a value stops being
+// fresh during which the stale value is returned while it is refreshed in the
+// background.
+func WithStaleWhileRevalidate(staleTTL time.Duration) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.StaleTTL = staleTTL
+	}
+}
+
+// WithNegativeTTL enables caching the absence of a value for the given duration.
+func WithNegativeTTL(ttl time.Duration) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.NegativeTTL = ttl
+	}
+}
+
+// WithJitter randomizes TTLs by up to the given fraction in either direction.
+func WithJitter(fraction float64) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.Jitter = fraction
+	}
+}
+
+// WithLoadTimeout bounds the duration of a single call to the LoadFunc.
+func WithLoadTimeout(timeout time.Duration) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.LoadTimeout = timeout
+	}
+}
+
+// WithRefreshErrorHandler configures a function that is called when a
+// background refresh fails.
+func WithRefreshErrorHandler(fn func(key string, err error)) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.OnRefreshError = fn
+	}
+}
+
+// WithLoaderTypedOptions configures the encoding of cached entries, e.g. the
+// codec or schema version.
+func WithLoaderTypedOptions(opts ...TypedOption) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.TypedOptions = append(o.TypedOptions, opts...)
+	}
+}
+
+// WithLoaderClock configures the function used to determine the current time.
+func WithLoaderClock(now func() time.Time) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.Now = now
+	}
+}
+
+// loaderEntry is the value stored in the Cache by a Loader.
+type loaderEntry[T any] struct {
+	Value      T         `json:"v" msgpack:"v"`
+	Missing    bool      `json:"m,omitempty" msgpack:"m,omitempty"`
+	FreshUntil time.Time `json:"f" msgpack:"f"`
+}
+
+// Loader is a read-through cache. On a cache miss, the value is loaded with a
+// LoadFunc and stored in the Cache. Concurrent requests for the same key on the
+// same Loader are coalesced into a single load, so that a login storm results
+// in one request to the backing system per user rather than one per login.
+//
+// If the underlying Cache returns an error other than ErrNotFound, e.g. because
+// it is unavailable or the cached entry cannot be decoded, the value is loaded
+// as on a cache miss, including coalescing with concurrent loads, so that an
+// unavailable cache does not block callers.
+//
+// Example:
+//
+//	c, _ := api.Cache("ldap")
+//	groups, err := cache.NewLoader(c, func(ctx context.Context, uid string) ([]string, error) {
+//		return searchGroups(ctx, uid)
+//	},
+//		cache.WithFreshTTL(5*time.Minute),
+//		cache.WithStaleWhileRevalidate(time.Hour),
+//		cache.WithNegativeTTL(time.Minute),
+//		cache.WithJitter(0.1),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//
+//	memberOf, err := groups.Get(ctx, uid)
+type Loader[T any] struct {
+	typed *Typed[loaderEntry[T]]
+	load  LoadFunc[T]
+	opts  LoaderOptions
+
+	mu      sync.Mutex
+	flights map[string]*flight[T]
+}
+
+// flight is an in-progress load shared by concurrent callers.
+type flight[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// NewLoader creates a Loader that stores values in c and loads missing values
+// with load. An error is returned if the options are invalid.
+func NewLoader[T any](c Cache, load LoadFunc[T], opts ...LoaderOption) (*Loader[T], error) {
+	o := LoaderOptions{
+		TTL: 5 * time.Minute,
+		Now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.TTL <= 0 {
+		return nil, fmt.Errorf("invalid TTL %v: must be positive", o.TTL)
+	}
+	if o.StaleTTL < 0 {
+		return nil, fmt.Errorf("invalid stale TTL %v: must not be negative", o.StaleTTL)
+	}
+	if o.NegativeTTL < 0 {
+		return nil, fmt.Errorf("invalid negative TTL %v: must not be negative", o.NegativeTTL)
+	}
+	if !(o.Jitter >= 0 && o.Jitter < 1) {
+		return nil, fmt.Errorf("invalid jitter %v: must be at least 0 and less than 1", o.Jitter)
+	}
+	return &Loader[T]{
+		typed:   NewTyped[loaderEntry[T]](c, o.TypedOptions...),
+		load:    load,
+		opts:    o,
+		flights: make(map[string]*flight[T]),
+	}, nil
+}
+
+// Get returns the value for a given key, loading it if it is not cached. If the
+// cached value is stale but within the stale-while-revalidate window, the stale
+// value is returned and refreshed in the background. If the value does not
+// exist, an error wrapping ErrNotFound is returned.
+func (l *Loader[T]) Get(ctx context.Context, key string) (T, error) {
+	e, err := l.typed.Get(ctx, key)
+	if err == nil {
+		if l.opts.Now().After(e.FreshUntil) {
+			l.refresh(ctx, key)
+		}
+		return l.result(key, e)
+	}
+	if !errors.Is(err, ErrNotFound) && ctx.Err() != nil {
+		var zero T
+		return zero, err
+	}
+
+	f := l.start(ctx, key)
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Invalidate removes the cached value for a given key so that the next call to
+// Get loads it again. An error wrapping ErrUnsupported is returned if the
+// underlying Cache does not implement Store.
+func (l *Loader[T]) Invalidate(ctx context.Context, key string) error {
+	return l.typed.Delete(ctx, key)
+}
+
+func (l *Loader[T]) result(key string, e loaderEntry[T]) (T, error) {
+	if e.Missing {
+		var zero T
+		return zero, fmt.Errorf("key '%s': %w", key, ErrNotFound)
+	}
+	return e.Value, nil
+}
+
+// refresh reloads a stale value in the background unless a load for the key is
+// already in progress.
+func (l *Loader[T]) refresh(ctx context.Context, key string) {
+	f := l.start(ctx, key)
+	if l.opts.OnRefreshError == nil {
+		return
+	}
+	go func() {
+		<-f.done
+		if f.err != nil && !errors.Is(f.err, ErrNotFound) {
+			l.opts.OnRefreshError(key, f.err)
+		}
+	}()
+}
+
+// start returns the in-progress load for key, starting a new one if necessary.
+func (l *Loader[T]) start(ctx context.Context, key string) *flight[T] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if f, ok := l.flights[key]; ok {
+		return f
+	}
+	f := &flight[T]{done: make(chan struct{})}
+	l.flights[key] = f
+
+	// The load is shared by all callers, so it must not be cancelled when the
+	// caller that started it goes away.
+	loadCtx := context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			l.mu.Lock()
+			delete(l.flights, key)
+			l.mu.Unlock()
+			close(f.done)
+		}()
+		f.value, f.err = l.loadAndStore(loadCtx, key)
+	}()
+	return f
+}
+
+func (l *Loader[T]) loadAndStore(ctx context.Context, key string) (T, error) {
+	if l.opts.LoadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.opts.LoadTimeout)
+		defer cancel()
+	}
+
+	value, err := l.safeLoad(ctx, key)
+	switch {
+	case err == nil:
+		ttl := l.jitter(l.opts.TTL)
+		// Failing to cache the value must not fail the load.
+		_ = l.typed.Set(ctx, key, loaderEntry[T]{
+			Value:      value,
+			FreshUntil: l.opts.Now().Add(ttl),
+		}, WithTTL(ttl+l.opts.StaleTTL))
+		return value, nil
+	case errors.Is(err, ErrNotFound):
+		if l.opts.NegativeTTL > 0 {
+			ttl := l.jitter(l.opts.NegativeTTL)
+			_ = l.typed.Set(ctx, key, loaderEntry[T]{
+				Missing:    true,
+				FreshUntil: l.opts.Now().Add(ttl),
+			}, WithTTL(ttl+l.opts.StaleTTL))
+		}
+		var zero T
+		return zero, err
+	default:
+		var zero T
+		return zero, fmt.Errorf("unable to load key '%s': %w", key, err)
+	}
+}
+
+// safeLoad calls the LoadFunc, converting a panic into an error. The load runs
+// in its own goroutine, so an unrecovered panic would crash the process and
+// leave waiting callers blocked.
+func (l *Loader[T]) safeLoad(ctx context.Context, key string) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var zero T
+			value, err = zero, fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return l.load(ctx, key)
+}
+
+func (l *Loader[T]) jitter(ttl time.Duration) time.Duration {
+	if l.opts.Jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	delta := (rand.Float64()*2 - 1) * l.opts.Jitter * float64(ttl)
+	return ttl + time.Duration(delta)
+}
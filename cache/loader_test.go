@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoaderCoalescesConcurrentLoads(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	l, err := NewLoader(newMapCache(), func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		<-release
+		return "v:" + key, nil
+	})
+	if err != nil {
+		t.Fatalf("NewLoader() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := l.Get(context.Background(), "k")
+			if err == nil && v != "v:k" {
+				err = fmt.Errorf("Get() = %q, want %q", v, "v:k")
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("LoadFunc called %d times, want 1", n)
+	}
+
+	// The loaded value is served from the cache.
+	if _, err := l.Get(context.Background(), "k"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("LoadFunc called %d times after cache hit, want 1", n)
+	}
+}
+
+func TestLoaderNegativeCaching(t *testing.T) {
+	var calls atomic.Int32
+	l, err := NewLoader(newMapCache(), func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		return "", ErrNotFound
+	}, WithNegativeTTL(time.Minute))
+	if err != nil {
+		t.Fatalf("NewLoader() error = %v", err)
+	}
+	for range 2 {
+		if _, err := l.Get(context.Background(), "k"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get() error = %v, want ErrNotFound", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("LoadFunc called %d times, want 1", n)
+	}
+}
+
+func TestLoaderRecoversPanic(t *testing.T) {
+	release := make(chan struct{})
+	l, err := NewLoader(newMapCache(), func(ctx context.Context, key string) (string, error) {
+		<-release
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatalf("NewLoader() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := l.Get(context.Background(), "k")
+			errs <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("Get() error = %v, want panic error", err)
+		}
+	}
+}
+
+func TestNewLoaderInvalidJitter(t *testing.T) {
+	load := func(ctx context.Context, key string) (string, error) { return "", nil }
+	for _, jitter := range []float64{-0.1, 1, 2, math.NaN()} {
+		if _, err := NewLoader(newMapCache(), load, WithJitter(jitter)); err == nil {
+			t.Errorf("NewLoader(WithJitter(%v)) succeeded", jitter)
+		}
+	}
+	if _, err := NewLoader(newMapCache(), load, WithJitter(0.5)); err != nil {
+		t.Errorf("NewLoader(WithJitter(0.5)) error = %v", err)
+	}
+}
+
+func TestNewLoaderInvalidTTL(t *testing.T) {
+	load := func(ctx context.Context, key string) (string, error) { return "", nil }
+	tests := []struct {
+		name string
+		opt  LoaderOption
+	}{
+		{"zero fresh TTL", WithFreshTTL(0)},
+		{"negative fresh TTL", WithFreshTTL(-time.Second)},
+		{"negative stale TTL", WithStaleWhileRevalidate(-time.Second)},
+		{"negative negative TTL", WithNegativeTTL(-time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLoader(newMapCache(), load, tt.opt); err == nil {
+				t.Error("NewLoader() succeeded")
+			}
+		})
+	}
+}
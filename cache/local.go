@@ -0,0 +1,464 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subscriptionBuffer is the number of undelivered messages or events buffered
+// per subscriber before further ones are dropped.
+const subscriptionBuffer = 64
+
+// LocalOptions are the options used to configure a Cache created with NewLocal.
+type LocalOptions struct {
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// LocalOption is an option used to configure a Cache created with NewLocal.
+type LocalOption func(*LocalOptions)
+
+// WithClock configures the function used to determine the current time, e.g.
+// to control expiry in tests.
+func WithClock(now func() time.Time) LocalOption {
+	return func(o *LocalOptions) {
+		o.Now = now
+	}
+}
+
+// NewLocal creates a Cache supporting all optional operations, storing entries
+// in the given Backend. Expired entries are removed lazily when they are
+// accessed, at which point an EventExpire is reported to watchers. Entries
+// evicted by the Backend to stay within its capacity are not reported.
+//
+// If the Backend returns an error wrapping ErrCompaction, the write was applied:
+// watchers are notified as usual and the error is returned once the remaining
+// keys of a batch operation have been written.
+//
+// Messages and events are delivered within the process only, to subscribers
+// and watchers of the returned Cache. Closing the Backend is the
+// responsibility of the caller.
+//
+// Example:
+//
+//	c := cache.NewLocal(cache.NewMemoryBackend(
+//		cache.WithMaxEntries(10000),
+//		cache.WithEviction(cache.EvictLRU),
+//	))
+func NewLocal(backend Backend, opts ...LocalOption) Extended {
+	o := LocalOptions{Now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &localCache{
+		backend:     backend,
+		now:         o.Now,
+		subscribers: make(map[string]map[chan Message]struct{}),
+		watchers:    make(map[chan Event]string),
+	}
+}
+
+type localCache struct {
+	backend Backend
+	now     func() time.Time
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan Message]struct{}
+	watchers    map[chan Event]string
+}
+
+func (c *localCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	e, ok, err := c.load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, notFound(key)
+	}
+	return e.Value, nil
+}
+
+func (c *localCache) SetBytes(ctx context.Context, key string, value []byte, opts ...Option) error {
+	return c.SetMany(ctx, map[string][]byte{key: value}, opts...)
+}
+
+func (c *localCache) Delete(ctx context.Context, key string) error {
+	return c.DeleteMany(ctx, []string{key})
+}
+
+func (c *localCache) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok, err := c.load(ctx, key)
+	return ok, err
+}
+
+func (c *localCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	e, ok, err := c.load(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, notFound(key)
+	}
+	if e.ExpiresAt.IsZero() {
+		return 0, nil
+	}
+	return e.ExpiresAt.Sub(c.now()), nil
+}
+
+func (c *localCache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return c.setTTL(ctx, key, func(Entry) time.Duration { return ttl })
+}
+
+func (c *localCache) Touch(ctx context.Context, key string) error {
+	return c.setTTL(ctx, key, func(e Entry) time.Duration { return e.TTL })
+}
+
+func (c *localCache) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		e, ok, err := c.load(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			values[key] = e.Value
+		}
+	}
+	return values, nil
+}
+
+func (c *localCache) SetMany(ctx context.Context, values map[string][]byte, opts ...Option) error {
+	o := newOptions(opts)
+	var compactErr error
+	for key, value := range values {
+		err := c.backend.Update(ctx, key, func(Entry, bool) (Entry, UpdateAction, error) {
+			return c.withTTL(Entry{Value: value}, o.TTL), UpdateSet, nil
+		})
+		if !persisted(err) {
+			return err
+		}
+		c.notify(EventSet, key)
+		if compactErr == nil {
+			compactErr = err
+		}
+	}
+	return compactErr
+}
+
+func (c *localCache) DeleteMany(ctx context.Context, keys []string) error {
+	var compactErr error
+	for _, key := range keys {
+		event := EventType(0)
+		err := c.backend.Update(ctx, key, func(e Entry, ok bool) (Entry, UpdateAction, error) {
+			switch {
+			case !ok:
+				return Entry{}, UpdateNone, nil
+			case e.Expired(c.now()):
+				event = EventExpire
+			default:
+				event = EventDelete
+			}
+			return Entry{}, UpdateDelete, nil
+		})
+		if !persisted(err) {
+			return err
+		}
+		if event != 0 {
+			c.notify(event, key)
+		}
+		if compactErr == nil {
+			compactErr = err
+		}
+	}
+	return compactErr
+}
+
+func (c *localCache) SetIfAbsent(ctx context.Context, key string, value []byte, opts ...Option) (bool, error) {
+	return c.CompareAndSwap(ctx, key, "", value, opts...)
+}
+
+func (c *localCache) GetWithVersion(ctx context.Context, key string) ([]byte, Version, error) {
+	e, ok, err := c.load(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "", notFound(key)
+	}
+	return e.Value, formatVersion(e.Version), nil
+}
+
+func (c *localCache) CompareAndSwap(ctx context.Context, key string, version Version, value []byte, opts ...Option) (bool, error) {
+	o := newOptions(opts)
+	swapped, expired := false, false
+	err := c.backend.Update(ctx, key, func(e Entry, ok bool) (Entry, UpdateAction, error) {
+		var current Version
+		if ok && e.Expired(c.now()) {
+			expired = true
+		} else if ok {
+			current = formatVersion(e.Version)
+		}
+		if current != version {
+			return Entry{}, UpdateNone, nil
+		}
+		swapped = true
+		return c.withTTL(Entry{Value: value}, o.TTL), UpdateSet, nil
+	})
+	if !persisted(err) {
+		return false, err
+	}
+	if expired {
+		c.notify(EventExpire, key)
+	}
+	if swapped {
+		c.notify(EventSet, key)
+	}
+	return swapped, err
+}
+
+func (c *localCache) Increment(ctx context.Context, key string, delta int64, opts ...Option) (int64, error) {
+	return c.add(ctx, key, opts, func(current int64) (int64, bool) {
+		n := current + delta
+		return n, (delta > 0 && n < current) || (delta < 0 && n > current)
+	})
+}
+
+func (c *localCache) Decrement(ctx context.Context, key string, delta int64, opts ...Option) (int64, error) {
+	return c.add(ctx, key, opts, func(current int64) (int64, bool) {
+		n := current - delta
+		return n, (delta > 0 && n > current) || (delta < 0 && n < current)
+	})
+}
+
+func (c *localCache) Scan(ctx context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	if count <= 0 {
+		return nil, "", fmt.Errorf("scan count must be positive, got %d", count)
+	}
+	entries, next, err := c.backend.Scan(ctx, prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	now := c.now()
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.Expired(now) {
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys, next, nil
+}
+
+func (c *localCache) Len(ctx context.Context) (int, error) {
+	return c.backend.Len(ctx)
+}
+
+func (c *localCache) Flush(ctx context.Context) error {
+	if err := c.backend.Flush(ctx); err != nil {
+		return err
+	}
+	c.notify(EventFlush, "")
+	return nil
+}
+
+func (c *localCache) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	msg := Message{
+		Topic:       topic,
+		Payload:     append([]byte(nil), payload...),
+		PublishedAt: c.now(),
+	}
+	for ch := range c.subscribers[topic] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+	return nil
+}
+
+func (c *localCache) Subscribe(ctx context.Context, topic string) (<-chan Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ch := make(chan Message, subscriptionBuffer)
+	c.mu.Lock()
+	if c.subscribers[topic] == nil {
+		c.subscribers[topic] = make(map[chan Message]struct{})
+	}
+	c.subscribers[topic][ch] = struct{}{}
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subscribers[topic], ch)
+		if len(c.subscribers[topic]) == 0 {
+			delete(c.subscribers, topic)
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func (c *localCache) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ch := make(chan Event, subscriptionBuffer)
+	c.mu.Lock()
+	c.watchers[ch] = prefix
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.watchers, ch)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// load returns the live entry for key, removing it if it has expired.
+func (c *localCache) load(ctx context.Context, key string) (Entry, bool, error) {
+	e, ok, err := c.backend.Get(ctx, key)
+	if err != nil || !ok {
+		return Entry{}, false, err
+	}
+	if !e.Expired(c.now()) {
+		return e, true, nil
+	}
+
+	expired := false
+	err = c.backend.Update(ctx, key, func(e Entry, ok bool) (Entry, UpdateAction, error) {
+		// The key may have been written since it was read.
+		if !ok || !e.Expired(c.now()) {
+			return Entry{}, UpdateNone, nil
+		}
+		expired = true
+		return Entry{}, UpdateDelete, nil
+	})
+	if !persisted(err) {
+		return Entry{}, false, err
+	}
+	if expired {
+		c.notify(EventExpire, key)
+	}
+	return Entry{}, false, err
+}
+
+// add atomically replaces the counter stored at key with the result of op,
+// treating a missing or expired key as zero. op reports whether the result
+// overflowed.
+func (c *localCache) add(ctx context.Context, key string, opts []Option, op func(current int64) (n int64, overflow bool)) (int64, error) {
+	o := newOptions(opts)
+	var n int64
+	err := c.backend.Update(ctx, key, func(e Entry, ok bool) (Entry, UpdateAction, error) {
+		created := !ok || e.Expired(c.now())
+		var current int64
+		if !created {
+			var err error
+			if current, err = strconv.ParseInt(string(e.Value), 10, 64); err != nil {
+				return Entry{}, UpdateNone, fmt.Errorf("key '%s': %w", key, ErrNotInteger)
+			}
+		}
+		var overflow bool
+		if n, overflow = op(current); overflow {
+			return Entry{}, UpdateNone, fmt.Errorf("key '%s': %w", key, ErrOverflow)
+		}
+		if created {
+			return c.withTTL(Entry{Value: []byte(strconv.FormatInt(n, 10))}, o.TTL), UpdateSet, nil
+		}
+		e.Value = []byte(strconv.FormatInt(n, 10))
+		return e, UpdateSet, nil
+	})
+	if !persisted(err) {
+		return 0, err
+	}
+	c.notify(EventSet, key)
+	return n, err
+}
+
+// setTTL replaces the TTL of a live key with the TTL returned by ttl.
+func (c *localCache) setTTL(ctx context.Context, key string, ttl func(e Entry) time.Duration) error {
+	expired := false
+	err := c.backend.Update(ctx, key, func(e Entry, ok bool) (Entry, UpdateAction, error) {
+		if !ok {
+			return Entry{}, UpdateNone, notFound(key)
+		}
+		if e.Expired(c.now()) {
+			expired = true
+			return Entry{}, UpdateDelete, nil
+		}
+		return c.withTTL(e, ttl(e)), UpdateSetTTL, nil
+	})
+	if !persisted(err) {
+		return err
+	}
+	if expired {
+		c.notify(EventExpire, key)
+		return notFound(key)
+	}
+	return err
+}
+
+// notify reports a change to all watchers whose prefix matches the key. Flush
+// events are reported to all watchers.
+func (c *localCache) notify(typ EventType, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ch, prefix := range c.watchers {
+		if typ != EventFlush && !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		select {
+		case ch <- Event{Type: typ, Key: key}:
+		default:
+		}
+	}
+}
+
+// withTTL returns e expiring after ttl from now. A ttl of zero or less removes
+// the expiry.
+func (c *localCache) withTTL(e Entry, ttl time.Duration) Entry {
+	e.TTL = 0
+	e.ExpiresAt = time.Time{}
+	if ttl > 0 {
+		e.TTL = ttl
+		e.ExpiresAt = c.now().Add(ttl)
+	}
+	return e
+}
+
+func newOptions(opts []Option) Options {
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func formatVersion(v uint64) Version {
+	return Version(strconv.FormatUint(v, 10))
+}
+
+// persisted reports whether a write whose Backend.Update returned err was
+// applied. Errors wrapping ErrCompaction are returned after the write was
+// persisted, so its notifications are still due.
+func persisted(err error) bool {
+	return err == nil || errors.Is(err, ErrCompaction)
+}
+
+func notFound(key string) error {
+	return fmt.Errorf("key '%s': %w", key, ErrNotFound)
+}
@@ -0,0 +1,350 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// scanCursorPrefix is prepended to the last key returned by Scan to form the
+// cursor, so that a cursor is never empty, even for the empty key.
+const scanCursorPrefix = "k:"
+
+// MemoryBackend is a Backend holding entries in memory. When the MaxEntries or
+// MaxBytes constraints are reached, entries are evicted according to the
+// Eviction constraint.
+//
+// Example:
+//
+//	backend := cache.NewMemoryBackend(cache.WithMaxEntries(10000))
+//	c := cache.NewLocal(backend)
+type MemoryBackend struct {
+	constraints Constraints
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the entries from the most to the least recently used, or
+	// inserted if the eviction policy is EvictFIFO.
+	order *list.List
+	// index holds the keys in lexicographical order for Scan. It may contain
+	// keys that have since been removed. Keys added since the index was last
+	// brought up to date are held in pending.
+	index   []string
+	pending []string
+	bytes   int64
+	// seq is the last version assigned to a write.
+	seq   uint64
+	stats BackendStats
+
+	// onEvict is called with b.mu held for every entry evicted to stay within
+	// capacity.
+	onEvict func(e Entry)
+}
+
+var _ Backend = (*MemoryBackend)(nil)
+
+// NewMemoryBackend creates an empty MemoryBackend with the given capacity and
+// eviction policy. The Name constraint is ignored.
+func NewMemoryBackend(constraints ...Constraint) *MemoryBackend {
+	c := Constraints{Eviction: EvictLRU}
+	for _, opt := range constraints {
+		opt(&c)
+	}
+	return &MemoryBackend{
+		constraints: c,
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+	}
+}
+
+// Get returns the entry for a given key.
+func (b *MemoryBackend) Get(ctx context.Context, key string) (Entry, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Entry{}, false, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.get(key, true)
+	if ok {
+		b.stats.Hits++
+	} else {
+		b.stats.Misses++
+	}
+	return cloneEntry(e), ok, nil
+}
+
+// Update atomically applies fn to the entry of a given key.
+func (b *MemoryBackend) Update(ctx context.Context, key string, fn UpdateFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current, ok := b.get(key, false)
+	next, action, err := fn(cloneEntry(current), ok)
+	if err != nil {
+		return err
+	}
+	next, err = b.resolve(key, current, ok, next, action)
+	if err != nil || action == UpdateNone {
+		return err
+	}
+	if action == UpdateDelete {
+		b.remove(key)
+		return nil
+	}
+	return b.put(next, false)
+}
+
+// Scan returns up to count entries whose keys begin with the given prefix, in
+// lexicographical order, starting after the given cursor. The cursor encodes
+// the last key returned by the previous call.
+func (b *MemoryBackend) Scan(ctx context.Context, prefix string, cursor string, count int) ([]Entry, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	if count <= 0 {
+		return nil, "", fmt.Errorf("scan count must be positive, got %d", count)
+	}
+	after, resume := strings.CutPrefix(cursor, scanCursorPrefix)
+	if cursor != "" && !resume {
+		return nil, "", fmt.Errorf("invalid scan cursor '%s'", cursor)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.updateIndex()
+	i := sort.SearchStrings(b.index, prefix)
+	if resume {
+		i = max(i, sort.Search(len(b.index), func(i int) bool { return b.index[i] > after }))
+	}
+
+	entries := make([]Entry, 0, min(count, len(b.entries)))
+	for ; i < len(b.index) && strings.HasPrefix(b.index[i], prefix); i++ {
+		el, ok := b.entries[b.index[i]]
+		if !ok {
+			continue
+		}
+		if len(entries) == count {
+			return entries, scanCursorPrefix + entries[len(entries)-1].Key, nil
+		}
+		entries = append(entries, cloneEntry(el.Value.(Entry)))
+	}
+	return entries, "", nil
+}
+
+// Len returns the number of entries in the backend.
+func (b *MemoryBackend) Len(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries), nil
+}
+
+// Flush removes all entries from the backend.
+func (b *MemoryBackend) Flush(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+	return nil
+}
+
+// Close removes all entries from the backend.
+func (b *MemoryBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+	return nil
+}
+
+// Stats returns statistics about the usage of the backend.
+func (b *MemoryBackend) Stats() BackendStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.stats
+	s.Entries = len(b.entries)
+	s.Bytes = b.bytes
+	return s
+}
+
+// get returns the entry for key. If touch is true and the eviction policy is
+// EvictLRU, the entry is marked as the most recently used. The caller must hold
+// b.mu.
+func (b *MemoryBackend) get(key string, touch bool) (Entry, bool) {
+	el, ok := b.entries[key]
+	if !ok {
+		return Entry{}, false
+	}
+	if touch && b.constraints.Eviction == EvictLRU {
+		b.order.MoveToFront(el)
+	}
+	return el.Value.(Entry), true
+}
+
+// resolve returns the entry to store for the result of an UpdateFunc, assigning
+// a new version if the value is written. The caller must hold b.mu.
+func (b *MemoryBackend) resolve(key string, current Entry, ok bool, next Entry, action UpdateAction) (Entry, error) {
+	switch action {
+	case UpdateNone:
+		return Entry{}, nil
+	case UpdateDelete:
+		return Entry{Key: key}, nil
+	case UpdateSet:
+		b.seq++
+		return Entry{
+			Key:       key,
+			Value:     append([]byte(nil), next.Value...),
+			Version:   b.seq,
+			TTL:       next.TTL,
+			ExpiresAt: next.ExpiresAt,
+		}, nil
+	case UpdateSetTTL:
+		if !ok {
+			return Entry{}, fmt.Errorf("unable to set TTL of key '%s': %w", key, ErrNotFound)
+		}
+		current.TTL = next.TTL
+		current.ExpiresAt = next.ExpiresAt
+		return current, nil
+	default:
+		return Entry{}, fmt.Errorf("invalid update action %s for key '%s'", action, key)
+	}
+}
+
+// put stores e, evicting other entries as required by the eviction policy. If
+// force is true, the capacity is not enforced. The caller must hold b.mu.
+func (b *MemoryBackend) put(e Entry, force bool) error {
+	if !force {
+		if err := b.admit(e); err != nil {
+			return err
+		}
+	}
+
+	if el, ok := b.entries[e.Key]; ok {
+		old := el.Value.(Entry)
+		b.bytes += e.Size() - old.Size()
+		el.Value = e
+		if b.constraints.Eviction == EvictLRU {
+			b.order.MoveToFront(el)
+		}
+	} else {
+		b.entries[e.Key] = b.order.PushFront(e)
+		b.pending = append(b.pending, e.Key)
+		b.bytes += e.Size()
+		// Bound the pending keys of a backend that is written to but not scanned.
+		if len(b.pending) > len(b.entries) {
+			b.updateIndex()
+		}
+	}
+	if e.Version > b.seq {
+		b.seq = e.Version
+	}
+
+	if force || b.constraints.Eviction == EvictNone {
+		return nil
+	}
+	for b.overCapacity() {
+		el := b.order.Back()
+		victim := el.Value.(Entry)
+		if victim.Key == e.Key {
+			break
+		}
+		b.remove(victim.Key)
+		b.stats.Evictions++
+		if b.onEvict != nil {
+			b.onEvict(victim)
+		}
+	}
+	return nil
+}
+
+// admit returns an error if e cannot be stored within the capacity of the
+// backend. The caller must hold b.mu.
+func (b *MemoryBackend) admit(e Entry) error {
+	c := b.constraints
+	if c.MaxBytes > 0 && e.Size() > c.MaxBytes {
+		return fmt.Errorf("key '%s' of %d bytes exceeds the cache size of %d bytes: %w", e.Key, e.Size(), c.MaxBytes, ErrCacheFull)
+	}
+	if c.Eviction != EvictNone {
+		return nil
+	}
+
+	entries, bytes := len(b.entries), b.bytes+e.Size()
+	if el, ok := b.entries[e.Key]; ok {
+		bytes -= el.Value.(Entry).Size()
+	} else {
+		entries++
+	}
+	if (c.MaxEntries > 0 && entries > c.MaxEntries) || (c.MaxBytes > 0 && bytes > c.MaxBytes) {
+		return fmt.Errorf("unable to store key '%s': %w", e.Key, ErrCacheFull)
+	}
+	return nil
+}
+
+func (b *MemoryBackend) overCapacity() bool {
+	c := b.constraints
+	return (c.MaxEntries > 0 && len(b.entries) > c.MaxEntries) || (c.MaxBytes > 0 && b.bytes > c.MaxBytes)
+}
+
+// remove deletes the entry for key. The caller must hold b.mu.
+func (b *MemoryBackend) remove(key string) {
+	el, ok := b.entries[key]
+	if !ok {
+		return
+	}
+	b.bytes -= el.Value.(Entry).Size()
+	b.order.Remove(el)
+	delete(b.entries, key)
+}
+
+// reset removes all entries. The version sequence is preserved so that versions
+// are never reused. The caller must hold b.mu.
+func (b *MemoryBackend) reset() {
+	b.entries = make(map[string]*list.Element)
+	b.order.Init()
+	b.index = nil
+	b.pending = nil
+	b.bytes = 0
+}
+
+// updateIndex merges the pending keys into the index, dropping keys that have
+// been removed. Only the pending keys are sorted. The caller must hold b.mu.
+func (b *MemoryBackend) updateIndex() {
+	if len(b.pending) == 0 {
+		return
+	}
+	sort.Strings(b.pending)
+	index := make([]string, 0, len(b.entries))
+	i, j := 0, 0
+	for i < len(b.index) || j < len(b.pending) {
+		var key string
+		if j == len(b.pending) || (i < len(b.index) && b.index[i] <= b.pending[j]) {
+			key, i = b.index[i], i+1
+		} else {
+			key, j = b.pending[j], j+1
+		}
+		if _, ok := b.entries[key]; !ok {
+			continue
+		}
+		if n := len(index); n > 0 && index[n-1] == key {
+			continue
+		}
+		index = append(index, key)
+	}
+	b.index = index
+	b.pending = nil
+}
+
+func cloneEntry(e Entry) Entry {
+	if e.Value != nil {
+		e.Value = append([]byte(nil), e.Value...)
+	}
+	return e
+}
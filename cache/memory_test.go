@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMemoryBackendEviction(t *testing.T) {
+	tests := []struct {
+		policy EvictionPolicy
+		// evicted is the key evicted when "d" is written after "a" was
+		// overwritten, read and had its TTL changed.
+		evicted string
+	}{
+		{EvictLRU, "b"},
+		{EvictFIFO, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			ctx := context.Background()
+			c := NewLocal(NewMemoryBackend(WithMaxEntries(3), WithEviction(tt.policy)))
+			for _, key := range []string{"a", "b", "c"} {
+				if err := c.SetBytes(ctx, key, []byte(key)); err != nil {
+					t.Fatalf("SetBytes() error = %v", err)
+				}
+			}
+			if err := c.SetBytes(ctx, "a", []byte("a2")); err != nil {
+				t.Fatalf("SetBytes() error = %v", err)
+			}
+			if _, err := c.GetBytes(ctx, "a"); err != nil {
+				t.Fatalf("GetBytes() error = %v", err)
+			}
+			if err := c.Expire(ctx, "a", time.Hour); err != nil {
+				t.Fatalf("Expire() error = %v", err)
+			}
+			if err := c.SetBytes(ctx, "d", []byte("d")); err != nil {
+				t.Fatalf("SetBytes() error = %v", err)
+			}
+
+			for _, key := range []string{"a", "b", "c", "d"} {
+				_, err := c.GetBytes(ctx, key)
+				if key == tt.evicted && !errors.Is(err, ErrNotFound) {
+					t.Errorf("GetBytes(%q) error = %v, want ErrNotFound", key, err)
+				}
+				if key != tt.evicted && err != nil {
+					t.Errorf("GetBytes(%q) error = %v", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryBackendEvictNone(t *testing.T) {
+	ctx := context.Background()
+	c := NewLocal(NewMemoryBackend(WithMaxEntries(1), WithEviction(EvictNone)))
+	if err := c.SetBytes(ctx, "a", nil); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	if err := c.SetBytes(ctx, "b", nil); !errors.Is(err, ErrCacheFull) {
+		t.Errorf("SetBytes() error = %v, want ErrCacheFull", err)
+	}
+	if err := c.SetBytes(ctx, "a", []byte("a")); err != nil {
+		t.Errorf("SetBytes() overwriting error = %v", err)
+	}
+}
+
+func TestMemoryBackendScanEmptyKey(t *testing.T) {
+	ctx := context.Background()
+	c := NewLocal(NewMemoryBackend())
+	for _, key := range []string{"", "a"} {
+		if err := c.SetBytes(ctx, key, nil); err != nil {
+			t.Fatalf("SetBytes(%q) error = %v", key, err)
+		}
+	}
+	keys, next, err := c.Scan(ctx, "", "", 1)
+	if err != nil || len(keys) != 1 || keys[0] != "" {
+		t.Fatalf("Scan() = %q, %q, %v, want the empty key", keys, next, err)
+	}
+	if next == "" {
+		t.Fatal("Scan() returned an empty cursor before the scan was complete")
+	}
+	keys, next, err = c.Scan(ctx, "", next, 1)
+	if err != nil || len(keys) != 1 || keys[0] != "a" || next != "" {
+		t.Errorf("Scan() = %q, %q, %v, want [\"a\"] and an empty cursor", keys, next, err)
+	}
+}
+
+func TestMemoryBackendScanInvalidCursor(t *testing.T) {
+	c := NewLocal(NewMemoryBackend())
+	if _, _, err := c.Scan(context.Background(), "", "bogus", 1); err == nil {
+		t.Error("Scan() with invalid cursor error = nil")
+	}
+}
+
+func TestMemoryBackendScanConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	c := NewLocal(NewMemoryBackend())
+	for i := range 10 {
+		if err := c.SetBytes(ctx, fmt.Sprintf("k%02d", i), nil); err != nil {
+			t.Fatalf("SetBytes() error = %v", err)
+		}
+	}
+	keys, next, err := c.Scan(ctx, "", "", 5)
+	if err != nil || next == "" {
+		t.Fatalf("Scan() = %q, %q, %v", keys, next, err)
+	}
+	// Keys deleted or added after the cursor are reflected by later pages, and
+	// keys that exist for the whole scan are returned.
+	if err := c.Delete(ctx, "k07"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := c.SetBytes(ctx, "k055", nil); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	rest, next, err := c.Scan(ctx, "", next, 100)
+	if err != nil || next != "" {
+		t.Fatalf("Scan() = %q, %q, %v", rest, next, err)
+	}
+	want := []string{"k05", "k055", "k06", "k08", "k09"}
+	if !slices.Equal(rest, want) {
+		t.Errorf("Scan() = %q, want %q", rest, want)
+	}
+}
@@ -0,0 +1,921 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The MessagePack format is specified at
+// https://github.com/msgpack/msgpack/blob/master/spec.md.
+const (
+	mpNil      = 0xc0
+	mpFalse    = 0xc2
+	mpTrue     = 0xc3
+	mpBin8     = 0xc4
+	mpBin16    = 0xc5
+	mpBin32    = 0xc6
+	mpExt8     = 0xc7
+	mpFloat32  = 0xca
+	mpFloat64  = 0xcb
+	mpUint8    = 0xcc
+	mpUint16   = 0xcd
+	mpUint32   = 0xce
+	mpUint64   = 0xcf
+	mpInt8     = 0xd0
+	mpInt16    = 0xd1
+	mpInt32    = 0xd2
+	mpInt64    = 0xd3
+	mpFixExt4  = 0xd6
+	mpFixExt8  = 0xd7
+	mpStr8     = 0xd9
+	mpStr16    = 0xda
+	mpStr32    = 0xdb
+	mpArray16  = 0xdc
+	mpArray32  = 0xdd
+	mpMap16    = 0xde
+	mpMap32    = 0xdf
+	mpExtTime  = 0xff // timestamp extension type -1
+	mpFixStr   = 0xa0
+	mpFixArray = 0x90
+	mpFixMap   = 0x80
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var errMsgpackShort = errors.New("msgpack: unexpected end of data")
+
+// msgpackMaxDepth is the maximum nesting depth of arrays and maps accepted by
+// the decoder, so that corrupt data cannot exhaust the stack. The encoder
+// applies the same limit to nested values, which also stops it from following
+// cyclic pointers, maps or slices forever.
+const msgpackMaxDepth = 1000
+
+type msgpackEncoder struct {
+	buf   []byte
+	depth int
+}
+
+func (e *msgpackEncoder) encode(v any) error {
+	return e.encodeValue(reflect.ValueOf(v))
+}
+
+func (e *msgpackEncoder) encodeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, mpNil)
+		return nil
+	}
+	if v.Type() == timeType {
+		e.encodeTime(v.Interface().(time.Time))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, mpTrue)
+		} else {
+			e.buf = append(e.buf, mpFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+	case reflect.Float32:
+		e.buf = append(e.buf, mpFloat32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf = append(e.buf, mpFloat64)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, mpNil)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeBytes(v.Bytes())
+			return nil
+		}
+		return e.encodeArray(v)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			e.encodeBytes(b)
+			return nil
+		}
+		return e.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, mpNil)
+			return nil
+		}
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, mpNil)
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			return e.encodeValue(v.Elem())
+		}
+		// Pointers are counted towards the depth so that values pointing to
+		// themselves are rejected.
+		if err := e.enter(); err != nil {
+			return err
+		}
+		defer e.leave()
+		return e.encodeValue(v.Elem())
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// enter increments the nesting depth, returning an error if it exceeds
+// msgpackMaxDepth. Every successful call must be paired with a call to leave.
+func (e *msgpackEncoder) enter() error {
+	if e.depth >= msgpackMaxDepth {
+		return fmt.Errorf("msgpack: nesting exceeds maximum depth of %d, the value may be cyclic", msgpackMaxDepth)
+	}
+	e.depth++
+	return nil
+}
+
+func (e *msgpackEncoder) leave() {
+	e.depth--
+}
+
+func (e *msgpackEncoder) encodeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.encodeUint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(int8(i)))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, mpInt8, byte(int8(i)))
+	case i >= math.MinInt16:
+		e.buf = append(e.buf, mpInt16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(int16(i)))
+	case i >= math.MinInt32:
+		e.buf = append(e.buf, mpInt32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(int32(i)))
+	default:
+		e.buf = append(e.buf, mpInt64)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(i))
+	}
+}
+
+func (e *msgpackEncoder) encodeUint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, mpUint8, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = append(e.buf, mpUint16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(u))
+	case u <= math.MaxUint32:
+		e.buf = append(e.buf, mpUint32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(u))
+	default:
+		e.buf = append(e.buf, mpUint64)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, u)
+	}
+}
+
+func (e *msgpackEncoder) encodeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.buf = append(e.buf, mpFixStr|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, mpStr8, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, mpStr16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, mpStr32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) encodeBytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, mpBin8, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, mpBin16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, mpBin32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *msgpackEncoder) encodeArrayHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, mpFixArray|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, mpArray16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, mpArray32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) encodeMapHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, mpFixMap|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, mpMap16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, mpMap32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) encodeArray(v reflect.Value) error {
+	if err := e.enter(); err != nil {
+		return err
+	}
+	defer e.leave()
+	e.encodeArrayHeader(v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encodeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *msgpackEncoder) encodeMap(v reflect.Value) error {
+	if err := e.enter(); err != nil {
+		return err
+	}
+	defer e.leave()
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String {
+		// Sort string keys so that equal maps have equal encodings.
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	}
+	e.encodeMapHeader(len(keys))
+	for _, k := range keys {
+		if err := e.encodeValue(k); err != nil {
+			return err
+		}
+		if err := e.encodeValue(v.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *msgpackEncoder) encodeStruct(v reflect.Value) error {
+	if err := e.enter(); err != nil {
+		return err
+	}
+	defer e.leave()
+	fields := msgpackFields(v.Type())
+	n := 0
+	for _, f := range fields {
+		if !f.omitEmpty || !v.Field(f.index).IsZero() {
+			n++
+		}
+	}
+	e.encodeMapHeader(n)
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		e.encodeString(f.name)
+		if err := e.encodeValue(fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeTime encodes t using the smallest MessagePack timestamp format that can
+// represent it.
+func (e *msgpackEncoder) encodeTime(t time.Time) {
+	sec := t.Unix()
+	nsec := uint64(t.Nanosecond())
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		e.buf = append(e.buf, mpFixExt4, mpExtTime)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(sec))
+	case sec>>34 == 0:
+		e.buf = append(e.buf, mpFixExt8, mpExtTime)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, nsec<<34|uint64(sec))
+	default:
+		e.buf = append(e.buf, mpExt8, 12, mpExtTime)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(nsec))
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(sec))
+	}
+}
+
+type msgpackField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// msgpackFields returns the encodable fields of struct type t.
+func msgpackFields(t reflect.Type) []msgpackField {
+	fields := make([]msgpackField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := msgpackField{name: sf.Name, index: i}
+		if tag, ok := sf.Tag.Lookup("msgpack"); ok {
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "-" && opts == "" {
+				continue
+			}
+			if name != "" {
+				f.name = name
+			}
+			f.omitEmpty = opts == "omitempty"
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+type msgpackDecoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func (d *msgpackDecoder) decodeInto(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("msgpack: destination must be a non-nil pointer, got %T", v)
+	}
+	return d.decodeValue(rv.Elem())
+}
+
+// enter increments the nesting depth, returning an error if it exceeds
+// msgpackMaxDepth. Every call must be paired with a call to leave.
+func (d *msgpackDecoder) enter() error {
+	d.depth++
+	if d.depth > msgpackMaxDepth {
+		return fmt.Errorf("msgpack: nesting exceeds maximum depth of %d", msgpackMaxDepth)
+	}
+	return nil
+}
+
+func (d *msgpackDecoder) leave() {
+	d.depth--
+}
+
+func (d *msgpackDecoder) peek() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errMsgpackShort
+	}
+	return d.data[d.pos], nil
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errMsgpackShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) readByte() (byte, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// readLen reads a big-endian length of size bytes.
+func (d *msgpackDecoder) readLen(size int) (int, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	default:
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+func (d *msgpackDecoder) decodeValue(v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	b, err := d.peek()
+	if err != nil {
+		return err
+	}
+	if b == mpNil {
+		d.pos++
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Type() == timeType {
+		t, err := d.readTime()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeValue(v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("msgpack: cannot decode into non-empty interface %s", v.Type())
+		}
+		x, err := d.decodeAny()
+		if err != nil {
+			return err
+		}
+		if x != nil {
+			v.Set(reflect.ValueOf(x))
+		}
+		return nil
+	case reflect.Bool:
+		d.pos++
+		switch b {
+		case mpTrue:
+			v.SetBool(true)
+		case mpFalse:
+			v.SetBool(false)
+		default:
+			return d.typeError(b, v.Type())
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := d.readInt(v.Type())
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("msgpack: value %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, err := d.readInt(v.Type())
+		if err != nil {
+			// Values above math.MaxInt64 are only representable as uint64.
+			if b != mpUint64 {
+				return err
+			}
+			raw, err := d.next(9)
+			if err != nil {
+				return err
+			}
+			v.SetUint(binary.BigEndian.Uint64(raw[1:]))
+			return nil
+		}
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return fmt.Errorf("msgpack: value %d overflows %s", i, v.Type())
+		}
+		v.SetUint(uint64(i))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := d.readFloat(v.Type())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+		return nil
+	case reflect.String:
+		s, err := d.readRaw(v.Type())
+		if err != nil {
+			return err
+		}
+		v.SetString(string(s))
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			raw, err := d.readRaw(v.Type())
+			if err != nil {
+				return err
+			}
+			v.SetBytes(append([]byte{}, raw...))
+			return nil
+		}
+		n, err := d.readArrayLen(v.Type())
+		if err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			if err := d.decodeValue(s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			raw, err := d.readRaw(v.Type())
+			if err != nil {
+				return err
+			}
+			if len(raw) != v.Len() {
+				return fmt.Errorf("msgpack: cannot decode %d bytes into %s", len(raw), v.Type())
+			}
+			reflect.Copy(v, reflect.ValueOf(raw))
+			return nil
+		}
+		n, err := d.readArrayLen(v.Type())
+		if err != nil {
+			return err
+		}
+		if n != v.Len() {
+			return fmt.Errorf("msgpack: cannot decode array of length %d into %s", n, v.Type())
+		}
+		for i := 0; i < n; i++ {
+			if err := d.decodeValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		n, err := d.readMapLen(v.Type())
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(v.Type(), n)
+		for i := 0; i < n; i++ {
+			k := reflect.New(v.Type().Key()).Elem()
+			if err := d.decodeValue(k); err != nil {
+				return err
+			}
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decodeValue(e); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Struct:
+		return d.decodeStruct(v)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+}
+
+func (d *msgpackDecoder) decodeStruct(v reflect.Value) error {
+	n, err := d.readMapLen(v.Type())
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]int)
+	for _, f := range msgpackFields(v.Type()) {
+		byName[f.name] = f.index
+	}
+	for i := 0; i < n; i++ {
+		name, err := d.readRaw(reflect.TypeOf(""))
+		if err != nil {
+			return err
+		}
+		idx, ok := byName[string(name)]
+		if !ok {
+			if _, err := d.decodeAny(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.decodeValue(v.Field(idx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeAny decodes the next value into its natural Go representation: nil,
+// bool, int64, uint64 (only for values above math.MaxInt64), float64, string,
+// []byte, time.Time, []any or map[string]any.
+func (d *msgpackDecoder) decodeAny() (any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+	b, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b == mpNil:
+		d.pos++
+		return nil, nil
+	case b == mpTrue, b == mpFalse:
+		d.pos++
+		return b == mpTrue, nil
+	case b <= 0x7f, b >= 0xe0, b >= mpInt8 && b <= mpInt64, b >= mpUint8 && b <= mpUint64:
+		var i int64
+		v := reflect.ValueOf(&i).Elem()
+		if err := d.decodeValue(v); err != nil {
+			var u uint64
+			if b != mpUint64 {
+				return nil, err
+			}
+			if err := d.decodeValue(reflect.ValueOf(&u).Elem()); err != nil {
+				return nil, err
+			}
+			return u, nil
+		}
+		return i, nil
+	case b == mpFloat32, b == mpFloat64:
+		return d.readFloat(reflect.TypeOf(float64(0)))
+	case b&0xe0 == mpFixStr, b == mpStr8, b == mpStr16, b == mpStr32:
+		s, err := d.readRaw(reflect.TypeOf(""))
+		return string(s), err
+	case b == mpBin8, b == mpBin16, b == mpBin32:
+		raw, err := d.readRaw(reflect.TypeOf([]byte(nil)))
+		return append([]byte{}, raw...), err
+	case b&0xf0 == mpFixArray, b == mpArray16, b == mpArray32:
+		n, err := d.readArrayLen(reflect.TypeOf([]any(nil)))
+		if err != nil {
+			return nil, err
+		}
+		s := make([]any, n)
+		for i := range s {
+			if s[i], err = d.decodeAny(); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	case b&0xf0 == mpFixMap, b == mpMap16, b == mpMap32:
+		n, err := d.readMapLen(reflect.TypeOf(map[string]any(nil)))
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			k, err := d.decodeAny()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.decodeAny()
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := k.(string); ok {
+				m[s] = v
+			} else {
+				m[fmt.Sprint(k)] = v
+			}
+		}
+		return m, nil
+	case b == mpFixExt4, b == mpFixExt8, b == mpExt8:
+		return d.readTime()
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported format 0x%02x", b)
+	}
+}
+
+func (d *msgpackDecoder) readInt(t reflect.Type) (int64, error) {
+	b, err := d.peek()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case b <= 0x7f:
+		d.pos++
+		return int64(b), nil
+	case b >= 0xe0:
+		d.pos++
+		return int64(int8(b)), nil
+	}
+
+	var size int
+	switch b {
+	case mpUint8, mpInt8:
+		size = 1
+	case mpUint16, mpInt16:
+		size = 2
+	case mpUint32, mpInt32:
+		size = 4
+	case mpUint64, mpInt64:
+		size = 8
+	default:
+		return 0, d.typeError(b, t)
+	}
+	if len(d.data)-d.pos < size+1 {
+		return 0, errMsgpackShort
+	}
+	raw := d.data[d.pos+1 : d.pos+1+size]
+	var i int64
+	switch b {
+	case mpUint8:
+		i = int64(raw[0])
+	case mpUint16:
+		i = int64(binary.BigEndian.Uint16(raw))
+	case mpUint32:
+		i = int64(binary.BigEndian.Uint32(raw))
+	case mpUint64:
+		u := binary.BigEndian.Uint64(raw)
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("msgpack: value %d overflows %s", u, t)
+		}
+		i = int64(u)
+	case mpInt8:
+		i = int64(int8(raw[0]))
+	case mpInt16:
+		i = int64(int16(binary.BigEndian.Uint16(raw)))
+	case mpInt32:
+		i = int64(int32(binary.BigEndian.Uint32(raw)))
+	case mpInt64:
+		i = int64(binary.BigEndian.Uint64(raw))
+	}
+	d.pos += size + 1
+	return i, nil
+}
+
+func (d *msgpackDecoder) readFloat(t reflect.Type) (float64, error) {
+	b, err := d.peek()
+	if err != nil {
+		return 0, err
+	}
+	switch b {
+	case mpFloat32:
+		raw, err := d.next(5)
+		if err != nil {
+			return 0, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw[1:]))), nil
+	case mpFloat64:
+		raw, err := d.next(9)
+		if err != nil {
+			return 0, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw[1:])), nil
+	default:
+		i, err := d.readInt(t)
+		if err != nil {
+			return 0, err
+		}
+		return float64(i), nil
+	}
+}
+
+// readRaw reads the payload of a str or bin value.
+func (d *msgpackDecoder) readRaw(t reflect.Type) ([]byte, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch {
+	case b&0xe0 == mpFixStr:
+		n = int(b & 0x1f)
+	case b == mpStr8, b == mpBin8:
+		n, err = d.readLen(1)
+	case b == mpStr16, b == mpBin16:
+		n, err = d.readLen(2)
+	case b == mpStr32, b == mpBin32:
+		n, err = d.readLen(4)
+	default:
+		d.pos--
+		return nil, d.typeError(b, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.next(n)
+}
+
+func (d *msgpackDecoder) readArrayLen(t reflect.Type) (int, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case b&0xf0 == mpFixArray:
+		return int(b & 0x0f), nil
+	case b == mpArray16:
+		return d.readCount(2, 1)
+	case b == mpArray32:
+		return d.readCount(4, 1)
+	default:
+		d.pos--
+		return 0, d.typeError(b, t)
+	}
+}
+
+func (d *msgpackDecoder) readMapLen(t reflect.Type) (int, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case b&0xf0 == mpFixMap:
+		return int(b & 0x0f), nil
+	case b == mpMap16:
+		return d.readCount(2, 2)
+	case b == mpMap32:
+		return d.readCount(4, 2)
+	default:
+		d.pos--
+		return 0, d.typeError(b, t)
+	}
+}
+
+// readCount reads the element count of an array or map as a big-endian length
+// of size bytes. Every element occupies at least minSize bytes, so counts that
+// exceed the remaining data are rejected before any memory is allocated for
+// them.
+func (d *msgpackDecoder) readCount(size, minSize int) (int, error) {
+	n, err := d.readLen(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > (len(d.data)-d.pos)/minSize {
+		return 0, errMsgpackShort
+	}
+	return n, nil
+}
+
+// readTime reads a value encoded with the MessagePack timestamp extension. The
+// returned time is in UTC.
+func (d *msgpackDecoder) readTime() (time.Time, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var n int
+	switch b {
+	case mpFixExt4:
+		n = 4
+	case mpFixExt8:
+		n = 8
+	case mpExt8:
+		if n, err = d.readLen(1); err != nil {
+			return time.Time{}, err
+		}
+	default:
+		d.pos--
+		return time.Time{}, d.typeError(b, timeType)
+	}
+	typ, err := d.readByte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if typ != mpExtTime {
+		return time.Time{}, fmt.Errorf("msgpack: unsupported extension type %d", int8(typ))
+	}
+	raw, err := d.next(n)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(raw)), 0).UTC(), nil
+	case 8:
+		v := binary.BigEndian.Uint64(raw)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)).UTC(), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(raw[:4])
+		sec := int64(binary.BigEndian.Uint64(raw[4:]))
+		return time.Unix(sec, int64(nsec)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+	}
+}
+
+func (d *msgpackDecoder) typeError(b byte, t reflect.Type) error {
+	return fmt.Errorf("msgpack: cannot decode format 0x%02x into %s", b, t)
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// scanBatchSize is the number of keys requested per call to Scanner.Scan by
+// Keys.
+const scanBatchSize = 100
+
+// Keys returns an iterator over the keys of c that begin with the given prefix.
+// Keys uses Scanner.Scan and therefore shares its consistency semantics; in
+// particular, a key may be yielded more than once. Iteration stops after the
+// first error, which is yielded with an empty key. If c does not implement
+// Scanner, an error wrapping ErrUnsupported is yielded.
+//
+// Example (purging cached authorization decisions):
+//
+//	c, _ := api.Cache("authz")
+//	var stale []string
+//	for key, err := range cache.Keys(ctx, c, "decision:") {
+//		if err != nil {
+//			return err
+//		}
+//		stale = append(stale, key)
+//	}
+//	_ = c.(cache.Store).DeleteMany(ctx, stale)
+func Keys(ctx context.Context, c Cache, prefix string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		scanner, ok := c.(Scanner)
+		if !ok {
+			yield("", fmt.Errorf("unable to scan keys: %w", ErrUnsupported))
+			return
+		}
+		cursor := ""
+		for {
+			keys, next, err := scanner.Scan(ctx, prefix, cursor, scanBatchSize)
+			if err != nil {
+				yield("", err)
+				return
+			}
+			for _, key := range keys {
+				if !yield(key, nil) {
+					return
+				}
+			}
+			if next == "" {
+				return
+			}
+			cursor = next
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrSchemaMismatch is returned by Typed when a cached value was written with a
+// different schema version or codec. Errors wrapping ErrSchemaMismatch also
+// wrap ErrNotFound, so callers that reload values on ErrNotFound handle stale
+// entries transparently.
+var ErrSchemaMismatch = errors.New("cached value has a different schema")
+
+// envelopeMagic is the first byte of every value written by Typed.
+const envelopeMagic = 0xa7
+
+// envelopeFormat is the version of the envelope layout written by Typed.
+const envelopeFormat = 1
+
+const flagGzip = 1 << 0
+
+// TypedOptions are the options used to configure a Typed cache.
+type TypedOptions struct {
+	// Codec is used to encode and decode values. Defaults to JSONCodec.
+	Codec Codec
+
+	// SchemaVersion is recorded alongside every value. Values written with a
+	// different schema version are discarded when read.
+	SchemaVersion uint64
+
+	// CompressionThreshold is the encoded size in bytes at or above which values
+	// are compressed with gzip. Compression is disabled if the threshold is zero.
+	CompressionThreshold int
+
+	// MaxDecompressedSize is the maximum size in bytes of a decompressed value.
+	// Larger values are rejected, so that corrupt or hostile entries cannot
+	// exhaust memory. Defaults to DefaultMaxDecompressedSize.
+	MaxDecompressedSize int64
+}
+
+// DefaultMaxDecompressedSize is the default maximum size in bytes of a value
+// decompressed by Typed.
+const DefaultMaxDecompressedSize = 32 << 20
+
+// TypedOption is an option used to configure a Typed cache.
+type TypedOption func(*TypedOptions)
+
+// WithCodec configures the codec used to encode and decode values.
+func WithCodec(codec Codec) TypedOption {
+	return func(o *TypedOptions) {
+		o.Codec = codec
+	}
+}
+
+// WithSchemaVersion configures the schema version recorded alongside every
+// value. The schema version should be incremented whenever the type stored in
+// the cache changes in an incompatible way.
+func WithSchemaVersion(version uint64) TypedOption {
+	return func(o *TypedOptions) {
+		o.SchemaVersion = version
+	}
+}
+
+// WithCompression enables gzip compression of values whose encoded size is at
+// least threshold bytes.
+func WithCompression(threshold int) TypedOption {
+	return func(o *TypedOptions) {
+		o.CompressionThreshold = threshold
+	}
+}
+
+// WithMaxDecompressedSize configures the maximum size in bytes of a
+// decompressed value.
+func WithMaxDecompressedSize(n int64) TypedOption {
+	return func(o *TypedOptions) {
+		o.MaxDecompressedSize = n
+	}
+}
+
+// Typed wraps a Cache to store values of type T. Values are encoded with a
+// Codec and stored in an envelope recording the codec and schema version, so
+// that values written by an older version of a service extension are detected
+// and discarded instead of being decoded incorrectly.
+//
+// Example:
+//
+//	type groups struct {
+//		Names []string `json:"names"`
+//	}
+//
+//	c, _ := api.Cache("ldap")
+//	typed := cache.NewTyped[groups](c, cache.WithSchemaVersion(2), cache.WithCompression(1024))
+//	_ = typed.Set(ctx, uid, groups{Names: names}, cache.WithTTL(10*time.Minute))
+//
+//	g, err := typed.Get(ctx, uid)
+//	if errors.Is(err, cache.ErrNotFound) {
+//		// Not cached, or cached with an older schema.
+//	}
+type Typed[T any] struct {
+	cache Cache
+	opts  TypedOptions
+}
+
+// NewTyped creates a Typed cache storing values of type T in c.
+func NewTyped[T any](c Cache, opts ...TypedOption) *Typed[T] {
+	o := TypedOptions{Codec: JSONCodec{}, MaxDecompressedSize: DefaultMaxDecompressedSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Typed[T]{cache: c, opts: o}
+}
+
+// Cache returns the underlying Cache.
+func (t *Typed[T]) Cache() Cache {
+	return t.cache
+}
+
+// Get returns the value for a given key. If the key does not exist, an error
+// wrapping ErrNotFound is returned. If the value was written with a different
+// codec or schema version, it is deleted if the underlying Cache implements
+// Store, and an error wrapping both ErrNotFound and ErrSchemaMismatch is
+// returned.
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	var zero T
+	data, err := t.cache.GetBytes(ctx, key)
+	if err != nil {
+		return zero, err
+	}
+	v, err := t.Decode(data)
+	if errors.Is(err, ErrSchemaMismatch) {
+		if store, ok := t.cache.(Store); ok {
+			if delErr := store.Delete(ctx, key); delErr != nil {
+				return zero, fmt.Errorf("unable to discard stale value for key '%s': %w", key, delErr)
+			}
+		}
+		return zero, fmt.Errorf("key '%s': %w: %w", key, ErrNotFound, err)
+	}
+	if err != nil {
+		return zero, fmt.Errorf("unable to decode value for key '%s': %w", key, err)
+	}
+	return v, nil
+}
+
+// Set encodes the value and stores it under the given key. Any existing value
+// for the key will be replaced.
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, opts ...Option) error {
+	data, err := t.Encode(value)
+	if err != nil {
+		return fmt.Errorf("unable to encode value for key '%s': %w", key, err)
+	}
+	return t.cache.SetBytes(ctx, key, data, opts...)
+}
+
+// SetIfAbsent encodes the value and stores it under the given key only if the
+// key does not already exist. True is returned if the value was set. An error
+// wrapping ErrUnsupported is returned if the Cache does not implement Atomic.
+func (t *Typed[T]) SetIfAbsent(ctx context.Context, key string, value T, opts ...Option) (bool, error) {
+	data, err := t.Encode(value)
+	if err != nil {
+		return false, fmt.Errorf("unable to encode value for key '%s': %w", key, err)
+	}
+	atomic, ok := t.cache.(Atomic)
+	if !ok {
+		return false, fmt.Errorf("unable to set key '%s' if absent: %w", key, ErrUnsupported)
+	}
+	return atomic.SetIfAbsent(ctx, key, data, opts...)
+}
+
+// Delete removes a key from the underlying Cache. An error wrapping
+// ErrUnsupported is returned if the Cache does not implement Store.
+func (t *Typed[T]) Delete(ctx context.Context, key string) error {
+	store, ok := t.cache.(Store)
+	if !ok {
+		return fmt.Errorf("unable to delete key '%s': %w", key, ErrUnsupported)
+	}
+	return store.Delete(ctx, key)
+}
+
+// Encode returns the enveloped encoding of value as it is stored in the
+// underlying Cache.
+func (t *Typed[T]) Encode(value T) ([]byte, error) {
+	payload, err := t.opts.Codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	if t.opts.CompressionThreshold > 0 && len(payload) >= t.opts.CompressionThreshold {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		payload = buf.Bytes()
+		flags |= flagGzip
+	}
+
+	name := t.opts.Codec.Name()
+	out := make([]byte, 0, 3+2*binary.MaxVarintLen64+len(name)+len(payload))
+	out = append(out, envelopeMagic, envelopeFormat, flags)
+	out = binary.AppendUvarint(out, t.opts.SchemaVersion)
+	out = binary.AppendUvarint(out, uint64(len(name)))
+	out = append(out, name...)
+	return append(out, payload...), nil
+}
+
+// Decode decodes a value produced by Encode. An error wrapping
+// ErrSchemaMismatch is returned if data was not produced by a Typed cache with
+// the same codec and schema version.
+func (t *Typed[T]) Decode(data []byte) (T, error) {
+	var v T
+	if len(data) < 3 || data[0] != envelopeMagic || data[1] != envelopeFormat {
+		return v, fmt.Errorf("%w: unrecognized envelope", ErrSchemaMismatch)
+	}
+	flags := data[2]
+	rest := data[3:]
+
+	schema, n := binary.Uvarint(rest)
+	if n <= 0 {
+		return v, fmt.Errorf("%w: invalid schema version", ErrSchemaMismatch)
+	}
+	rest = rest[n:]
+	if schema != t.opts.SchemaVersion {
+		return v, fmt.Errorf("%w: schema version %d, expected %d", ErrSchemaMismatch, schema, t.opts.SchemaVersion)
+	}
+
+	nameLen, n := binary.Uvarint(rest)
+	if n <= 0 || uint64(len(rest)-n) < nameLen {
+		return v, fmt.Errorf("%w: invalid codec name", ErrSchemaMismatch)
+	}
+	name := string(rest[n : n+int(nameLen)])
+	rest = rest[n+int(nameLen):]
+	if name != t.opts.Codec.Name() {
+		return v, fmt.Errorf("%w: codec '%s', expected '%s'", ErrSchemaMismatch, name, t.opts.Codec.Name())
+	}
+
+	payload := rest
+	if flags&flagGzip != 0 {
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return v, err
+		}
+		if payload, err = io.ReadAll(io.LimitReader(zr, t.opts.MaxDecompressedSize+1)); err != nil {
+			return v, err
+		}
+		if int64(len(payload)) > t.opts.MaxDecompressedSize {
+			return v, fmt.Errorf("decompressed value exceeds %d bytes", t.opts.MaxDecompressedSize)
+		}
+	}
+	if err := t.opts.Codec.Unmarshal(payload, &v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mapCache is a Store backed by a map. TTLs are ignored.
+type mapCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+var _ Store = (*mapCache)(nil)
+
+func newMapCache() *mapCache {
+	return &mapCache{values: make(map[string][]byte)}
+}
+
+func (c *mapCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	values, _ := c.GetMany(ctx, []string{key})
+	value, ok := values[key]
+	if !ok {
+		return nil, fmt.Errorf("key '%s': %w", key, ErrNotFound)
+	}
+	return value, nil
+}
+
+func (c *mapCache) SetBytes(ctx context.Context, key string, value []byte, opts ...Option) error {
+	return c.SetMany(ctx, map[string][]byte{key: value}, opts...)
+}
+
+func (c *mapCache) Delete(ctx context.Context, key string) error {
+	return c.DeleteMany(ctx, []string{key})
+}
+
+func (c *mapCache) Exists(ctx context.Context, key string) (bool, error) {
+	values, _ := c.GetMany(ctx, []string{key})
+	return len(values) == 1, nil
+}
+
+func (c *mapCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	_, err := c.GetBytes(ctx, key)
+	return 0, err
+}
+
+func (c *mapCache) Expire(ctx context.Context, key string, _ time.Duration) error {
+	_, err := c.GetBytes(ctx, key)
+	return err
+}
+
+func (c *mapCache) Touch(ctx context.Context, key string) error {
+	_, err := c.GetBytes(ctx, key)
+	return err
+}
+
+func (c *mapCache) GetMany(_ context.Context, keys []string) (map[string][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if value, ok := c.values[key]; ok {
+			values[key] = append([]byte(nil), value...)
+		}
+	}
+	return values, nil
+}
+
+func (c *mapCache) SetMany(_ context.Context, values map[string][]byte, _ ...Option) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, value := range values {
+		c.values[key] = append([]byte(nil), value...)
+	}
+	return nil
+}
+
+func (c *mapCache) DeleteMany(_ context.Context, keys []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.values, key)
+	}
+	return nil
+}
+
+type typedValue struct {
+	Names []string `json:"names"`
+}
+
+func TestTypedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c := newMapCache()
+	typed := NewTyped[typedValue](c, WithSchemaVersion(2), WithCompression(16))
+
+	want := typedValue{Names: []string{strings.Repeat("a", 64), "b"}}
+	if err := typed.Set(ctx, "k", want); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	raw, err := c.GetBytes(ctx, "k")
+	if err != nil {
+		t.Fatalf("GetBytes() error = %v", err)
+	}
+	if raw[2]&flagGzip == 0 {
+		t.Error("value above the compression threshold was not compressed")
+	}
+	got, err := typed.Get(ctx, "k")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(got.Names) != 2 || got.Names[0] != want.Names[0] || got.Names[1] != "b" {
+		t.Errorf("Get() = %v, want %v", got, want)
+	}
+}
+
+func TestTypedSchemaMismatch(t *testing.T) {
+	ctx := context.Background()
+	c := newMapCache()
+	if err := NewTyped[typedValue](c, WithSchemaVersion(1)).Set(ctx, "k", typedValue{}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	_, err := NewTyped[typedValue](c, WithSchemaVersion(2)).Get(ctx, "k")
+	if !errors.Is(err, ErrSchemaMismatch) || !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() error = %v, want ErrSchemaMismatch and ErrNotFound", err)
+	}
+	if ok, _ := c.Exists(ctx, "k"); ok {
+		t.Error("stale value was not deleted")
+	}
+}
+
+func TestTypedDecodeCorrupt(t *testing.T) {
+	typed := NewTyped[typedValue](nil, WithCompression(1))
+	valid, err := typed.Encode(typedValue{Names: []string{"a"}})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	for i := range len(valid) {
+		if _, err := typed.Decode(valid[:i]); err == nil {
+			t.Errorf("Decode(valid[:%d]) succeeded", i)
+		}
+	}
+	if _, err := typed.Decode([]byte{envelopeMagic, envelopeFormat, 0, 0, 0xff, 0xff, 0xff, 0xff, 0x0f}); !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("Decode() with oversized codec name error = %v, want ErrSchemaMismatch", err)
+	}
+}
+
+func TestTypedDecodeMaxDecompressedSize(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(bytes.Repeat([]byte{' '}, 1<<20))
+	_ = zw.Close()
+
+	name := JSONCodec{}.Name()
+	data := []byte{envelopeMagic, envelopeFormat, flagGzip}
+	data = binary.AppendUvarint(data, 0)
+	data = binary.AppendUvarint(data, uint64(len(name)))
+	data = append(data, name...)
+	data = append(data, buf.Bytes()...)
+
+	typed := NewTyped[typedValue](nil, WithMaxDecompressedSize(1<<10))
+	if _, err := typed.Decode(data); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Decode() error = %v, want size limit error", err)
+	}
+}
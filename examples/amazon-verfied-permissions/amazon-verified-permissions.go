@@ -4,10 +4,12 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/verifiedpermissions"
 	"github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
+	"github.com/strata-io/service-extension/audit"
 	"github.com/strata-io/service-extension/orchestrator"
 )
 
@@ -36,10 +38,26 @@ func IsAuthorized(api orchestrator.Orchestrator, _ http.ResponseWriter, req *htt
 	session, _ := api.Session()
 	logger := api.Logger()
 	email, _ := session.GetString(principalID)
-	logger.Info("requesting isAuthorized decision for " + email + " at " + req.URL.Path)
+	logger.Info(
+		"se", "requesting isAuthorized decision",
+		"email", email,
+		"path", req.URL.Path,
+	)
+	event := audit.Event{
+		Action:   "authz.decision",
+		Actor:    audit.Actor{ID: email, IP: req.RemoteAddr},
+		Resource: audit.Resource{Type: resourceType, ID: req.URL.Path},
+		Decision: audit.DecisionDeny,
+	}
+	defer func() {
+		recordDecision(api, req, event)
+	}()
+
 	avpReq, err := createVerifiedPermissionsRequest(email, req.URL.Path, api)
 	if err != nil {
-		logger.Info("error creating request: " + err.Error())
+		logger.Error("se", "error creating request", "error", err.Error())
+		event.Decision = audit.DecisionError
+		event.Reason = err.Error()
 		return false
 	}
 	if len(avpReq.Errors) > 0 {
@@ -48,15 +66,37 @@ func IsAuthorized(api orchestrator.Orchestrator, _ http.ResponseWriter, req *htt
 		}
 	}
 	logger.Info("se", "The following policy id's contributed to the decision:")
+	var policyIDs []string
 	for _, dp := range avpReq.DeterminingPolicies {
 		logger.Info("se", "Determing policy id for the decision: "+*dp.PolicyId)
+		policyIDs = append(policyIDs, *dp.PolicyId)
+	}
+	event.Reason = "determining policies: " + strings.Join(policyIDs, ", ")
+
+	if avpReq.Decision == types.DecisionAllow {
+		event.Decision = audit.DecisionAllow
 	}
+	logger.Info("se", "isAuthorized decision from Amazon verified permissions: "+string(avpReq.Decision))
+	return event.Decision == audit.DecisionAllow
+}
 
-	for _, d := range avpReq.Decision.Values() {
-		logger.Info("se", "isAuthorized decision from Amazon verified permissions: "+d)
-		return d == "ALLOW"
+// recordDecision records the authorization decision in the audit log, which
+// keeps a tamper-evident record separate from the diagnostic logs. Decisions are
+// only logged if the Orchestrator provides an audit log.
+func recordDecision(api orchestrator.Orchestrator, req *http.Request, event audit.Event) {
+	ap, ok := api.(orchestrator.AuditProvider)
+	if !ok {
+		api.Logger().Info("se", "audit log not available, authorization decision not recorded")
+		return
+	}
+	auditor, err := ap.Audit()
+	if err != nil {
+		api.Logger().Error("se", "unable to get audit logger", "error", err.Error())
+		return
+	}
+	if err := auditor.Record(req.Context(), event); err != nil {
+		api.Logger().Error("se", "unable to record authorization decision", "error", err.Error())
 	}
-	return false
 }
 
 // createVerifiedPermissionsRequest builds a new verified permissions API request with the supplied
@@ -97,6 +97,11 @@ func getGroups(serverName, baseDN, filter string, secretP secret.Provider) (map[
 
 	serviceAccountUsername := secretP.GetString("serviceAccountUsername")
 	serviceAccountPassword := secretP.GetString("serviceAccountPassword")
+	// An empty password would result in an unauthenticated bind, which most LDAP
+	// servers accept, so missing secrets must be rejected explicitly.
+	if serviceAccountUsername == "" || serviceAccountPassword == "" {
+		return nil, errors.New("serviceAccountUsername and serviceAccountPassword secrets must be set")
+	}
 	err = conn.Bind(serviceAccountUsername, serviceAccountPassword)
 	if err != nil {
 		return nil, fmt.Errorf("unable to bind ldap3: %w", err)
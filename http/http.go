@@ -5,6 +5,12 @@ import (
 )
 
 // HTTP provides a way to interact with the HTTP client.
+//
+// Clients returned by GetClient and DefaultClient start a client span for each
+// request and propagate the trace context of the request's context with the
+// W3C traceparent and tracestate headers, as done by trace.InstrumentClient.
+// Requests must therefore be created with the context to trace, e.g. with
+// http.NewRequestWithContext(api.Context(), ...).
 type HTTP interface {
 	// GetClient returns the HTTP client based on the provided name.
 	// If the client does not exist, an error will be returned.
@@ -1,8 +1,12 @@
 package idfabric
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+
+	"github.com/strata-io/service-extension/log"
 )
 
 const (
@@ -91,12 +95,47 @@ type ROPCRequest struct {
 	Password string
 }
 
+// String returns the request with the password redacted, so that formatting or
+// logging the request does not leak the password.
+func (r ROPCRequest) String() string {
+	return fmt.Sprintf("{Username:%s Password:%s}", r.Username, log.Redacted)
+}
+
+// GoString returns the request with the password redacted.
+func (r ROPCRequest) GoString() string {
+	return fmt.Sprintf("idfabric.ROPCRequest{Username:%q, Password:%q}", r.Username, log.Redacted)
+}
+
+// LogValue returns the request with the password redacted.
+func (r ROPCRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("username", r.Username),
+		slog.String("password", log.Redacted),
+	)
+}
+
 // LoginResult is the response from the IdentityProvider after a login attempt.
 type LoginResult struct {
 	TokenResult
 	Error error
 }
 
+// String returns the result with its tokens redacted.
+func (r LoginResult) String() string {
+	return fmt.Sprintf("{TokenResult:%s Error:%v}", r.TokenResult, r.Error)
+}
+
+// GoString returns the result with its tokens redacted.
+func (r LoginResult) GoString() string {
+	return fmt.Sprintf("idfabric.LoginResult{TokenResult:%#v, Error:%#v}", r.TokenResult, r.Error)
+}
+
+// LogValue returns the result with its tokens redacted.
+func (r LoginResult) LogValue() slog.Value {
+	attrs := append(r.TokenResult.LogValue().Group(), slog.Any("error", r.Error))
+	return slog.GroupValue(attrs...)
+}
+
 // TokenResult is the response from the IdentityProvider after a login attempt.
 type TokenResult struct {
 	// AccessToken is the token that can be used to access protected resources.
@@ -111,6 +150,39 @@ type TokenResult struct {
 	Scope string
 }
 
+// String returns the result with its tokens redacted, so that formatting or
+// logging the result does not leak the tokens.
+func (r TokenResult) String() string {
+	return fmt.Sprintf("{AccessToken:%s IDToken:%s RefreshToken:%s ExpiresIn:%d Scope:%s}",
+		redactToken(r.AccessToken), redactToken(r.IDToken), redactToken(r.RefreshToken), r.ExpiresIn, r.Scope)
+}
+
+// GoString returns the result with its tokens redacted.
+func (r TokenResult) GoString() string {
+	return fmt.Sprintf("idfabric.TokenResult{AccessToken:%q, IDToken:%q, RefreshToken:%q, ExpiresIn:%d, Scope:%q}",
+		redactToken(r.AccessToken), redactToken(r.IDToken), redactToken(r.RefreshToken), r.ExpiresIn, r.Scope)
+}
+
+// LogValue returns the result with its tokens redacted.
+func (r TokenResult) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("access_token", redactToken(r.AccessToken)),
+		slog.String("id_token", redactToken(r.IDToken)),
+		slog.String("refresh_token", redactToken(r.RefreshToken)),
+		slog.Int64("expires_in", r.ExpiresIn),
+		slog.String("scope", r.Scope),
+	)
+}
+
+// redactToken redacts a token, leaving empty tokens empty so that it remains
+// visible whether a token was issued.
+func redactToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	return log.Redacted
+}
+
 // WithGrantTypeROPC specifies the Resource Owner Password Credentials (ROPC)
 // flow for authenticating a user. This flow is typically used for legacy
 // applications that require a username and password to authenticate the user
@@ -0,0 +1,467 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/strata-io/service-extension/secret"
+)
+
+// format is the version of the layout of ciphertexts and signatures.
+const format = 1
+
+// minSymmetricKeySize is the minimum size of a symmetric key in bytes.
+const minSymmetricKeySize = 32
+
+// Algorithms recorded in ciphertexts and signatures.
+const (
+	algAESKeyWrap = 1
+	algRSAOAEP    = 2
+
+	algHS256 = 1
+	algRS256 = 2
+	algES256 = 3
+	algEdDSA = 4
+)
+
+// New creates a Keyring using the versions of the secret with the given name.
+// Key material is retrieved from p when it is first needed and cached by
+// version.
+func New(p secret.Versioned, name string) Keyring {
+	return &keyring{
+		provider: p,
+		name:     name,
+		keys:     make(map[string]*key),
+	}
+}
+
+type keyring struct {
+	provider secret.Versioned
+	name     string
+
+	mu   sync.Mutex
+	keys map[string]*key
+}
+
+// key is a parsed version of the key.
+type key struct {
+	id string
+	// encKey and macKey are derived from a symmetric key.
+	encKey []byte
+	macKey []byte
+	// signer is set for asymmetric keys.
+	signer crypto.Signer
+}
+
+func (r *keyring) Encrypt(plaintext, associatedData []byte) ([]byte, error) {
+	k, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	var (
+		alg     byte
+		wrapped []byte
+	)
+	switch pub := k.public().(type) {
+	case nil:
+		alg = algAESKeyWrap
+		wrapped, err = seal(k.encKey, dataKey, []byte(k.id))
+	case *rsa.PublicKey:
+		alg = algRSAOAEP
+		wrapped, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dataKey, []byte(k.id))
+	default:
+		return nil, fmt.Errorf("unable to encrypt with key '%s' of type %T: %w", r.name, pub, ErrUnsupported)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to wrap data key with key '%s': %w", r.name, err)
+	}
+
+	out := header(alg, k.id)
+	out = binary.AppendUvarint(out, uint64(len(wrapped)))
+	out = append(out, wrapped...)
+	sealed, err := seal(dataKey, plaintext, append(append([]byte(nil), out...), associatedData...))
+	if err != nil {
+		return nil, err
+	}
+	return append(out, sealed...), nil
+}
+
+func (r *keyring) Decrypt(ciphertext, associatedData []byte) ([]byte, error) {
+	alg, kid, rest, err := parseHeader(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	wrappedLen, n := binary.Uvarint(rest)
+	if n <= 0 || uint64(len(rest)-n) < wrappedLen {
+		return nil, fmt.Errorf("%w: invalid data key", ErrDecrypt)
+	}
+	wrapped := rest[n : n+int(wrappedLen)]
+	sealed := rest[n+int(wrappedLen):]
+	headerLen := len(ciphertext) - len(sealed)
+
+	k, err := r.version(kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	var dataKey []byte
+	switch priv := k.signer.(type) {
+	case nil:
+		if alg != algAESKeyWrap {
+			return nil, fmt.Errorf("%w: algorithm does not match key", ErrDecrypt)
+		}
+		dataKey, err = open(k.encKey, wrapped, []byte(k.id))
+	case *rsa.PrivateKey:
+		if alg != algRSAOAEP {
+			return nil, fmt.Errorf("%w: algorithm does not match key", ErrDecrypt)
+		}
+		dataKey, err = rsa.DecryptOAEP(sha256.New(), nil, priv, wrapped, []byte(k.id))
+	default:
+		return nil, fmt.Errorf("unable to decrypt with key '%s' of type %T: %w", r.name, priv.Public(), ErrUnsupported)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to unwrap data key", ErrDecrypt)
+	}
+
+	aad := append(append([]byte(nil), ciphertext[:headerLen]...), associatedData...)
+	plaintext, err := open(dataKey, sealed, aad)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	return plaintext, nil
+}
+
+func (r *keyring) Sign(data []byte) ([]byte, error) {
+	k, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	alg, sig, err := k.sign(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign with key '%s': %w", r.name, err)
+	}
+	return append(header(alg, k.id), sig...), nil
+}
+
+func (r *keyring) Verify(data, signature []byte) error {
+	alg, kid, sig, err := parseHeader(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	k, err := r.version(kid)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	if !k.verify(alg, data, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (r *keyring) JWKS() (JWKS, error) {
+	meta, err := r.provider.Metadata(r.name)
+	if err != nil {
+		return JWKS{}, err
+	}
+	set := JWKS{Keys: make([]JWK, 0, 2)}
+	for _, version := range []string{meta.Version, meta.PreviousVersion} {
+		if version == "" {
+			continue
+		}
+		k, err := r.version(version)
+		if errors.Is(err, secret.ErrNotFound) && version == meta.PreviousVersion {
+			// The previous version is no longer retained.
+			continue
+		}
+		if err != nil {
+			return JWKS{}, err
+		}
+		if k.signer == nil {
+			return set, nil
+		}
+		jwk, err := k.jwk()
+		if err != nil {
+			return JWKS{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// current returns the current version of the key.
+func (r *keyring) current() (*key, error) {
+	meta, err := r.provider.Metadata(r.name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get key '%s': %w", r.name, err)
+	}
+	return r.version(meta.Version)
+}
+
+// version returns the given version of the key, parsing it if it is not cached.
+// r.mu is not held while the version is retrieved from the provider, so that a
+// slow provider or an unknown version does not block other operations.
+func (r *keyring) version(id string) (*key, error) {
+	r.mu.Lock()
+	k, ok := r.keys[id]
+	r.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+
+	s, err := r.provider.GetVersion(r.name, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get key '%s' version '%s': %w", r.name, id, err)
+	}
+	k, err = parseKey(id, s.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key '%s' version '%s': %w", r.name, id, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached, ok := r.keys[id]; ok {
+		return cached, nil
+	}
+	r.keys[id] = k
+	return k, nil
+}
+
+// parseKey parses the value of a version of the key. Errors never include the
+// value.
+func parseKey(id, value string) (*key, error) {
+	if !strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.New("symmetric key is not base64-encoded")
+		}
+		if len(raw) < minSymmetricKeySize {
+			return nil, fmt.Errorf("symmetric key must be at least %d bytes", minSymmetricKeySize)
+		}
+		encKey, err := hkdf.Key(sha256.New, raw, nil, "service-extension keys encrypt", 32)
+		if err != nil {
+			return nil, err
+		}
+		macKey, err := hkdf.Key(sha256.New, raw, nil, "service-extension keys sign", 32)
+		if err != nil {
+			return nil, err
+		}
+		return &key{id: id, encKey: encKey, macKey: macKey}, nil
+	}
+
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, errors.New("key is not PEM-encoded")
+	}
+	var (
+		parsed any
+		err    error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type '%s'", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch pk := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &key{id: id, signer: pk}, nil
+	case *ecdsa.PrivateKey:
+		if pk.Curve != elliptic.P256() {
+			return nil, errors.New("only ECDSA keys on curve P-256 are supported")
+		}
+		return &key{id: id, signer: pk}, nil
+	case ed25519.PrivateKey:
+		return &key{id: id, signer: pk}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+}
+
+// public returns the public key of an asymmetric key, or nil for a symmetric
+// key.
+func (k *key) public() crypto.PublicKey {
+	if k.signer == nil {
+		return nil
+	}
+	return k.signer.Public()
+}
+
+func (k *key) sign(data []byte) (byte, []byte, error) {
+	switch priv := k.signer.(type) {
+	case nil:
+		mac := hmac.New(sha256.New, k.macKey)
+		mac.Write(data)
+		return algHS256, mac.Sum(nil), nil
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+		return algRS256, sig, err
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		if err != nil {
+			return 0, nil, err
+		}
+		// ES256 signatures are the fixed-size concatenation of r and s.
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return algES256, sig, nil
+	case ed25519.PrivateKey:
+		return algEdDSA, ed25519.Sign(priv, data), nil
+	default:
+		return 0, nil, ErrUnsupported
+	}
+}
+
+func (k *key) verify(alg byte, data, sig []byte) bool {
+	switch pub := k.public().(type) {
+	case nil:
+		if alg != algHS256 {
+			return false
+		}
+		mac := hmac.New(sha256.New, k.macKey)
+		mac.Write(data)
+		return hmac.Equal(mac.Sum(nil), sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return alg == algRS256 && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		if alg != algES256 || len(sig) != 64 {
+			return false
+		}
+		digest := sha256.Sum256(data)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	case ed25519.PublicKey:
+		return alg == algEdDSA && ed25519.Verify(pub, data, sig)
+	default:
+		return false
+	}
+}
+
+func (k *key) jwk() (JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := k.public().(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     k.id,
+			Use:       "sig",
+			Algorithm: "RS256",
+			N:         b64(pub.N.Bytes()),
+			E:         b64(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		x, y := make([]byte, 32), make([]byte, 32)
+		pub.X.FillBytes(x)
+		pub.Y.FillBytes(y)
+		return JWK{
+			KeyType:   "EC",
+			KeyID:     k.id,
+			Use:       "sig",
+			Algorithm: "ES256",
+			Curve:     "P-256",
+			X:         b64(x),
+			Y:         b64(y),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     k.id,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         b64(pub),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unable to create JWK for key of type %T: %w", pub, ErrUnsupported)
+	}
+}
+
+// header returns the header of a ciphertext or signature.
+func header(alg byte, kid string) []byte {
+	out := make([]byte, 0, 2+binary.MaxVarintLen64+len(kid))
+	out = append(out, format, alg)
+	out = binary.AppendUvarint(out, uint64(len(kid)))
+	return append(out, kid...)
+}
+
+// parseHeader parses the header of a ciphertext or signature and returns the
+// remaining bytes.
+func parseHeader(data []byte) (alg byte, kid string, rest []byte, err error) {
+	if len(data) < 2 || data[0] != format {
+		return 0, "", nil, errors.New("unrecognized format")
+	}
+	alg = data[1]
+	kidLen, n := binary.Uvarint(data[2:])
+	// An empty key ID is rejected, since it would select the current version.
+	if n <= 0 || kidLen == 0 || uint64(len(data)-2-n) < kidLen {
+		return 0, "", nil, errors.New("invalid key ID")
+	}
+	kid = string(data[2+n : 2+n+int(kidLen)])
+	return alg, kid, data[2+n+int(kidLen):], nil
+}
+
+func seal(k, plaintext, associatedData []byte) ([]byte, error) {
+	aead, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+func open(k, sealed, associatedData []byte) ([]byte, error) {
+	aead, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, associatedData)
+}
+
+func newGCM(k []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
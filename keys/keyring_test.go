@@ -0,0 +1,204 @@
+package keys_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/strata-io/service-extension/keys"
+	"github.com/strata-io/service-extension/orchestratortest"
+	"github.com/strata-io/service-extension/secret"
+)
+
+func symmetricKey(t *testing.T) string {
+	t.Helper()
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func pemKey(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func testKeys(t *testing.T) map[string]string {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{
+		"symmetric": symmetricKey(t),
+		"rsa":       pemKey(t, rsaKey),
+		"ecdsa":     pemKey(t, ecKey),
+		"ed25519":   pemKey(t, edKey),
+	}
+}
+
+func TestKeyringEncryptRoundTrip(t *testing.T) {
+	for name, value := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			kr := keys.New(orchestratortest.NewSecretProvider(map[string]any{"k": value}), "k")
+			sealed, err := kr.Encrypt([]byte("plaintext"), []byte("aad"))
+			if name == "ecdsa" || name == "ed25519" {
+				if !errors.Is(err, keys.ErrUnsupported) {
+					t.Fatalf("Encrypt() error = %v, want ErrUnsupported", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			got, err := kr.Decrypt(sealed, []byte("aad"))
+			if err != nil || string(got) != "plaintext" {
+				t.Fatalf("Decrypt() = %q, %v", got, err)
+			}
+
+			if _, err := kr.Decrypt(sealed, []byte("other")); !errors.Is(err, keys.ErrDecrypt) {
+				t.Errorf("Decrypt() with other associated data error = %v, want ErrDecrypt", err)
+			}
+			for i := range sealed {
+				tampered := append([]byte(nil), sealed...)
+				tampered[i] ^= 0x01
+				if _, err := kr.Decrypt(tampered, []byte("aad")); !errors.Is(err, keys.ErrDecrypt) {
+					t.Fatalf("Decrypt() with byte %d modified error = %v, want ErrDecrypt", i, err)
+				}
+			}
+			for i := range sealed {
+				if _, err := kr.Decrypt(sealed[:i], []byte("aad")); !errors.Is(err, keys.ErrDecrypt) {
+					t.Fatalf("Decrypt() truncated to %d bytes error = %v, want ErrDecrypt", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyringSignRoundTrip(t *testing.T) {
+	for name, value := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			kr := keys.New(orchestratortest.NewSecretProvider(map[string]any{"k": value}), "k")
+			sig, err := kr.Sign([]byte("data"))
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if err := kr.Verify([]byte("data"), sig); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if err := kr.Verify([]byte("other"), sig); !errors.Is(err, keys.ErrInvalidSignature) {
+				t.Errorf("Verify() of other data error = %v, want ErrInvalidSignature", err)
+			}
+			for i := range sig {
+				tampered := append([]byte(nil), sig...)
+				tampered[i] ^= 0x01
+				if err := kr.Verify([]byte("data"), tampered); !errors.Is(err, keys.ErrInvalidSignature) {
+					t.Fatalf("Verify() with byte %d modified error = %v, want ErrInvalidSignature", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	p := orchestratortest.NewSecretProvider(map[string]any{"k": symmetricKey(t)})
+	kr := keys.New(p, "k")
+	sealed, err := kr.Encrypt([]byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	sig, err := kr.Sign([]byte("data"))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	p.Set("k", symmetricKey(t))
+	if got, err := kr.Decrypt(sealed, nil); err != nil || string(got) != "plaintext" {
+		t.Errorf("Decrypt() after rotation = %q, %v", got, err)
+	}
+	if err := kr.Verify([]byte("data"), sig); err != nil {
+		t.Errorf("Verify() after rotation error = %v", err)
+	}
+	rotated, err := kr.Sign([]byte("data"))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if string(rotated) == string(sig) {
+		t.Error("Sign() after rotation used the previous version")
+	}
+}
+
+func TestKeyringUnknownKeyID(t *testing.T) {
+	p := orchestratortest.NewSecretProvider(map[string]any{"k": symmetricKey(t)})
+	kr := keys.New(p, "k")
+	sealed, err := kr.Encrypt([]byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	sig, err := kr.Sign([]byte("data"))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// A keyring for another secret does not know the version.
+	other := keys.New(orchestratortest.NewSecretProvider(map[string]any{"other": symmetricKey(t)}), "k")
+	if _, err := other.Decrypt(sealed, nil); !errors.Is(err, keys.ErrDecrypt) || !errors.Is(err, secret.ErrNotFound) {
+		t.Errorf("Decrypt() with unknown key ID error = %v, want ErrDecrypt and secret.ErrNotFound", err)
+	}
+	if err := other.Verify([]byte("data"), sig); !errors.Is(err, keys.ErrInvalidSignature) {
+		t.Errorf("Verify() with unknown key ID error = %v, want ErrInvalidSignature", err)
+	}
+
+	// Version "1" in the header is replaced by an unknown version "9".
+	tampered := append([]byte(nil), sealed...)
+	tampered[3] = '9'
+	if _, err := kr.Decrypt(tampered, nil); !errors.Is(err, keys.ErrDecrypt) {
+		t.Errorf("Decrypt() with tampered key ID error = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestKeyringConcurrentUse(t *testing.T) {
+	p := orchestratortest.NewSecretProvider(map[string]any{"k": symmetricKey(t)})
+	kr := keys.New(p, "k")
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				sealed, err := kr.Encrypt([]byte("plaintext"), nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := kr.Decrypt(sealed, nil); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	p.Set("k", symmetricKey(t))
+	wg.Wait()
+}
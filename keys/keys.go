@@ -0,0 +1,111 @@
+// Package keys provides encryption and signing with keys stored in the secret
+// store, so that service extensions never handle raw key material. Keys are
+// rotated by adding a new version of the secret holding them: new values are
+// encrypted and signed with the current version, while values produced with
+// previous versions can still be decrypted and verified for as long as the
+// secret store retains those versions.
+package keys
+
+import (
+	"errors"
+)
+
+// ErrDecrypt is returned when a value cannot be decrypted, e.g. because it was
+// tampered with, was encrypted with a different key, or the associated data
+// does not match.
+var ErrDecrypt = errors.New("unable to decrypt value")
+
+// ErrInvalidSignature is returned when a signature does not match the signed
+// data.
+var ErrInvalidSignature = errors.New("signature is invalid")
+
+// ErrUnsupported is returned when an operation is not supported by the type of
+// key held by a Keyring, e.g. encrypting with an ECDSA key.
+var ErrUnsupported = errors.New("operation not supported by key type")
+
+// Keyring encrypts and signs values with the versions of a key held in the
+// secret store. The secret must contain either a base64-encoded random key of
+// at least 32 bytes, or a PEM-encoded RSA, ECDSA P-256 or Ed25519 private key.
+//
+// The key ID of a version of the key is the version of the secret. Ciphertexts
+// and signatures record the key ID they were produced with.
+//
+// Example:
+//
+//	kp, ok := api.(orchestrator.KeyringProvider)
+//	if !ok {
+//		return errors.New("keyring not available")
+//	}
+//	kr, err := kp.Keyring("cookieKey")
+//	if err != nil {
+//		return err
+//	}
+//	sealed, err := kr.Encrypt([]byte(uid), []byte("remember-me"))
+//	// ...
+//	uid, err := kr.Decrypt(sealed, []byte("remember-me"))
+type Keyring interface {
+	// Encrypt encrypts plaintext with a random data key, which is in turn
+	// encrypted with the current version of the key. associatedData is
+	// authenticated but not encrypted; the same associatedData must be passed to
+	// Decrypt. Encryption is supported for symmetric and RSA keys.
+	Encrypt(plaintext, associatedData []byte) ([]byte, error)
+
+	// Decrypt decrypts a ciphertext produced by Encrypt with any retained version
+	// of the key. If the ciphertext cannot be decrypted, an error wrapping
+	// ErrDecrypt is returned.
+	Decrypt(ciphertext, associatedData []byte) ([]byte, error)
+
+	// Sign signs data with the current version of the key. Symmetric keys sign
+	// with HMAC-SHA256, RSA keys with RS256, ECDSA keys with ES256 and Ed25519
+	// keys with EdDSA.
+	Sign(data []byte) ([]byte, error)
+
+	// Verify verifies a signature produced by Sign with any retained version of
+	// the key. If the signature does not match, an error wrapping
+	// ErrInvalidSignature is returned.
+	Verify(data, signature []byte) error
+
+	// JWKS returns the public keys of the current and previous versions of an
+	// asymmetric key, e.g. to publish them for relying parties. An empty set is
+	// returned for symmetric keys.
+	JWKS() (JWKS, error)
+}
+
+// JWKS is a JSON Web Key Set as defined by RFC 7517.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is a public JSON Web Key as defined by RFC 7517.
+type JWK struct {
+	// KeyType is the "kty" parameter, e.g. "RSA", "EC" or "OKP".
+	KeyType string `json:"kty"`
+
+	// KeyID is the "kid" parameter, the version of the secret holding the key.
+	KeyID string `json:"kid"`
+
+	// Use is the "use" parameter, "sig" for signing keys.
+	Use string `json:"use,omitempty"`
+
+	// Algorithm is the "alg" parameter, e.g. "RS256".
+	Algorithm string `json:"alg,omitempty"`
+
+	// N and E are the modulus and exponent of an RSA key.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Curve, X and Y are the curve and coordinates of an EC or OKP key.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// Key returns the key with the given key ID, if present.
+func (s JWKS) Key(kid string) (JWK, bool) {
+	for _, k := range s.Keys {
+		if k.KeyID == kid {
+			return k, true
+		}
+	}
+	return JWK{}, false
+}
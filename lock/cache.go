@@ -0,0 +1,231 @@
+package lock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/strata-io/service-extension/cache"
+)
+
+// released is stored in place of an owner when a lease is released so that the
+// release can be performed with an atomic CompareAndSwap.
+const released = "-"
+
+// releasedTTL is the TTL of the marker left behind by a released lease.
+const releasedTTL = time.Minute
+
+// LockerOptions are the options used to configure a Locker created with
+// NewCacheLocker.
+type LockerOptions struct {
+	// Now returns the current time. It is used to compute the expiry reported by
+	// Lease.ExpiresAt and should match the clock of the cache. Defaults to
+	// time.Now.
+	Now func() time.Time
+}
+
+// LockerOption is an option used to configure a Locker created with
+// NewCacheLocker.
+type LockerOption func(*LockerOptions)
+
+// WithClock configures the function used to determine the current time, e.g.
+// to match a cache created with cache.WithClock in tests.
+func WithClock(now func() time.Time) LockerOption {
+	return func(o *LockerOptions) {
+		o.Now = now
+	}
+}
+
+// NewCacheLocker creates a Locker that stores locks in c. Acquisition, renewal
+// and release are implemented with the atomic operations of cache.Atomic, so
+// locks are distributed across Orchestrators if c is shared between them.
+//
+// Locks are stored under keys prefixed with "lock:" and fencing tokens under
+// keys prefixed with "lock-fence:". Fencing token keys do not expire. The
+// fencing token of a lease is allocated before the lock is acquired and is
+// stored in the lock together with the owner, so that a lease can only renew or
+// release the lock it acquired. Tokens allocated by failed acquisitions are
+// skipped, so the tokens of successive leases increase but are not necessarily
+// consecutive.
+func NewCacheLocker(c cache.Atomic, opts ...LockerOption) Locker {
+	o := LockerOptions{Now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &cacheLocker{cache: c, now: o.Now}
+}
+
+type cacheLocker struct {
+	cache cache.Atomic
+	now   func() time.Time
+}
+
+func lockKey(name string) string {
+	return "lock:" + name
+}
+
+func fenceKey(name string) string {
+	return "lock-fence:" + name
+}
+
+func (l *cacheLocker) Acquire(ctx context.Context, name string, opts ...Option) (Lease, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		lease, err := l.TryAcquire(ctx, name, opts...)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lease, err
+		}
+
+		t := time.NewTimer(o.RetryInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, fmt.Errorf("unable to acquire lock '%s': %w", name, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+func (l *cacheLocker) TryAcquire(ctx context.Context, name string, opts ...Option) (Lease, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := newOwner()
+	if err != nil {
+		return nil, err
+	}
+
+	key := lockKey(name)
+	var version cache.Version
+	current, v, err := l.cache.GetWithVersion(ctx, key)
+	switch {
+	case err == nil && string(current) != released:
+		return nil, fmt.Errorf("lock '%s': %w", name, ErrNotAcquired)
+	case err == nil:
+		version = v
+	case !errors.Is(err, cache.ErrNotFound):
+		return nil, fmt.Errorf("unable to read lock '%s': %w", name, err)
+	}
+
+	// The token is allocated before the lock is acquired, so that it is written
+	// to the lock by the same CompareAndSwap that acquires it. A token allocated
+	// after the CompareAndSwap could be lower than the token of a lease that
+	// acquired the lock in the meantime.
+	token, err := l.cache.Increment(ctx, fenceKey(name), 1)
+	if err != nil {
+		return nil, fmt.Errorf("unable to issue fencing token for lock '%s': %w", name, err)
+	}
+	lease := &cacheLease{
+		locker: l,
+		name:   name,
+		owner:  owner,
+		token:  uint64(token),
+	}
+
+	ok, err := l.cache.CompareAndSwap(ctx, key, version, lease.value(), cache.WithTTL(o.TTL))
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire lock '%s': %w", name, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("lock '%s': %w", name, ErrNotAcquired)
+	}
+	lease.expiresAt = l.now().Add(o.TTL)
+	return lease, nil
+}
+
+type cacheLease struct {
+	locker *cacheLocker
+	name   string
+	owner  string
+	token  uint64
+
+	mu        sync.Mutex
+	expiresAt time.Time
+}
+
+func (l *cacheLease) Name() string {
+	return l.name
+}
+
+func (l *cacheLease) Token() uint64 {
+	return l.token
+}
+
+func (l *cacheLease) ExpiresAt() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.expiresAt
+}
+
+func (l *cacheLease) Renew(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("invalid lock TTL %v: must be positive", ttl)
+	}
+	if err := l.swap(ctx, l.value(), ttl); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.expiresAt = l.locker.now().Add(ttl)
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *cacheLease) Release(ctx context.Context) error {
+	if err := l.swap(ctx, []byte(released), releasedTTL); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.expiresAt = l.locker.now()
+	l.mu.Unlock()
+	return nil
+}
+
+// value returns the value of the lock key while it is held by the lease: the
+// fencing token and the owner.
+func (l *cacheLease) value() []byte {
+	return []byte(strconv.FormatUint(l.token, 10) + ":" + l.owner)
+}
+
+// swap atomically replaces the value of the lock key if it is still held by the
+// lease, i.e. if it contains both the owner and the fencing token of the lease.
+func (l *cacheLease) swap(ctx context.Context, value []byte, ttl time.Duration) error {
+	key := lockKey(l.name)
+	current, version, err := l.locker.cache.GetWithVersion(ctx, key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return fmt.Errorf("lock '%s': %w", l.name, ErrLeaseLost)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read lock '%s': %w", l.name, err)
+	}
+	if !bytes.Equal(current, l.value()) {
+		return fmt.Errorf("lock '%s': %w", l.name, ErrLeaseLost)
+	}
+
+	ok, err := l.locker.cache.CompareAndSwap(ctx, key, version, value, cache.WithTTL(ttl))
+	if err != nil {
+		return fmt.Errorf("unable to update lock '%s': %w", l.name, err)
+	}
+	if !ok {
+		return fmt.Errorf("lock '%s': %w", l.name, ErrLeaseLost)
+	}
+	return nil
+}
+
+func newOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate lock owner: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,322 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/strata-io/service-extension/cache"
+)
+
+// clock is a manually advanced clock used to expire locks.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// atomicCache is a cache.Atomic backed by a map that expires keys according to
+// a clock.
+type atomicCache struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]atomicEntry
+	seq     uint64
+}
+
+type atomicEntry struct {
+	value     []byte
+	version   cache.Version
+	expiresAt time.Time
+}
+
+func newAtomicCache(now func() time.Time) *atomicCache {
+	return &atomicCache{now: now, entries: make(map[string]atomicEntry)}
+}
+
+func (c *atomicCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := c.GetWithVersion(ctx, key)
+	return value, err
+}
+
+func (c *atomicCache) SetBytes(_ context.Context, key string, value []byte, opts ...cache.Option) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, opts)
+	return nil
+}
+
+func (c *atomicCache) SetIfAbsent(ctx context.Context, key string, value []byte, opts ...cache.Option) (bool, error) {
+	return c.CompareAndSwap(ctx, key, "", value, opts...)
+}
+
+func (c *atomicCache) GetWithVersion(_ context.Context, key string) ([]byte, cache.Version, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.load(key)
+	if !ok {
+		return nil, "", fmt.Errorf("key '%s': %w", key, cache.ErrNotFound)
+	}
+	return append([]byte(nil), e.value...), e.version, nil
+}
+
+func (c *atomicCache) CompareAndSwap(_ context.Context, key string, version cache.Version, value []byte, opts ...cache.Option) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, _ := c.load(key)
+	if e.version != version {
+		return false, nil
+	}
+	c.set(key, value, opts)
+	return true, nil
+}
+
+func (c *atomicCache) Increment(_ context.Context, key string, delta int64, opts ...cache.Option) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n int64
+	if e, ok := c.load(key); ok {
+		var err error
+		if n, err = strconv.ParseInt(string(e.value), 10, 64); err != nil {
+			return 0, fmt.Errorf("key '%s': %w", key, cache.ErrNotInteger)
+		}
+	}
+	n += delta
+	c.set(key, []byte(strconv.FormatInt(n, 10)), opts)
+	return n, nil
+}
+
+func (c *atomicCache) Decrement(ctx context.Context, key string, delta int64, opts ...cache.Option) (int64, error) {
+	return c.Increment(ctx, key, -delta, opts...)
+}
+
+// load returns the entry of key if it exists and has not expired. The caller
+// must hold c.mu.
+func (c *atomicCache) load(key string) (atomicEntry, bool) {
+	e, ok := c.entries[key]
+	if !ok || (!e.expiresAt.IsZero() && !c.now().Before(e.expiresAt)) {
+		return atomicEntry{}, false
+	}
+	return e, true
+}
+
+// set writes a new version of key. The caller must hold c.mu.
+func (c *atomicCache) set(key string, value []byte, opts []cache.Option) {
+	o := cache.Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c.seq++
+	e := atomicEntry{value: append([]byte(nil), value...), version: cache.Version(strconv.FormatUint(c.seq, 10))}
+	if o.TTL > 0 {
+		e.expiresAt = c.now().Add(o.TTL)
+	}
+	c.entries[key] = e
+}
+
+func newTestLocker() (Locker, *atomicCache, *clock) {
+	clk := &clock{now: time.Now()}
+	c := newAtomicCache(clk.Now)
+	return NewCacheLocker(c, WithClock(clk.Now)), c, clk
+}
+
+func TestCacheLockerMutualExclusion(t *testing.T) {
+	locker, _, _ := newTestLocker()
+	ctx := context.Background()
+
+	var (
+		held    atomic.Int32
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		tokens  []uint64
+		failure atomic.Value
+	)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lease, err := locker.Acquire(ctx, "job", WithRetryInterval(time.Millisecond))
+			if err != nil {
+				failure.Store(err)
+				return
+			}
+			if held.Add(1) != 1 {
+				failure.Store(errors.New("lock held by more than one lease"))
+			}
+			mu.Lock()
+			tokens = append(tokens, lease.Token())
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			held.Add(-1)
+			if err := lease.Release(ctx); err != nil {
+				failure.Store(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err, ok := failure.Load().(error); ok {
+		t.Fatal(err)
+	}
+	// Leases are acquired one at a time, so each token must exceed the previous.
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i] <= tokens[i-1] {
+			t.Fatalf("tokens %v are not increasing", tokens)
+		}
+	}
+}
+
+func TestCacheLockerTryAcquireHeld(t *testing.T) {
+	locker, _, _ := newTestLocker()
+	ctx := context.Background()
+	if _, err := locker.TryAcquire(ctx, "job"); err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	if _, err := locker.TryAcquire(ctx, "job"); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("TryAcquire() error = %v, want ErrNotAcquired", err)
+	}
+}
+
+func TestCacheLockerStaleLease(t *testing.T) {
+	locker, _, clk := newTestLocker()
+	ctx := context.Background()
+
+	stale, err := locker.TryAcquire(ctx, "job", WithTTL(time.Second))
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	clk.Advance(2 * time.Second)
+	current, err := locker.TryAcquire(ctx, "job", WithTTL(time.Minute))
+	if err != nil {
+		t.Fatalf("TryAcquire() after expiry error = %v", err)
+	}
+	if current.Token() <= stale.Token() {
+		t.Errorf("Token() = %d, want greater than %d", current.Token(), stale.Token())
+	}
+
+	if err := stale.Renew(ctx, time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Renew() of stale lease error = %v, want ErrLeaseLost", err)
+	}
+	if err := stale.Release(ctx); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Release() of stale lease error = %v, want ErrLeaseLost", err)
+	}
+	if err := current.Renew(ctx, time.Minute); err != nil {
+		t.Errorf("Renew() error = %v", err)
+	}
+	if err := current.Release(ctx); err != nil {
+		t.Errorf("Release() error = %v", err)
+	}
+	if err := current.Release(ctx); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("second Release() error = %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestCacheLeaseRejectsOtherToken(t *testing.T) {
+	locker, c, _ := newTestLocker()
+	ctx := context.Background()
+
+	lease, err := locker.TryAcquire(ctx, "job")
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	// A lock value with the same owner but another token must not be treated as
+	// held by the lease.
+	l := lease.(*cacheLease)
+	other := &cacheLease{name: l.name, owner: l.owner, token: l.token + 1}
+	if err := c.SetBytes(ctx, lockKey("job"), other.value()); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	if err := lease.Release(ctx); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Release() error = %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestCacheLeaseExpiresAtUsesClock(t *testing.T) {
+	locker, _, clk := newTestLocker()
+	ctx := context.Background()
+	clk.Advance(-time.Hour)
+	start := clk.Now()
+
+	lease, err := locker.TryAcquire(ctx, "job", WithTTL(time.Minute))
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	if got, want := lease.ExpiresAt(), start.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("ExpiresAt() = %v, want %v", got, want)
+	}
+	clk.Advance(30 * time.Second)
+	if err := lease.Renew(ctx, time.Minute); err != nil {
+		t.Fatalf("Renew() error = %v", err)
+	}
+	if got, want := lease.ExpiresAt(), start.Add(90*time.Second); !got.Equal(want) {
+		t.Errorf("ExpiresAt() after Renew = %v, want %v", got, want)
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	locker, _, _ := newTestLocker()
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"zero TTL", []Option{WithTTL(0)}},
+		{"negative TTL", []Option{WithTTL(-time.Second)}},
+		{"zero retry interval", []Option{WithRetryInterval(0)}},
+		{"negative retry interval", []Option{WithRetryInterval(-time.Second)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := locker.TryAcquire(ctx, "job", tt.opts...); err == nil {
+				t.Error("TryAcquire() succeeded")
+			}
+			if _, err := locker.Acquire(ctx, "job", tt.opts...); err == nil {
+				t.Error("Acquire() succeeded")
+			}
+			called := false
+			err := Do(ctx, locker, "job", func(context.Context, Lease) error {
+				called = true
+				return nil
+			}, tt.opts...)
+			if err == nil || called {
+				t.Errorf("Do() error = %v, called = %v, want an error without calling fn", err, called)
+			}
+		})
+	}
+
+	lease, err := locker.TryAcquire(ctx, "job")
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	if err := lease.Renew(ctx, 0); err == nil {
+		t.Error("Renew(0) succeeded")
+	}
+}
+
+func TestDoShortTTL(t *testing.T) {
+	locker, _, _ := newTestLocker()
+	// A TTL shorter than three nanoseconds must not make the renewal ticker
+	// panic.
+	err := Do(context.Background(), locker, "job", func(context.Context, Lease) error {
+		return nil
+	}, WithTTL(time.Nanosecond))
+	if err != nil && !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Do() error = %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+// Package lock provides lease-based distributed locks for service extensions.
+// Locks coordinate work, such as token refresh or provisioning, that must
+// happen at most once at a time across a cluster of Orchestrators.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotAcquired is returned by TryAcquire when the lock is held by another
+// owner.
+var ErrNotAcquired = errors.New("lock is held by another owner")
+
+// ErrLeaseLost is returned when operating on a lease that has expired, was
+// released, or was acquired by another owner.
+var ErrLeaseLost = errors.New("lease has been lost")
+
+// Locker provides named locks.
+//
+// Example:
+//
+//	lp, ok := api.(orchestrator.LockerProvider)
+//	if !ok {
+//		return errors.New("locks not available")
+//	}
+//	locker, err := lp.Locker("provisioning")
+//	if err != nil {
+//		return err
+//	}
+//	lease, err := locker.Acquire(ctx, "user:"+uid, lock.WithTTL(30*time.Second))
+//	if err != nil {
+//		return err
+//	}
+//	defer lease.Release(ctx)
+//
+//	// Pass lease.Token() to downstream systems so they can reject writes from
+//	// owners whose lease has expired.
+type Locker interface {
+	// Acquire blocks until the named lock is acquired or the context is done.
+	Acquire(ctx context.Context, name string, opts ...Option) (Lease, error)
+
+	// TryAcquire attempts to acquire the named lock once. If the lock is held by
+	// another owner, an error wrapping ErrNotAcquired is returned.
+	TryAcquire(ctx context.Context, name string, opts ...Option) (Lease, error)
+}
+
+// Lease represents ownership of a lock for a limited time. A lease must be
+// renewed before it expires, otherwise another owner may acquire the lock.
+type Lease interface {
+	// Name returns the name of the lock.
+	Name() string
+
+	// Token returns the fencing token of the lease. Fencing tokens increase
+	// strictly every time a given lock is acquired, so a resource guarded by the
+	// lock can reject requests carrying a token lower than one it has already
+	// seen.
+	Token() uint64
+
+	// ExpiresAt returns the time at which the lease expires unless it is renewed.
+	ExpiresAt() time.Time
+
+	// Renew extends the lease by the given TTL from now. If the lease has already
+	// been lost, an error wrapping ErrLeaseLost is returned.
+	Renew(ctx context.Context, ttl time.Duration) error
+
+	// Release releases the lock. Releasing a lease that has already been lost
+	// returns an error wrapping ErrLeaseLost.
+	Release(ctx context.Context) error
+}
+
+// Options are the options used when acquiring a lock.
+type Options struct {
+	// TTL is the lifetime of the lease. Must be positive. Defaults to 30 seconds.
+	TTL time.Duration
+
+	// RetryInterval is the interval at which Acquire retries while the lock is
+	// held by another owner. Must be positive. Defaults to 100 milliseconds.
+	RetryInterval time.Duration
+}
+
+// Option is an option used when acquiring a lock.
+type Option func(*Options)
+
+// WithTTL configures the lifetime of the lease.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = ttl
+	}
+}
+
+// WithRetryInterval configures the interval at which Acquire retries while the
+// lock is held by another owner.
+func WithRetryInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.RetryInterval = interval
+	}
+}
+
+// newOptions applies opts to the default options, returning an error if the
+// result is invalid.
+func newOptions(opts []Option) (Options, error) {
+	o := Options{
+		TTL:           30 * time.Second,
+		RetryInterval: 100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.TTL <= 0 {
+		return Options{}, fmt.Errorf("invalid lock TTL %v: must be positive", o.TTL)
+	}
+	if o.RetryInterval <= 0 {
+		return Options{}, fmt.Errorf("invalid lock retry interval %v: must be positive", o.RetryInterval)
+	}
+	return o, nil
+}
+
+// Do acquires the named lock, calls fn while holding it and releases it
+// afterwards. The lease is renewed in the background at a third of its TTL. An
+// error is returned without acquiring the lock if the options are invalid.
+// If the lease is lost while fn is running, the context passed to fn is
+// cancelled and an error wrapping ErrLeaseLost is returned.
+//
+// Example:
+//
+//	err := lock.Do(ctx, locker, "refresh:"+uid, func(ctx context.Context, lease lock.Lease) error {
+//		return refreshToken(ctx, uid)
+//	})
+func Do(ctx context.Context, l Locker, name string, fn func(ctx context.Context, lease Lease) error, opts ...Option) error {
+	o, err := newOptions(opts)
+	if err != nil {
+		return err
+	}
+	lease, err := l.Acquire(ctx, name, opts...)
+	if err != nil {
+		return err
+	}
+
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(max(o.TTL/3, time.Nanosecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := lease.Renew(fnCtx, o.TTL); err != nil {
+					cancel(err)
+					return
+				}
+			}
+		}
+	}()
+
+	fnErr := fn(fnCtx, lease)
+	close(done)
+	<-renewed
+
+	if cause := context.Cause(fnCtx); errors.Is(cause, ErrLeaseLost) {
+		return errors.Join(fnErr, cause)
+	}
+	// Release with the caller's context so that a cancelled fn context does not
+	// prevent the lock from being released.
+	if err := lease.Release(context.WithoutCancel(ctx)); err != nil {
+		return errors.Join(fnErr, err)
+	}
+	return fnErr
+}
@@ -1,10 +1,23 @@
 package log
 
-import "net/http"
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+)
 
 // Logger exposes three different levels of logging.
 //
-// The logger expects key-value pairs to enable structured logging.
+// The logger expects key-value pairs to enable structured logging. Keys must be
+// strings. A slog.Attr may be passed in place of a key-value pair. Use Validate
+// to check key-value pairs, e.g. in tests.
+//
+// Implementations redact sensitive values as described by Redact at every
+// level, so values wrapped with Secret and the values of sensitive keys such as
+// "password" are never written.
+//
+// Loggers returned by the Orchestrator may support further operations through
+// the optional Extended interface. Use Extend to use them with any Logger.
 //
 // Example:
 //
@@ -20,6 +33,148 @@ type Logger interface {
 	Error(keyPairs ...any)
 }
 
+// Extended is implemented by loggers that support the warn level, child
+// loggers and level checks.
+type Extended interface {
+	Logger
+
+	// Warn will log at warn level.
+	Warn(keyPairs ...any)
+
+	// With returns a child logger that includes the given key-value pairs in
+	// every message it logs.
+	//
+	// Example:
+	//
+	//	logger := log.Extend(api.Logger()).With("se", "ldap-search", "uid", uid)
+	//	logger.Info("msg", "loading attributes")
+	With(keyPairs ...any) Extended
+
+	// Enabled reports whether messages at the given level are logged. It can be
+	// used to avoid computing expensive values that would be discarded.
+	//
+	// Example:
+	//
+	//	if logger.Enabled(log.LevelDebug) {
+	//		logger.Debug("msg", "loaded groups", "groups", strings.Join(groups, ","))
+	//	}
+	Enabled(level Level) bool
+}
+
+// Extend returns l if it implements Extended. Otherwise, it returns an Extended
+// that writes to l: warnings are logged at info level, the key-value pairs of
+// child loggers are appended to those of every message, and all levels are
+// reported as enabled.
+//
+// Example:
+//
+//	logger := log.Extend(api.Logger())
+//	logger.Warn("msg", "falling back to the secondary LDAP server", "server", server)
+func Extend(l Logger) Extended {
+	if e, ok := l.(Extended); ok {
+		return e
+	}
+	return extendedLogger{logger: l}
+}
+
+type extendedLogger struct {
+	logger   Logger
+	keyPairs []any
+}
+
+func (l extendedLogger) Debug(keyPairs ...any) {
+	l.logger.Debug(l.append(keyPairs)...)
+}
+
+func (l extendedLogger) Info(keyPairs ...any) {
+	l.logger.Info(l.append(keyPairs)...)
+}
+
+func (l extendedLogger) Warn(keyPairs ...any) {
+	l.logger.Info(l.append(keyPairs)...)
+}
+
+func (l extendedLogger) Error(keyPairs ...any) {
+	l.logger.Error(l.append(keyPairs)...)
+}
+
+func (l extendedLogger) With(keyPairs ...any) Extended {
+	return extendedLogger{logger: l.logger, keyPairs: append(l.keyPairs[:len(l.keyPairs):len(l.keyPairs)], keyPairs...)}
+}
+
+func (l extendedLogger) Enabled(Level) bool {
+	return true
+}
+
+// append returns keyPairs followed by the key-value pairs of the logger, so
+// that a leading message key is passed first.
+func (l extendedLogger) append(keyPairs []any) []any {
+	if len(l.keyPairs) == 0 {
+		return keyPairs
+	}
+	return append(keyPairs[:len(keyPairs):len(keyPairs)], l.keyPairs...)
+}
+
+// Level is the severity of a log message.
+type Level int
+
+const (
+	// LevelDebug is the level of verbose messages used for troubleshooting.
+	LevelDebug Level = iota + 1
+	// LevelInfo is the level of messages describing normal operation.
+	LevelInfo
+	// LevelWarn is the level of messages describing unexpected conditions that do
+	// not prevent a request from being served.
+	LevelWarn
+	// LevelError is the level of messages describing failures.
+	LevelError
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// Slog returns the slog.Level corresponding to the level.
+func (l Level) Slog() slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// LevelFromSlog returns the Level corresponding to a slog.Level. Levels between
+// the standard slog levels are rounded down.
+func LevelFromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	default:
+		return LevelError
+	}
+}
+
 // Option is an option used to configure the retrieval of the Logger.
 //
 // Example:
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// recorder is a Logger implementing none of the optional interfaces, recording
+// the key-value pairs of every message.
+type recorder struct {
+	entries []entry
+}
+
+type entry struct {
+	level    Level
+	keyPairs []any
+}
+
+func (r *recorder) Debug(keyPairs ...any) { r.log(LevelDebug, keyPairs) }
+func (r *recorder) Info(keyPairs ...any)  { r.log(LevelInfo, keyPairs) }
+func (r *recorder) Error(keyPairs ...any) { r.log(LevelError, keyPairs) }
+
+func (r *recorder) log(level Level, keyPairs []any) {
+	r.entries = append(r.entries, entry{level: level, keyPairs: keyPairs})
+}
+
+func TestExtend(t *testing.T) {
+	r := &recorder{}
+	l := Extend(r)
+	if !l.Enabled(LevelDebug) {
+		t.Error("Enabled(LevelDebug) = false, want true")
+	}
+
+	child := l.With("server", "s1").With("attempt", 2)
+	l.Debug("msg", "plain")
+	child.Warn("msg", "retrying")
+	child.Error("msg", "failed", "err", "timeout")
+
+	want := []entry{
+		{LevelDebug, []any{"msg", "plain"}},
+		{LevelInfo, []any{"msg", "retrying", "server", "s1", "attempt", 2}},
+		{LevelError, []any{"msg", "failed", "err", "timeout", "server", "s1", "attempt", 2}},
+	}
+	if !reflect.DeepEqual(r.entries, want) {
+		t.Errorf("entries = %v, want %v", r.entries, want)
+	}
+}
+
+func TestExtendWithDoesNotShareKeyPairs(t *testing.T) {
+	r := &recorder{}
+	parent := Extend(r).With("a", 1)
+	first := parent.With("b", 2)
+	second := parent.With("c", 3)
+	first.Info("msg", "first")
+	second.Info("msg", "second")
+
+	want := []entry{
+		{LevelInfo, []any{"msg", "first", "a", 1, "b", 2}},
+		{LevelInfo, []any{"msg", "second", "a", 1, "c", 3}},
+	}
+	if !reflect.DeepEqual(r.entries, want) {
+		t.Errorf("entries = %v, want %v", r.entries, want)
+	}
+}
+
+func TestExtendExtended(t *testing.T) {
+	if _, ok := Extend(FromSlog(slog.Default())).(slogLogger); !ok {
+		t.Error("Extend() wrapped a logger implementing Extended")
+	}
+}
+
+func TestLevelSlog(t *testing.T) {
+	for _, level := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		if got := LevelFromSlog(level.Slog()); got != level {
+			t.Errorf("LevelFromSlog(%v.Slog()) = %v", level, got)
+		}
+	}
+}
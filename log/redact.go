@@ -0,0 +1,243 @@
+package log
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces sensitive values in log messages.
+const Redacted = "[REDACTED]"
+
+// SensitiveValue wraps a value that must never be logged. Its String, GoString,
+// LogValue and MarshalJSON methods all return Redacted, so the value is
+// redacted whether it is passed to a Logger, a slog.Logger, formatted with fmt
+// or encoded as JSON.
+type SensitiveValue struct {
+	value any
+}
+
+// Secret marks a value as sensitive. Regardless of its key, the value is
+// replaced by Redacted when it is logged.
+//
+// Example:
+//
+//	logger.Debug("msg", "binding to LDAP", "dn", dn, "credential", log.Secret(password))
+func Secret(v any) SensitiveValue {
+	return SensitiveValue{value: v}
+}
+
+// String returns Redacted.
+func (SensitiveValue) String() string {
+	return Redacted
+}
+
+// GoString returns Redacted.
+func (SensitiveValue) GoString() string {
+	return Redacted
+}
+
+// LogValue returns Redacted as a slog.Value.
+func (SensitiveValue) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+// MarshalJSON returns Redacted as a JSON string.
+func (SensitiveValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Redacted)
+}
+
+var sensitiveKeys = struct {
+	mu   sync.RWMutex
+	keys map[string]struct{}
+}{
+	keys: map[string]struct{}{
+		"password":      {},
+		"passwd":        {},
+		"secret":        {},
+		"client_secret": {},
+		"access_token":  {},
+		"refresh_token": {},
+		"id_token":      {},
+		"token":         {},
+		"authorization": {},
+		"cookie":        {},
+		"set_cookie":    {},
+		"api_key":       {},
+		"private_key":   {},
+	},
+}
+
+// RegisterSensitiveKeys adds keys whose values are replaced by Redacted when
+// they are logged. Keys are matched as described by IsSensitiveKey.
+//
+// Example:
+//
+//	func init() {
+//		log.RegisterSensitiveKeys("ssn", "otp")
+//	}
+func RegisterSensitiveKeys(keys ...string) {
+	sensitiveKeys.mu.Lock()
+	defer sensitiveKeys.mu.Unlock()
+	for _, k := range keys {
+		sensitiveKeys.keys[normalizeKey(k)] = struct{}{}
+	}
+}
+
+// IsSensitiveKey reports whether values logged under the given key are
+// redacted. Keys are normalized before they are compared: they are lowercased,
+// camel case is split into words, and "-" and spaces are treated like "_". A
+// key matches a registered key if it is equal to it or ends with it after a
+// "_" or "." separator, so that "password", "ldap.password", "bindPassword" and
+// "X-Api-Key" match the registered keys "password" and "api_key". By default,
+// common names of credentials such as "password", "access_token" and
+// "authorization" are sensitive.
+func IsSensitiveKey(key string) bool {
+	key = normalizeKey(key)
+	sensitiveKeys.mu.RLock()
+	defer sensitiveKeys.mu.RUnlock()
+	if _, ok := sensitiveKeys.keys[key]; ok {
+		return true
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] != '_' && key[i] != '.' {
+			continue
+		}
+		if _, ok := sensitiveKeys.keys[key[i+1:]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeKey returns key in lower snake case, e.g. "x_api_key" for both
+// "X-Api-Key" and "xApiKey".
+func normalizeKey(key string) string {
+	var b strings.Builder
+	b.Grow(len(key) + 4)
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			// Start a new word at "K" in "apiKey" and "APIKey", but not within
+			// "API".
+			if i > 0 && (isLowerOrDigit(key[i-1]) || (isUpper(key[i-1]) && i+1 < len(key) && isLowerOrDigit(key[i+1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteByte(c + ('a' - 'A'))
+		case c == '-' || c == ' ':
+			b.WriteByte('_')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func isLowerOrDigit(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+// Redact returns a copy of keyPairs in which the values of sensitive keys and
+// values wrapped with Secret are replaced by Redacted. slog.Attr elements,
+// including the attributes of groups, are redacted by key in the same way.
+// Values implementing slog.LogValuer are resolved, so that groups they resolve
+// to are redacted as well.
+//
+// Implementations of Logger apply Redact to all key-value pairs, regardless of
+// the level they are logged at.
+func Redact(keyPairs ...any) []any {
+	out := make([]any, len(keyPairs))
+	for i := 0; i < len(keyPairs); i++ {
+		switch k := keyPairs[i].(type) {
+		case slog.Attr:
+			out[i] = redactAttr(k)
+		case string:
+			out[i] = k
+			if i+1 < len(keyPairs) {
+				i++
+				out[i] = redactValue(IsSensitiveKey(k), keyPairs[i])
+			}
+		default:
+			out[i] = redactValue(false, k)
+		}
+	}
+	return out
+}
+
+func redactValue(sensitiveKey bool, v any) any {
+	if _, ok := v.(SensitiveValue); ok || sensitiveKey {
+		return Redacted
+	}
+	// Values implementing slog.LogValuer may resolve to groups holding
+	// sensitive keys.
+	if _, ok := v.(slog.LogValuer); ok {
+		if rv := slog.AnyValue(v).Resolve(); rv.Kind() == slog.KindGroup {
+			return slog.GroupValue(redactGroup(rv.Group())...)
+		}
+	}
+	return v
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	if IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		return a
+	}
+	return slog.Attr{Key: a.Key, Value: slog.GroupValue(redactGroup(a.Value.Group())...)}
+}
+
+func redactGroup(group []slog.Attr) []slog.Attr {
+	redacted := make([]slog.Attr, len(group))
+	for i, ga := range group {
+		redacted[i] = redactAttr(ga)
+	}
+	return redacted
+}
+
+// Redacting returns a Logger that applies Redact to all key-value pairs before
+// passing them to l. It can be used to add redaction to a custom Logger
+// implementation. If l does not implement Extended, it is used as described by
+// Extend.
+func Redacting(l Logger) Extended {
+	if r, ok := l.(redactingLogger); ok {
+		return r
+	}
+	return redactingLogger{logger: Extend(l)}
+}
+
+type redactingLogger struct {
+	logger Extended
+}
+
+func (l redactingLogger) Debug(keyPairs ...any) {
+	l.logger.Debug(Redact(keyPairs...)...)
+}
+
+func (l redactingLogger) Info(keyPairs ...any) {
+	l.logger.Info(Redact(keyPairs...)...)
+}
+
+func (l redactingLogger) Warn(keyPairs ...any) {
+	l.logger.Warn(Redact(keyPairs...)...)
+}
+
+func (l redactingLogger) Error(keyPairs ...any) {
+	l.logger.Error(Redact(keyPairs...)...)
+}
+
+func (l redactingLogger) With(keyPairs ...any) Extended {
+	return redactingLogger{logger: l.logger.With(Redact(keyPairs...)...)}
+}
+
+func (l redactingLogger) Enabled(level Level) bool {
+	return l.logger.Enabled(level)
+}
@@ -0,0 +1,176 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// credentials is a slog.LogValuer resolving to a group with a sensitive key.
+type credentials struct {
+	user, password string
+}
+
+func (c credentials) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("user", c.user), slog.String("password", c.password))
+}
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"PASSWORD", true},
+		{"Password", true},
+		{"ldap.password", true},
+		{"ldap.bind.password", true},
+		{"bind_password", true},
+		{"bindPassword", true},
+		{"api_key", true},
+		{"api-key", true},
+		{"x-api-key", true},
+		{"X-Api-Key", true},
+		{"xApiKey", true},
+		{"APIKey", true},
+		{"Set-Cookie", true},
+		{"clientSecret", true},
+		{"oidc.refreshToken", true},
+		{"Authorization", true},
+		{"user", false},
+		{"passwords_count", false},
+		{"keyring", false},
+		{"api", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := IsSensitiveKey(tt.key); got != tt.want {
+				t.Errorf("IsSensitiveKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterSensitiveKeys(t *testing.T) {
+	if IsSensitiveKey("user.socialSecurityNumber") {
+		t.Fatal("key is sensitive before it is registered")
+	}
+	RegisterSensitiveKeys("Social-Security-Number")
+	if !IsSensitiveKey("user.socialSecurityNumber") {
+		t.Error("registered key is not sensitive")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []any
+		want []any
+	}{
+		{
+			"sensitive keys",
+			[]any{"msg", "bind", "user", "alice", "X-Api-Key", "k", "ldap.password", "p"},
+			[]any{"msg", "bind", "user", "alice", "X-Api-Key", Redacted, "ldap.password", Redacted},
+		},
+		{
+			"secret",
+			[]any{"credential", Secret("p"), "count", 1},
+			[]any{"credential", Redacted, "count", 1},
+		},
+		{
+			"secret without key",
+			[]any{Secret("p")},
+			[]any{Redacted},
+		},
+		{
+			"missing value",
+			[]any{"password"},
+			[]any{"password"},
+		},
+		{
+			"attr",
+			[]any{slog.String("accessToken", "t"), slog.Int("n", 1)},
+			[]any{slog.String("accessToken", Redacted), slog.Int("n", 1)},
+		},
+		{
+			"attr with secret",
+			[]any{slog.Any("credential", Secret("p"))},
+			[]any{slog.String("credential", Redacted)},
+		},
+		{
+			"nested groups",
+			[]any{slog.Group("ldap", slog.String("server", "s"), slog.Group("bind", slog.String("dn", "d"), slog.String("Password", "p")))},
+			[]any{slog.Group("ldap", slog.String("server", "s"), slog.Group("bind", slog.String("dn", "d"), slog.String("Password", Redacted)))},
+		},
+		{
+			"log valuer attr",
+			[]any{slog.Any("creds", credentials{"alice", "p"})},
+			[]any{slog.Group("creds", slog.String("user", "alice"), slog.String("password", Redacted))},
+		},
+		{
+			"log valuer value",
+			[]any{"creds", credentials{"alice", "p"}},
+			[]any{"creds", slog.GroupValue(slog.String("user", "alice"), slog.String("password", Redacted))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Redact(tt.in...)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Redact() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !equalLogValue(got[i], tt.want[i]) {
+					t.Errorf("Redact()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// equalLogValue reports whether a and b are equal, comparing slog attributes
+// and values by their resolved values.
+func equalLogValue(a, b any) bool {
+	switch av := a.(type) {
+	case slog.Attr:
+		bv, ok := b.(slog.Attr)
+		return ok && av.Equal(bv)
+	case slog.Value:
+		bv, ok := b.(slog.Value)
+		return ok && av.Equal(bv)
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func TestSensitiveValueFormatting(t *testing.T) {
+	s := Secret("p")
+	for _, got := range []string{fmt.Sprint(s), fmt.Sprintf("%v %s %#v", s, s, s), slog.AnyValue(s).Resolve().String()} {
+		if got != Redacted && got != Redacted+" "+Redacted+" "+Redacted {
+			t.Errorf("formatted secret = %q", got)
+		}
+	}
+	b, err := json.Marshal(map[string]any{"v": s})
+	if err != nil || string(b) != `{"v":"[REDACTED]"}` {
+		t.Errorf("json.Marshal() = %s, %v", b, err)
+	}
+}
+
+func TestRedacting(t *testing.T) {
+	r := &recorder{}
+	l := Redacting(r)
+	if got, ok := Redacting(l).(redactingLogger); !ok {
+		t.Error("Redacting() did not return a redacting logger")
+	} else if _, nested := got.logger.(redactingLogger); nested {
+		t.Error("Redacting() of a redacting logger wrapped it again")
+	}
+	l.With("token", "t").Warn("msg", "retrying", "password", "p")
+
+	want := []any{"msg", "retrying", "password", Redacted, "token", Redacted}
+	if len(r.entries) != 1 || r.entries[0].level != LevelInfo || !reflect.DeepEqual(r.entries[0].keyPairs, want) {
+		t.Errorf("entries = %+v, want an info entry with %v", r.entries, want)
+	}
+}
@@ -0,0 +1,144 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+// msgKey is the key under which the message of a slog.Record is passed to a
+// Logger, and from which the message of a slog.Record is taken.
+const msgKey = "msg"
+
+// NewSlogHandler returns a slog.Handler that writes records to l, so that
+// service extensions can log with a *slog.Logger and slog.Attr values. The
+// message of a record is passed under the "msg" key, followed by its
+// attributes. Attributes in groups are passed with keys qualified by the group
+// names, e.g. "ldap.server". Attributes are redacted as described by Redact. If
+// l does not implement Extended, it is used as described by Extend.
+//
+// Example:
+//
+//	logger := slog.New(log.NewSlogHandler(api.Logger()))
+//	logger.Info("bound to LDAP", slog.String("server", server), slog.Duration("took", took))
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{logger: Extend(l)}
+}
+
+type slogHandler struct {
+	logger Extended
+	// prefix qualifies the keys of attributes, e.g. "ldap." after WithGroup("ldap").
+	prefix string
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(LevelFromSlog(level))
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	keyPairs := make([]any, 0, 2+2*r.NumAttrs())
+	keyPairs = append(keyPairs, msgKey, r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		keyPairs = appendAttr(keyPairs, h.prefix, a)
+		return true
+	})
+
+	keyPairs = Redact(keyPairs...)
+	switch LevelFromSlog(r.Level) {
+	case LevelDebug:
+		h.logger.Debug(keyPairs...)
+	case LevelInfo:
+		h.logger.Info(keyPairs...)
+	case LevelWarn:
+		h.logger.Warn(keyPairs...)
+	default:
+		h.logger.Error(keyPairs...)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	keyPairs := make([]any, 0, 2*len(attrs))
+	for _, a := range attrs {
+		keyPairs = appendAttr(keyPairs, h.prefix, a)
+	}
+	return &slogHandler{logger: h.logger.With(Redact(keyPairs...)...), prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+// appendAttr appends a as key-value pairs, flattening groups into qualified
+// keys.
+func appendAttr(keyPairs []any, prefix string, a slog.Attr) []any {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return keyPairs
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(keyPairs, prefix+a.Key, a.Value.Any())
+	}
+	// Attributes of a group with an empty key are inlined.
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		keyPairs = appendAttr(keyPairs, prefix, ga)
+	}
+	return keyPairs
+}
+
+// FromSlog returns a Logger that writes to l, e.g. to use a standard
+// slog.Handler with code that expects a Logger. If the first key-value pair
+// has the key "msg", its value is used as the message of the record. Key-value
+// pairs are redacted as described by Redact.
+//
+// Example:
+//
+//	logger := log.FromSlog(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+func FromSlog(l *slog.Logger) Extended {
+	return slogLogger{logger: l}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Debug(keyPairs ...any) {
+	l.log(LevelDebug, keyPairs)
+}
+
+func (l slogLogger) Info(keyPairs ...any) {
+	l.log(LevelInfo, keyPairs)
+}
+
+func (l slogLogger) Warn(keyPairs ...any) {
+	l.log(LevelWarn, keyPairs)
+}
+
+func (l slogLogger) Error(keyPairs ...any) {
+	l.log(LevelError, keyPairs)
+}
+
+func (l slogLogger) With(keyPairs ...any) Extended {
+	return slogLogger{logger: l.logger.With(Redact(keyPairs...)...)}
+}
+
+func (l slogLogger) Enabled(level Level) bool {
+	return l.logger.Enabled(context.Background(), level.Slog())
+}
+
+func (l slogLogger) log(level Level, keyPairs []any) {
+	keyPairs = Redact(keyPairs...)
+	msg := ""
+	if len(keyPairs) >= 2 {
+		k, _ := keyPairs[0].(string)
+		if m, ok := keyPairs[1].(string); ok && k == msgKey {
+			msg, keyPairs = m, keyPairs[2:]
+		}
+	}
+	l.logger.Log(context.Background(), level.Slog(), msg, keyPairs...)
+}
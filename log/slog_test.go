@@ -0,0 +1,143 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *slog.Logger)
+		want entry
+	}{
+		{
+			"message and attributes",
+			func(l *slog.Logger) { l.Info("bound", "server", "s1", slog.Int("port", 636)) },
+			entry{LevelInfo, []any{"msg", "bound", "server", "s1", "port", int64(636)}},
+		},
+		{
+			"warn falls back to info",
+			func(l *slog.Logger) { l.Warn("retrying") },
+			entry{LevelInfo, []any{"msg", "retrying"}},
+		},
+		{
+			"sensitive keys",
+			func(l *slog.Logger) { l.Debug("request", "X-Api-Key", "k", "clientSecret", "s") },
+			entry{LevelDebug, []any{"msg", "request", "X-Api-Key", Redacted, "clientSecret", Redacted}},
+		},
+		{
+			"secret",
+			func(l *slog.Logger) { l.Error("bind failed", "credential", Secret("p")) },
+			entry{LevelError, []any{"msg", "bind failed", "credential", Redacted}},
+		},
+		{
+			"nested groups",
+			func(l *slog.Logger) {
+				l.Info("bound", slog.Group("ldap", "server", "s1", slog.Group("bind", "password", "p")))
+			},
+			entry{LevelInfo, []any{"msg", "bound", "ldap.server", "s1", "ldap.bind.password", Redacted}},
+		},
+		{
+			"log valuer",
+			func(l *slog.Logger) { l.Info("bound", "creds", credentials{"alice", "p"}) },
+			entry{LevelInfo, []any{"msg", "bound", "creds.user", "alice", "creds.password", Redacted}},
+		},
+		{
+			"with group and attrs",
+			func(l *slog.Logger) {
+				l.WithGroup("ldap").With("token", "t", "server", "s1").Info("bound", "user", "alice")
+			},
+			entry{LevelInfo, []any{"msg", "bound", "ldap.user", "alice", "ldap.token", Redacted, "ldap.server", "s1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			tt.log(slog.New(NewSlogHandler(r)))
+			if len(r.entries) != 1 || !reflect.DeepEqual(r.entries[0], tt.want) {
+				t.Errorf("entries = %v, want [%v]", r.entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromSlog(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l Extended)
+		want map[string]any
+	}{
+		{
+			"message",
+			func(l Extended) { l.Info("msg", "bound", "server", "s1") },
+			map[string]any{"level": "INFO", "msg": "bound", "server": "s1"},
+		},
+		{
+			"no message",
+			func(l Extended) { l.Warn("server", "s1") },
+			map[string]any{"level": "WARN", "msg": "", "server": "s1"},
+		},
+		{
+			"sensitive keys",
+			func(l Extended) { l.Error("msg", "failed", "x-api-key", "k", "ldap.bindPassword", "p") },
+			map[string]any{"level": "ERROR", "msg": "failed", "x-api-key": Redacted, "ldap.bindPassword": Redacted},
+		},
+		{
+			"secret",
+			func(l Extended) { l.Info("msg", "bound", "credential", Secret("p")) },
+			map[string]any{"level": "INFO", "msg": "bound", "credential": Redacted},
+		},
+		{
+			"nested groups",
+			func(l Extended) {
+				l.Info("msg", "bound", slog.Group("ldap", "server", "s1", slog.Group("bind", "Password", "p")))
+			},
+			map[string]any{"level": "INFO", "msg": "bound", "ldap": map[string]any{
+				"server": "s1", "bind": map[string]any{"Password": Redacted},
+			}},
+		},
+		{
+			"log valuer",
+			func(l Extended) { l.Info("msg", "bound", "creds", credentials{"alice", "p"}) },
+			map[string]any{"level": "INFO", "msg": "bound", "creds": map[string]any{"user": "alice", "password": Redacted}},
+		},
+		{
+			"with",
+			func(l Extended) { l.With("session-token", "t").Info("msg", "bound") },
+			map[string]any{"level": "INFO", "msg": "bound", "session-token": Redacted},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if len(groups) == 0 && a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			})
+			tt.log(FromSlog(slog.New(h)))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("json.Unmarshal(%q): %v", buf.String(), err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("record = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromSlogEnabled(t *testing.T) {
+	l := FromSlog(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	if l.Enabled(LevelInfo) || !l.Enabled(LevelError) {
+		t.Error("Enabled() does not follow the handler level")
+	}
+}
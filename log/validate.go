@@ -0,0 +1,39 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ErrInvalidKeyPairs is returned by Validate when key-value pairs are
+// malformed.
+var ErrInvalidKeyPairs = errors.New("invalid key-value pairs")
+
+// Validate reports malformed key-value pairs: keys that are not strings and
+// keys without a value. A slog.Attr is accepted in place of a key-value pair.
+// The returned error wraps ErrInvalidKeyPairs and describes every problem
+// found.
+//
+// Example:
+//
+//	// Returns an error because the key "error" has no value.
+//	err := log.Validate("msg", "unable to bind", "error")
+func Validate(keyPairs ...any) error {
+	var errs []error
+	for i := 0; i < len(keyPairs); i++ {
+		switch k := keyPairs[i].(type) {
+		case slog.Attr:
+			continue
+		case string:
+			if i+1 == len(keyPairs) {
+				errs = append(errs, fmt.Errorf("%w: key '%s' at position %d has no value", ErrInvalidKeyPairs, k, i))
+			}
+			i++
+		default:
+			errs = append(errs, fmt.Errorf("%w: key at position %d is a %T, not a string", ErrInvalidKeyPairs, i, k))
+			// Assume the key was omitted and the element is a value.
+		}
+	}
+	return errors.Join(errs...)
+}
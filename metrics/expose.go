@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"maps"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ContentTypePrometheus is the content type of the Prometheus text exposition
+	// format.
+	ContentTypePrometheus = "text/plain; version=0.0.4; charset=utf-8"
+
+	// ContentTypeOpenMetrics is the content type of the OpenMetrics text format.
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// WritePrometheus writes all metrics to w in the Prometheus text exposition
+// format, version 0.0.4.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		if f.Help != "" {
+			bw.WriteString("# HELP " + f.Name + " " + helpEscaper.Replace(f.Help) + "\n")
+		}
+		bw.WriteString("# TYPE " + f.Name + " " + f.Type.String() + "\n")
+		writeSamples(bw, f, f.Name)
+	}
+	return bw.Flush()
+}
+
+// WriteOpenMetrics writes all metrics to w in the OpenMetrics text format,
+// version 1.0.0. The family of a counter is named without its "_total" suffix,
+// while its samples always have the suffix.
+func (r *Registry) WriteOpenMetrics(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		name := familyName(f.Name, f.Type)
+		if f.Help != "" {
+			bw.WriteString("# HELP " + name + " " + openMetricsHelpEscaper.Replace(f.Help) + "\n")
+		}
+		bw.WriteString("# TYPE " + name + " " + f.Type.String() + "\n")
+		if f.Unit != "" {
+			bw.WriteString("# UNIT " + name + " " + f.Unit + "\n")
+		}
+		sample := name
+		if f.Type == TypeCounter {
+			sample += "_total"
+		}
+		writeSamples(bw, f, sample)
+	}
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+// Handler returns an http.Handler serving all metrics, in OpenMetrics if the
+// request accepts it and in the Prometheus text exposition format otherwise.
+//
+// Example:
+//
+//	api.Router().HandleFunc("/metrics", reg.Handler().ServeHTTP)
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if acceptsOpenMetrics(req.Header.Values("Accept")) {
+			w.Header().Set("Content-Type", ContentTypeOpenMetrics)
+			_ = r.WriteOpenMetrics(w)
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypePrometheus)
+		_ = r.WritePrometheus(w)
+	})
+}
+
+func acceptsOpenMetrics(accept []string) bool {
+	for _, h := range accept {
+		for _, part := range strings.Split(h, ",") {
+			if mt, _, err := mime.ParseMediaType(part); err == nil && mt == "application/openmetrics-text" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeSamples(w *bufio.Writer, f Family, name string) {
+	for _, s := range f.Series {
+		if f.Type != TypeHistogram {
+			writeSample(w, name, s.Labels, "", "", s.Value)
+			continue
+		}
+		for _, b := range s.Buckets {
+			writeSample(w, f.Name+"_bucket", s.Labels, "le", formatFloat(b.UpperBound), float64(b.Count))
+		}
+		writeSample(w, f.Name+"_sum", s.Labels, "", "", s.Sum)
+		writeSample(w, f.Name+"_count", s.Labels, "", "", float64(s.Count))
+	}
+}
+
+// writeSample writes a sample with the given labels, followed by the extra
+// label if extraName is not empty.
+func writeSample(w *bufio.Writer, name string, labels Labels, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		sep := ""
+		for _, k := range slices.Sorted(maps.Keys(labels)) {
+			w.WriteString(sep + k + `="` + labelEscaper.Replace(labels[k]) + `"`)
+			sep = ","
+		}
+		if extraName != "" {
+			w.WriteString(sep + extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	labelEscaper           = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper            = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	openMetricsHelpEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
@@ -0,0 +1,200 @@
+// Package metrics lets service extensions emit counters, gauges and
+// histograms, e.g. to alert on LDAP lookup latency or identity provider
+// selection failures. Metrics are exported in the Prometheus text exposition
+// format and in OpenMetrics.
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// Provider creates metrics. Creating a metric that already exists with the
+// same type returns the existing metric, so metrics may be created on every
+// request.
+//
+// Example:
+//
+//	mp, ok := api.(orchestrator.MetricsProvider)
+//	if !ok {
+//		return conn.Search(searchReq)
+//	}
+//	lookups, err := mp.Metrics().Histogram(
+//		"ldap_lookup_duration_seconds",
+//		metrics.WithHelp("Duration of LDAP lookups."),
+//		metrics.WithUnit("seconds"),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	start := time.Now()
+//	res, err := conn.Search(searchReq)
+//	lookups.With(metrics.Labels{"server": server}).ObserveDuration(start)
+type Provider interface {
+	// Counter gets or creates a counter. An error is returned if the name or
+	// options are invalid, a metric of a different type or with conflicting
+	// options exists with the same name, or the samples of the counter would
+	// collide with those of another metric.
+	Counter(name string, opts ...Option) (Counter, error)
+
+	// Gauge gets or creates a gauge. Errors are returned as for Counter.
+	Gauge(name string, opts ...Option) (Gauge, error)
+
+	// Histogram gets or creates a histogram. Errors are returned as for Counter.
+	Histogram(name string, opts ...Option) (Histogram, error)
+}
+
+// Labels are the label names and values identifying a series of a metric.
+// Label names must match [a-zA-Z_][a-zA-Z0-9_]*; other characters are replaced
+// with underscores. Reserved label names are ignored: names beginning with "__"
+// and, for histograms, "le".
+type Labels map[string]string
+
+// Counter is a metric whose value only increases, e.g. the number of failed
+// identity provider selections.
+type Counter interface {
+	// Inc increments the counter by 1.
+	Inc()
+
+	// Add increases the counter by v. Negative values are ignored.
+	Add(v float64)
+
+	// With returns the counter of the series with the given labels, in addition
+	// to the labels of this counter.
+	With(labels Labels) Counter
+}
+
+// Gauge is a metric whose value can go up and down, e.g. the number of open
+// LDAP connections.
+type Gauge interface {
+	// Set sets the gauge to v.
+	Set(v float64)
+
+	// Inc increments the gauge by 1.
+	Inc()
+
+	// Dec decrements the gauge by 1.
+	Dec()
+
+	// Add adds v, which may be negative, to the gauge.
+	Add(v float64)
+
+	// With returns the gauge of the series with the given labels, in addition to
+	// the labels of this gauge.
+	With(labels Labels) Gauge
+}
+
+// Histogram is a metric that counts observations, e.g. request durations, in
+// configurable buckets.
+type Histogram interface {
+	// Observe records an observation.
+	Observe(v float64)
+
+	// ObserveDuration records the time elapsed since start in seconds.
+	ObserveDuration(start time.Time)
+
+	// With returns the histogram of the series with the given labels, in addition
+	// to the labels of this histogram.
+	With(labels Labels) Histogram
+}
+
+// Type is the type of a metric.
+type Type int
+
+const (
+	// TypeCounter is the type of a Counter.
+	TypeCounter Type = iota + 1
+	// TypeGauge is the type of a Gauge.
+	TypeGauge
+	// TypeHistogram is the type of a Histogram.
+	TypeHistogram
+)
+
+// String returns the name of the type as used in the exposition formats.
+func (t Type) String() string {
+	switch t {
+	case TypeCounter:
+		return "counter"
+	case TypeGauge:
+		return "gauge"
+	case TypeHistogram:
+		return "histogram"
+	default:
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+}
+
+// DefaultBuckets are the upper bounds of the buckets of a Histogram if none are
+// configured, suitable for durations in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Options are the options used to configure a metric.
+type Options struct {
+	// Help describes the metric.
+	Help string
+
+	// Unit is the unit of the metric, e.g. "seconds" or "bytes". If set, the
+	// name of the metric must end with "_" followed by the unit, optionally
+	// followed by "_total" for counters.
+	Unit string
+
+	// Buckets are the upper bounds of the buckets of a histogram, in increasing
+	// order. Defaults to DefaultBuckets.
+	Buckets []float64
+}
+
+// Option is an option used to configure a metric.
+type Option func(*Options)
+
+// WithHelp configures the description of a metric.
+func WithHelp(help string) Option {
+	return func(o *Options) {
+		o.Help = help
+	}
+}
+
+// WithUnit configures the unit of a metric.
+func WithUnit(unit string) Option {
+	return func(o *Options) {
+		o.Unit = unit
+	}
+}
+
+// WithBuckets configures the upper bounds of the buckets of a histogram.
+func WithBuckets(buckets ...float64) Option {
+	return func(o *Options) {
+		o.Buckets = buckets
+	}
+}
+
+// Family is a snapshot of a metric and all of its series.
+type Family struct {
+	Name string
+	Help string
+	Unit string
+	Type Type
+
+	// Series are the series of the metric, ordered by their labels.
+	Series []Series
+}
+
+// Series is a snapshot of a series of a metric.
+type Series struct {
+	Labels Labels
+
+	// Value is the value of a counter or gauge.
+	Value float64
+
+	// Count, Sum and Buckets are the number of observations, their sum and the
+	// cumulative bucket counts of a histogram. The last bucket has an upper bound
+	// of +Inf.
+	Count   uint64
+	Sum     float64
+	Buckets []Bucket
+}
+
+// Bucket is a cumulative histogram bucket.
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
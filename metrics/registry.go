@@ -0,0 +1,398 @@
+package metrics
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	unitRE       = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+)
+
+// Registry is a Provider holding metrics in memory, from which they can be
+// exported with WritePrometheus, WriteOpenMetrics or Handler. It is safe for
+// concurrent use.
+//
+// Example:
+//
+//	reg := metrics.NewRegistry()
+//	failures, _ := reg.Counter("idp_selection_failures_total")
+//	failures.With(metrics.Labels{"reason": "unknown_idp"}).Inc()
+//	_ = reg.WritePrometheus(os.Stdout)
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+	// exposed maps every name exposed by a family, i.e. its family and sample
+	// names in both exposition formats, to the name of the family.
+	exposed map[string]string
+}
+
+var _ Provider = (*Registry)(nil)
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+		exposed:  make(map[string]string),
+	}
+}
+
+// Counter gets or creates a counter. Options that are not set do not conflict
+// with those of a registered counter. Samples collide with those of another
+// metric if they share a name in either exposition format, e.g. a counter
+// "requests_count" and a histogram "requests".
+func (r *Registry) Counter(name string, opts ...Option) (Counter, error) {
+	f, err := r.family(name, TypeCounter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return counter{f: f}, nil
+}
+
+// Gauge gets or creates a gauge.
+func (r *Registry) Gauge(name string, opts ...Option) (Gauge, error) {
+	f, err := r.family(name, TypeGauge, opts)
+	if err != nil {
+		return nil, err
+	}
+	return gauge{f: f}, nil
+}
+
+// Histogram gets or creates a histogram.
+func (r *Registry) Histogram(name string, opts ...Option) (Histogram, error) {
+	f, err := r.family(name, TypeHistogram, opts)
+	if err != nil {
+		return nil, err
+	}
+	return histogram{f: f}, nil
+}
+
+// Gather returns a snapshot of all metrics, ordered by name.
+func (r *Registry) Gather() []Family {
+	r.mu.RLock()
+	fams := slices.Collect(maps.Values(r.families))
+	r.mu.RUnlock()
+
+	sort.Slice(fams, func(i, j int) bool { return fams[i].name < fams[j].name })
+	out := make([]Family, 0, len(fams))
+	for _, f := range fams {
+		out = append(out, f.snapshot())
+	}
+	return out
+}
+
+// Reset removes all series of all metrics. Metrics remain registered, and
+// previously obtained Counters, Gauges and Histograms remain usable.
+func (r *Registry) Reset() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, f := range r.families {
+		f.mu.Lock()
+		clear(f.series)
+		f.mu.Unlock()
+	}
+}
+
+func (r *Registry) family(name string, typ Type, opts []Option) (*family, error) {
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	hasBuckets := len(o.Buckets) > 0
+	if err := validate(name, typ, &o); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	f, ok := r.families[name]
+	r.mu.RUnlock()
+	if !ok {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if f, ok = r.families[name]; !ok {
+			names := exposedNames(name, typ)
+			for _, n := range names {
+				if other, ok := r.exposed[n]; ok {
+					return nil, fmt.Errorf("metric '%s' collides with metric '%s' on name '%s'", name, other, n)
+				}
+			}
+			for _, n := range names {
+				r.exposed[n] = name
+			}
+			f = &family{
+				name:    name,
+				help:    o.Help,
+				unit:    o.Unit,
+				typ:     typ,
+				buckets: o.Buckets,
+				series:  make(map[string]*series),
+			}
+			r.families[name] = f
+			return f, nil
+		}
+	}
+
+	// Options that are not set do not conflict, so that a registered metric can
+	// be retrieved by its name alone.
+	switch {
+	case f.typ != typ:
+		return nil, fmt.Errorf("metric '%s' is already registered as a %s", name, f.typ)
+	case o.Help != "" && o.Help != f.help:
+		return nil, fmt.Errorf("metric '%s' is already registered with another help text", name)
+	case o.Unit != "" && o.Unit != f.unit:
+		return nil, fmt.Errorf("metric '%s' is already registered with another unit", name)
+	case hasBuckets && !slices.Equal(o.Buckets, f.buckets):
+		return nil, fmt.Errorf("metric '%s' is already registered with other buckets", name)
+	}
+	return f, nil
+}
+
+// exposedNames returns the family and sample names exposed for a metric in the
+// Prometheus and OpenMetrics formats.
+func exposedNames(name string, typ Type) []string {
+	switch typ {
+	case TypeCounter:
+		fam := familyName(name, typ)
+		return slices.Compact([]string{fam, fam + "_total", name})
+	case TypeHistogram:
+		return []string{name, name + "_bucket", name + "_sum", name + "_count"}
+	default:
+		return []string{name}
+	}
+}
+
+func validate(name string, typ Type, o *Options) error {
+	if !metricNameRE.MatchString(name) {
+		return fmt.Errorf("invalid metric name '%s'", name)
+	}
+	if o.Unit != "" {
+		if !unitRE.MatchString(o.Unit) {
+			return fmt.Errorf("invalid unit '%s' of metric '%s'", o.Unit, name)
+		}
+		if !strings.HasSuffix(familyName(name, typ), "_"+o.Unit) {
+			return fmt.Errorf("name of metric '%s' must end with its unit '%s'", name, o.Unit)
+		}
+	}
+	if typ != TypeHistogram {
+		return nil
+	}
+	if len(o.Buckets) == 0 {
+		o.Buckets = DefaultBuckets
+	}
+	o.Buckets = slices.Clone(o.Buckets)
+	if math.IsInf(o.Buckets[len(o.Buckets)-1], 1) {
+		o.Buckets = o.Buckets[:len(o.Buckets)-1]
+	}
+	for i, b := range o.Buckets {
+		if math.IsNaN(b) || (i > 0 && b <= o.Buckets[i-1]) {
+			return fmt.Errorf("buckets of metric '%s' must be in increasing order", name)
+		}
+	}
+	return nil
+}
+
+// familyName returns the OpenMetrics family name of a metric, which excludes
+// the "_total" suffix of counters.
+func familyName(name string, typ Type) string {
+	if typ == TypeCounter {
+		return strings.TrimSuffix(name, "_total")
+	}
+	return name
+}
+
+type family struct {
+	name    string
+	help    string
+	unit    string
+	typ     Type
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labels Labels
+	value  float64
+	counts []uint64 // non-cumulative, one per bucket plus +Inf
+	sum    float64
+	count  uint64
+}
+
+// get returns the series with the given labels, creating it if necessary. It
+// must be called with f.mu held.
+func (f *family) get(labels Labels) *series {
+	key := seriesKey(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: labels}
+		if f.typ == TypeHistogram {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) add(labels Labels, v float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.get(labels).value += v
+}
+
+func (f *family) set(labels Labels, v float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.get(labels).value = v
+}
+
+func (f *family) observe(labels Labels, v float64) {
+	i := sort.SearchFloat64s(f.buckets, v)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.get(labels)
+	s.counts[i]++
+	s.sum += v
+	s.count++
+}
+
+func (f *family) snapshot() Family {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := Family{Name: f.name, Help: f.help, Unit: f.unit, Type: f.typ}
+	keys := slices.Sorted(maps.Keys(f.series))
+	for _, k := range keys {
+		s := f.series[k]
+		ss := Series{Labels: maps.Clone(s.labels), Value: s.value, Count: s.count, Sum: s.sum}
+		if f.typ == TypeHistogram {
+			var cum uint64
+			for i, c := range s.counts {
+				cum += c
+				ub := math.Inf(1)
+				if i < len(f.buckets) {
+					ub = f.buckets[i]
+				}
+				ss.Buckets = append(ss.Buckets, Bucket{UpperBound: ub, Count: cum})
+			}
+		}
+		out.Series = append(out.Series, ss)
+	}
+	return out
+}
+
+// seriesKey returns a key uniquely identifying labels.
+func seriesKey(labels Labels) string {
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(labels[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// merge returns the union of base and labels of a metric of type typ, with
+// label names sanitized. Reserved label names are dropped.
+func merge(typ Type, base, labels Labels) Labels {
+	out := make(Labels, len(base)+len(labels))
+	maps.Copy(out, base)
+	for k, v := range labels {
+		k = sanitizeLabelName(k)
+		if reservedLabelName(typ, k) {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// reservedLabelName reports whether a sanitized label name is reserved for a
+// metric of type typ: names beginning with "__" are reserved for internal use,
+// and "le" holds the upper bound of histogram buckets.
+func reservedLabelName(typ Type, name string) bool {
+	return strings.HasPrefix(name, "__") || (typ == TypeHistogram && name == "le")
+}
+
+func sanitizeLabelName(name string) string {
+	if name == "" {
+		return "_"
+	}
+	b := []byte(name)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+type counter struct {
+	f      *family
+	labels Labels
+}
+
+func (c counter) Inc() {
+	c.f.add(c.labels, 1)
+}
+
+func (c counter) Add(v float64) {
+	if v < 0 || math.IsNaN(v) {
+		return
+	}
+	c.f.add(c.labels, v)
+}
+
+func (c counter) With(labels Labels) Counter {
+	return counter{f: c.f, labels: merge(TypeCounter, c.labels, labels)}
+}
+
+type gauge struct {
+	f      *family
+	labels Labels
+}
+
+func (g gauge) Set(v float64) {
+	g.f.set(g.labels, v)
+}
+
+func (g gauge) Inc() {
+	g.f.add(g.labels, 1)
+}
+
+func (g gauge) Dec() {
+	g.f.add(g.labels, -1)
+}
+
+func (g gauge) Add(v float64) {
+	g.f.add(g.labels, v)
+}
+
+func (g gauge) With(labels Labels) Gauge {
+	return gauge{f: g.f, labels: merge(TypeGauge, g.labels, labels)}
+}
+
+type histogram struct {
+	f      *family
+	labels Labels
+}
+
+func (h histogram) Observe(v float64) {
+	h.f.observe(h.labels, v)
+}
+
+func (h histogram) ObserveDuration(start time.Time) {
+	h.f.observe(h.labels, time.Since(start).Seconds())
+}
+
+func (h histogram) With(labels Labels) Histogram {
+	return histogram{f: h.f, labels: merge(TypeHistogram, h.labels, labels)}
+}
@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestRegistryConflictingOptions(t *testing.T) {
+	r := NewRegistry()
+	if _, err := r.Histogram("latency_seconds", WithHelp("Latency."), WithUnit("seconds"), WithBuckets(0.1, 1)); err != nil {
+		t.Fatalf("Histogram() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{"no options", nil, false},
+		{"same options", []Option{WithHelp("Latency."), WithUnit("seconds"), WithBuckets(0.1, 1)}, false},
+		{"other help", []Option{WithHelp("Other.")}, true},
+		{"other unit", []Option{WithUnit("milliseconds")}, true},
+		{"invalid unit", []Option{WithUnit("sec onds")}, true},
+		{"other buckets", []Option{WithBuckets(0.5, 1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Histogram("latency_seconds", tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Histogram() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := r.Counter("latency_seconds"); err == nil {
+		t.Error("Counter() with the name of a histogram succeeded")
+	}
+}
+
+func TestRegistryCollisions(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  func(r *Registry) error
+		second func(r *Registry) error
+	}{
+		{
+			"histogram and counter count",
+			func(r *Registry) error { _, err := r.Histogram("x"); return err },
+			func(r *Registry) error { _, err := r.Counter("x_count"); return err },
+		},
+		{
+			"histogram and gauge bucket",
+			func(r *Registry) error { _, err := r.Histogram("x"); return err },
+			func(r *Registry) error { _, err := r.Gauge("x_bucket"); return err },
+		},
+		{
+			"gauge sum and histogram",
+			func(r *Registry) error { _, err := r.Gauge("x_sum"); return err },
+			func(r *Registry) error { _, err := r.Histogram("x"); return err },
+		},
+		{
+			"counter with and without total",
+			func(r *Registry) error { _, err := r.Counter("x_total"); return err },
+			func(r *Registry) error { _, err := r.Counter("x"); return err },
+		},
+		{
+			"counter total and gauge family",
+			func(r *Registry) error { _, err := r.Counter("x_total"); return err },
+			func(r *Registry) error { _, err := r.Gauge("x"); return err },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			if err := tt.first(r); err != nil {
+				t.Fatalf("first registration error = %v", err)
+			}
+			err := tt.second(r)
+			if err == nil || !strings.Contains(err.Error(), "collides") {
+				t.Errorf("second registration error = %v, want collision", err)
+			}
+		})
+	}
+}
+
+func TestRegistryExposition(t *testing.T) {
+	r := NewRegistry()
+	c, err := r.Counter("requests_total", WithHelp("Requests."))
+	if err != nil {
+		t.Fatalf("Counter() error = %v", err)
+	}
+	c.With(Labels{"code": "200"}).Inc()
+	h, err := r.Histogram("latency_seconds", WithUnit("seconds"), WithBuckets(1))
+	if err != nil {
+		t.Fatalf("Histogram() error = %v", err)
+	}
+	h.Observe(0.5)
+
+	var b strings.Builder
+	if err := r.WriteOpenMetrics(&b); err != nil {
+		t.Fatalf("WriteOpenMetrics() error = %v", err)
+	}
+	for _, want := range []string{
+		"# TYPE requests counter\n",
+		`requests_total{code="200"} 1` + "\n",
+		"# UNIT latency_seconds seconds\n",
+		`latency_seconds_bucket{le="1"} 1` + "\n",
+		`latency_seconds_bucket{le="+Inf"} 1` + "\n",
+		"latency_seconds_count 1\n",
+		"# EOF\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("WriteOpenMetrics() = %q, missing %q", b.String(), want)
+		}
+	}
+}
+
+func TestRegistryReservedLabels(t *testing.T) {
+	r := NewRegistry()
+	c, err := r.Counter("requests_total")
+	if err != nil {
+		t.Fatalf("Counter() error = %v", err)
+	}
+	c.With(Labels{"le": "1", "__name__": "other", "..x": "y", "code": "200"}).Inc()
+	h, err := r.Histogram("latency_seconds", WithBuckets(1))
+	if err != nil {
+		t.Fatalf("Histogram() error = %v", err)
+	}
+	h.With(Labels{"le": "0.5", "__meta": "x", "server": "a"}).Observe(2)
+
+	fams := r.Gather()
+	if len(fams) != 2 {
+		t.Fatalf("Gather() returned %d families, want 2", len(fams))
+	}
+	want := map[string]Labels{
+		"latency_seconds": {"server": "a"},
+		"requests_total":  {"le": "1", "code": "200"},
+	}
+	for _, f := range fams {
+		if got := f.Series[0].Labels; !maps.Equal(got, want[f.Name]) {
+			t.Errorf("labels of %s = %v, want %v", f.Name, got, want[f.Name])
+		}
+	}
+
+	var b strings.Builder
+	if err := r.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+	if want := `latency_seconds_bucket{server="a",le="1"} 0` + "\n"; !strings.Contains(b.String(), want) {
+		t.Errorf("WritePrometheus() = %q, missing %q", b.String(), want)
+	}
+}
@@ -4,18 +4,34 @@ import (
 	"context"
 
 	"github.com/strata-io/service-extension/app"
+	"github.com/strata-io/service-extension/audit"
 	"github.com/strata-io/service-extension/bundle"
 	"github.com/strata-io/service-extension/cache"
 	"github.com/strata-io/service-extension/http"
 	"github.com/strata-io/service-extension/idfabric"
+	"github.com/strata-io/service-extension/keys"
+	"github.com/strata-io/service-extension/lock"
 	"github.com/strata-io/service-extension/log"
+	"github.com/strata-io/service-extension/metrics"
 	"github.com/strata-io/service-extension/router"
 	"github.com/strata-io/service-extension/secret"
 	"github.com/strata-io/service-extension/session"
 	"github.com/strata-io/service-extension/tai"
+	"github.com/strata-io/service-extension/trace"
 	"github.com/strata-io/service-extension/weblogic"
 )
 
+// Orchestrator exposes the services of the Orchestrator to service extensions.
+//
+// The Orchestrator may provide further services through optional interfaces,
+// such as LockerProvider. Use a type assertion to find out whether a service is
+// available.
+//
+// Example:
+//
+//	if lp, ok := api.(orchestrator.LockerProvider); ok {
+//		locker, err := lp.Locker("provisioning")
+//	}
 type Orchestrator interface {
 	// Logger gets a logger.
 	Logger(opts ...log.Option) log.Logger
@@ -52,7 +68,8 @@ type Orchestrator interface {
 
 	// Context gets the context associated with the Service Extension in use.
 	// This is an experimental feature and may not be available in all Service Extensions.
-	// If context is unavailable, nil will be returned.
+	// If context is unavailable, nil will be returned. The context holds the span
+	// of the request being served, which is returned by trace.SpanFromContext.
 	Context() context.Context
 
 	// WithContext returns a shallow copy of an Orchestrator with the provided
@@ -70,3 +87,56 @@ type Orchestrator interface {
 	// HTTP provides utilities for making HTTP requests.
 	HTTP() http.HTTP
 }
+
+// LockerProvider is implemented by Orchestrators providing distributed locks.
+// Where it is not implemented, lock.NewCacheLocker can create locks in a cache
+// implementing cache.Atomic.
+type LockerProvider interface {
+	Orchestrator
+
+	// Locker returns a Locker providing distributed locks that are shared across
+	// all Orchestrators using the same cache namespace.
+	Locker(namespace string, opts ...cache.Constraint) (lock.Locker, error)
+}
+
+// KeyringProvider is implemented by Orchestrators providing keyrings. Where it
+// is not implemented, keys.New can create a keyring from a secret provider
+// implementing secret.Versioned.
+type KeyringProvider interface {
+	Orchestrator
+
+	// Keyring gets a keyring that encrypts and signs with the key held in the
+	// secret with the given name. An error is returned if a secret provider is
+	// not configured or the secret does not exist.
+	Keyring(name string) (keys.Keyring, error)
+}
+
+// AuditProvider is implemented by Orchestrators providing audit logging.
+type AuditProvider interface {
+	Orchestrator
+
+	// Audit gets the audit logger, which records authentication, authorization
+	// and other security-relevant events separately from diagnostic logs. An
+	// error is returned if audit logging is not configured.
+	Audit() (audit.Logger, error)
+}
+
+// MetricsProvider is implemented by Orchestrators exporting metrics created by
+// service extensions.
+type MetricsProvider interface {
+	Orchestrator
+
+	// Metrics gets a metrics provider. Metrics created by service extensions are
+	// exported alongside the metrics of the Orchestrator.
+	Metrics() metrics.Provider
+}
+
+// TracerProvider is implemented by Orchestrators exporting spans started by
+// service extensions.
+type TracerProvider interface {
+	Orchestrator
+
+	// Tracer gets a tracer that starts spans exported together with the spans of
+	// the Orchestrator.
+	Tracer() trace.Tracer
+}
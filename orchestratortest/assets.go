@@ -0,0 +1,39 @@
+package orchestratortest
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/strata-io/service-extension/bundle"
+)
+
+// Assets is a bundle.SEAssets backed by an fs.FS, such as an fstest.MapFS or an
+// os.DirFS pointing at a testdata directory.
+type Assets struct {
+	fsys fs.FS
+}
+
+var _ bundle.SEAssets = Assets{}
+
+// NewAssets creates Assets served from fsys. If fsys is nil, all methods return
+// an error as if no assets were bundled with the service extension.
+func NewAssets(fsys fs.FS) Assets {
+	return Assets{fsys: fsys}
+}
+
+// FS returns the filesystem containing the assets.
+func (a Assets) FS() (fs.FS, error) {
+	if a.fsys == nil {
+		return nil, errors.New("no assets bundled with service extension")
+	}
+	return a.fsys, nil
+}
+
+// ReadFile returns the contents of the named asset.
+func (a Assets) ReadFile(name string) ([]byte, error) {
+	fsys, err := a.FS()
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(fsys, name)
+}
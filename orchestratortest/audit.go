@@ -0,0 +1,82 @@
+package orchestratortest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/strata-io/service-extension/audit"
+)
+
+// AuditLog is an audit.Logger that captures all events in memory. Events are
+// hash-chained exactly as they would be by the Orchestrator, so they can be
+// checked with audit.Verify.
+//
+// Example:
+//
+//	auditLog := orchestratortest.NewAuditLog()
+//	api := orchestratortest.New(orchestratortest.WithAudit(auditLog))
+//	// ...
+//	events := auditLog.Events()
+//	if len(events) != 1 || events[0].Decision != audit.DecisionDeny {
+//		t.Fatalf("expected a single deny event, got %v", events)
+//	}
+type AuditLog struct {
+	// Now returns the time of recorded events. Defaults to time.Now. The AuditLog
+	// that New creates if none is configured uses the clock of the Orchestrator.
+	Now func() time.Time
+
+	logger audit.Logger
+	sink   *auditSink
+}
+
+// NewAuditLog creates an empty AuditLog.
+func NewAuditLog() *AuditLog {
+	a := &AuditLog{sink: &auditSink{}}
+	a.logger = audit.New([]audit.Sink{a.sink}, audit.WithClock(a.now))
+	return a
+}
+
+func (a *AuditLog) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// Record records an event.
+func (a *AuditLog) Record(ctx context.Context, e audit.Event) error {
+	return a.logger.Record(ctx, e)
+}
+
+// Events returns a copy of all events recorded so far, in order.
+func (a *AuditLog) Events() []audit.Event {
+	a.sink.mu.Lock()
+	defer a.sink.mu.Unlock()
+	return append([]audit.Event(nil), a.sink.events...)
+}
+
+// Reset discards all recorded events. The chain of subsequent events continues
+// from the last discarded event.
+func (a *AuditLog) Reset() {
+	a.sink.mu.Lock()
+	defer a.sink.mu.Unlock()
+	a.sink.events = nil
+}
+
+// auditSink is an audit.Sink holding events in memory.
+type auditSink struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (s *auditSink) Write(e audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *auditSink) Close() error {
+	return nil
+}
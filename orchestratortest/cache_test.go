@@ -0,0 +1,470 @@
+package orchestratortest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/strata-io/service-extension/cache"
+)
+
+func newTestCache(t *testing.T) (cache.Extended, *testClock) {
+	t.Helper()
+	clock := newTestClock()
+	c, err := New(WithClock(clock.Now)).Cache("test")
+	if err != nil {
+		t.Fatalf("Cache() error = %v", err)
+	}
+	return c.(cache.Extended), clock
+}
+
+func TestCacheDelete(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCache(t)
+	if err := c.SetBytes(ctx, "k", []byte("v")); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if ok, err := c.Exists(ctx, "k"); err != nil || ok {
+		t.Errorf("Exists() after Delete = %v, %v, want false", ok, err)
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Errorf("Delete() of missing key error = %v", err)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	c, clock := newTestCache(t)
+	if err := c.SetBytes(ctx, "k", nil, cache.WithTTL(time.Minute)); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	if err := c.SetBytes(ctx, "persistent", nil); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+
+	clock.Advance(20 * time.Second)
+	if ttl, err := c.TTL(ctx, "k"); err != nil || ttl != 40*time.Second {
+		t.Errorf("TTL() = %v, %v, want 40s", ttl, err)
+	}
+	if ttl, err := c.TTL(ctx, "persistent"); err != nil || ttl != 0 {
+		t.Errorf("TTL() without expiry = %v, %v, want 0", ttl, err)
+	}
+	if _, err := c.TTL(ctx, "missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("TTL() of missing key error = %v, want cache.ErrNotFound", err)
+	}
+
+	clock.Advance(40 * time.Second)
+	if _, err := c.GetBytes(ctx, "k"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("GetBytes() after expiry error = %v, want cache.ErrNotFound", err)
+	}
+	if _, err := c.TTL(ctx, "k"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("TTL() after expiry error = %v, want cache.ErrNotFound", err)
+	}
+}
+
+func TestCacheExpireAndTouch(t *testing.T) {
+	ctx := context.Background()
+	c, clock := newTestCache(t)
+	if err := c.SetBytes(ctx, "k", nil, cache.WithTTL(time.Minute)); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+
+	clock.Advance(30 * time.Second)
+	if err := c.Touch(ctx, "k"); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	if ttl, _ := c.TTL(ctx, "k"); ttl != time.Minute {
+		t.Errorf("TTL() after Touch = %v, want 1m", ttl)
+	}
+
+	if err := c.Expire(ctx, "k", time.Hour); err != nil {
+		t.Fatalf("Expire() error = %v", err)
+	}
+	if ttl, _ := c.TTL(ctx, "k"); ttl != time.Hour {
+		t.Errorf("TTL() after Expire = %v, want 1h", ttl)
+	}
+	// Touch resets the expiry to the TTL last configured for the key.
+	clock.Advance(time.Minute)
+	if err := c.Touch(ctx, "k"); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	if ttl, _ := c.TTL(ctx, "k"); ttl != time.Hour {
+		t.Errorf("TTL() after Expire and Touch = %v, want 1h", ttl)
+	}
+
+	if err := c.Expire(ctx, "k", 0); err != nil {
+		t.Fatalf("Expire() error = %v", err)
+	}
+	if ttl, _ := c.TTL(ctx, "k"); ttl != 0 {
+		t.Errorf("TTL() after Expire(0) = %v, want 0", ttl)
+	}
+
+	if err := c.Expire(ctx, "missing", time.Minute); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expire() of missing key error = %v, want cache.ErrNotFound", err)
+	}
+	if err := c.Touch(ctx, "missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Touch() of missing key error = %v, want cache.ErrNotFound", err)
+	}
+}
+
+func TestCacheMany(t *testing.T) {
+	ctx := context.Background()
+	c, clock := newTestCache(t)
+	values := map[string][]byte{"a": []byte("1"), "b": []byte("2"), "c": []byte("3")}
+	if err := c.SetMany(ctx, values, cache.WithTTL(time.Minute)); err != nil {
+		t.Fatalf("SetMany() error = %v", err)
+	}
+	got, err := c.GetMany(ctx, []string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatalf("GetMany() error = %v", err)
+	}
+	if len(got) != 2 || string(got["a"]) != "1" || string(got["b"]) != "2" {
+		t.Errorf("GetMany() = %q, want a and b", got)
+	}
+
+	if err := c.DeleteMany(ctx, []string{"a", "missing"}); err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+	if got, _ := c.GetMany(ctx, []string{"a", "b", "c"}); len(got) != 2 {
+		t.Errorf("GetMany() after DeleteMany = %q, want b and c", got)
+	}
+
+	clock.Advance(time.Minute)
+	if got, _ := c.GetMany(ctx, []string{"b", "c"}); len(got) != 0 {
+		t.Errorf("GetMany() after expiry = %q, want none", got)
+	}
+}
+
+func TestCacheSetIfAbsent(t *testing.T) {
+	ctx := context.Background()
+	c, clock := newTestCache(t)
+	if ok, err := c.SetIfAbsent(ctx, "k", []byte("1"), cache.WithTTL(time.Minute)); err != nil || !ok {
+		t.Fatalf("SetIfAbsent() = %v, %v, want true", ok, err)
+	}
+	if ok, err := c.SetIfAbsent(ctx, "k", []byte("2")); err != nil || ok {
+		t.Errorf("SetIfAbsent() of existing key = %v, %v, want false", ok, err)
+	}
+	if got, _ := c.GetBytes(ctx, "k"); string(got) != "1" {
+		t.Errorf("GetBytes() = %q, want %q", got, "1")
+	}
+
+	// An expired key counts as absent.
+	clock.Advance(time.Minute)
+	if ok, err := c.SetIfAbsent(ctx, "k", []byte("3")); err != nil || !ok {
+		t.Errorf("SetIfAbsent() of expired key = %v, %v, want true", ok, err)
+	}
+}
+
+func TestCacheCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCache(t)
+	if err := c.SetBytes(ctx, "k", []byte("1")); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	_, v1, err := c.GetWithVersion(ctx, "k")
+	if err != nil {
+		t.Fatalf("GetWithVersion() error = %v", err)
+	}
+	if ok, err := c.CompareAndSwap(ctx, "k", v1, []byte("2")); err != nil || !ok {
+		t.Fatalf("CompareAndSwap() = %v, %v, want true", ok, err)
+	}
+	if ok, err := c.CompareAndSwap(ctx, "k", v1, []byte("3")); err != nil || ok {
+		t.Errorf("CompareAndSwap() with stale version = %v, %v, want false", ok, err)
+	}
+	value, v2, err := c.GetWithVersion(ctx, "k")
+	if err != nil || string(value) != "2" || v2 == v1 {
+		t.Errorf("GetWithVersion() = %q, %q, %v, want %q with a new version", value, v2, err, "2")
+	}
+
+	// Versions are not reused after a key is deleted and written again.
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := c.SetBytes(ctx, "k", []byte("4")); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	if _, v3, _ := c.GetWithVersion(ctx, "k"); v3 == v1 || v3 == v2 {
+		t.Errorf("GetWithVersion() version = %q, reused an earlier version", v3)
+	}
+	if _, _, err := c.GetWithVersion(ctx, "missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("GetWithVersion() of missing key error = %v, want cache.ErrNotFound", err)
+	}
+}
+
+func TestCacheIncrement(t *testing.T) {
+	ctx := context.Background()
+	c, clock := newTestCache(t)
+	if n, err := c.Increment(ctx, "n", 5, cache.WithTTL(time.Minute)); err != nil || n != 5 {
+		t.Fatalf("Increment() = %d, %v, want 5", n, err)
+	}
+	// Options are ignored for existing keys.
+	if n, err := c.Increment(ctx, "n", 2, cache.WithTTL(time.Hour)); err != nil || n != 7 {
+		t.Errorf("Increment() = %d, %v, want 7", n, err)
+	}
+	if ttl, _ := c.TTL(ctx, "n"); ttl != time.Minute {
+		t.Errorf("TTL() = %v, want 1m", ttl)
+	}
+	if n, err := c.Decrement(ctx, "n", 10); err != nil || n != -3 {
+		t.Errorf("Decrement() = %d, %v, want -3", n, err)
+	}
+	if got, _ := c.GetBytes(ctx, "n"); string(got) != "-3" {
+		t.Errorf("GetBytes() = %q, want %q", got, "-3")
+	}
+
+	clock.Advance(time.Minute)
+	if n, err := c.Increment(ctx, "n", 1); err != nil || n != 1 {
+		t.Errorf("Increment() of expired key = %d, %v, want 1", n, err)
+	}
+
+	if err := c.SetBytes(ctx, "s", []byte("abc")); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	if _, err := c.Increment(ctx, "s", 1); !errors.Is(err, cache.ErrNotInteger) {
+		t.Errorf("Increment() of non-integer error = %v, want cache.ErrNotInteger", err)
+	}
+}
+
+func TestCacheIncrementOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial int64
+		op      func(c cache.Extended, ctx context.Context) (int64, error)
+		want    int64
+		wantErr bool
+	}{
+		{"increment", math.MaxInt64 - 1, func(c cache.Extended, ctx context.Context) (int64, error) {
+			return c.Increment(ctx, "n", 1)
+		}, math.MaxInt64, false},
+		{"increment overflow", math.MaxInt64, func(c cache.Extended, ctx context.Context) (int64, error) {
+			return c.Increment(ctx, "n", 1)
+		}, 0, true},
+		{"increment underflow", math.MinInt64, func(c cache.Extended, ctx context.Context) (int64, error) {
+			return c.Increment(ctx, "n", -1)
+		}, 0, true},
+		{"decrement underflow", math.MinInt64, func(c cache.Extended, ctx context.Context) (int64, error) {
+			return c.Decrement(ctx, "n", 1)
+		}, 0, true},
+		{"decrement overflow", 0, func(c cache.Extended, ctx context.Context) (int64, error) {
+			return c.Decrement(ctx, "n", math.MinInt64)
+		}, 0, true},
+		{"decrement min", -1, func(c cache.Extended, ctx context.Context) (int64, error) {
+			return c.Decrement(ctx, "n", math.MinInt64)
+		}, math.MaxInt64, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c, _ := newTestCache(t)
+			if err := c.SetBytes(ctx, "n", []byte(fmt.Sprint(tt.initial))); err != nil {
+				t.Fatalf("SetBytes() error = %v", err)
+			}
+			n, err := tt.op(c, ctx)
+			if tt.wantErr {
+				if !errors.Is(err, cache.ErrOverflow) {
+					t.Errorf("error = %v, want cache.ErrOverflow", err)
+				}
+				if got, _ := c.GetBytes(ctx, "n"); string(got) != fmt.Sprint(tt.initial) {
+					t.Errorf("GetBytes() = %q, want the counter unchanged", got)
+				}
+				return
+			}
+			if err != nil || n != tt.want {
+				t.Errorf("got %d, %v, want %d", n, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheScan(t *testing.T) {
+	ctx := context.Background()
+	c, clock := newTestCache(t)
+	for _, key := range []string{"", "a", "user:1", "user:2", "user:3", "user:4", "user:5", "z"} {
+		if err := c.SetBytes(ctx, key, nil); err != nil {
+			t.Fatalf("SetBytes(%q) error = %v", key, err)
+		}
+	}
+	if err := c.SetBytes(ctx, "user:0", nil, cache.WithTTL(time.Minute)); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	clock.Advance(time.Minute)
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"user:", []string{"user:1", "user:2", "user:3", "user:4", "user:5"}},
+		{"", []string{"", "a", "user:1", "user:2", "user:3", "user:4", "user:5", "z"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		for _, count := range []int{1, 2, 100} {
+			t.Run(fmt.Sprintf("%q/%d", tt.prefix, count), func(t *testing.T) {
+				var got []string
+				cursor := ""
+				for i := 0; ; i++ {
+					if i > len(tt.want)+1 {
+						t.Fatal("scan did not complete")
+					}
+					keys, next, err := c.Scan(ctx, tt.prefix, cursor, count)
+					if err != nil {
+						t.Fatalf("Scan() error = %v", err)
+					}
+					if len(keys) > count {
+						t.Fatalf("Scan() returned %d keys, want at most %d", len(keys), count)
+					}
+					got = append(got, keys...)
+					if next == "" {
+						break
+					}
+					cursor = next
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("scanned keys = %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+
+	if _, _, err := c.Scan(ctx, "", "", 0); err == nil {
+		t.Error("Scan() with count 0 error = nil")
+	}
+}
+
+func TestCacheKeys(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCache(t)
+	var want []string
+	for i := range 250 {
+		key := fmt.Sprintf("k%04d", i)
+		want = append(want, key)
+		if err := c.SetBytes(ctx, key, nil); err != nil {
+			t.Fatalf("SetBytes() error = %v", err)
+		}
+	}
+	var got []string
+	for key, err := range cache.Keys(ctx, c, "k") {
+		if err != nil {
+			t.Fatalf("cache.Keys() error = %v", err)
+		}
+		got = append(got, key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("cache.Keys() returned %d keys, want %d", len(got), len(want))
+	}
+}
+
+func TestCacheLenAndFlush(t *testing.T) {
+	ctx := context.Background()
+	c, clock := newTestCache(t)
+	if err := c.SetMany(ctx, map[string][]byte{"a": nil, "b": nil}); err != nil {
+		t.Fatalf("SetMany() error = %v", err)
+	}
+	if err := c.SetBytes(ctx, "c", nil, cache.WithTTL(time.Minute)); err != nil {
+		t.Fatalf("SetBytes() error = %v", err)
+	}
+	if n, err := c.Len(ctx); err != nil || n != 3 {
+		t.Errorf("Len() = %d, %v, want 3", n, err)
+	}
+
+	// Len may include expired keys until they are accessed.
+	clock.Advance(time.Minute)
+	if _, err := c.GetBytes(ctx, "c"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("GetBytes() error = %v, want cache.ErrNotFound", err)
+	}
+	if n, err := c.Len(ctx); err != nil || n != 2 {
+		t.Errorf("Len() after expiry = %d, %v, want 2", n, err)
+	}
+
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if n, err := c.Len(ctx); err != nil || n != 0 {
+		t.Errorf("Len() after Flush = %d, %v, want 0", n, err)
+	}
+	if keys, _, err := c.Scan(ctx, "", "", 10); err != nil || len(keys) != 0 {
+		t.Errorf("Scan() after Flush = %q, %v, want none", keys, err)
+	}
+}
+
+func TestCachePublishSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := newTestCache(t)
+	messages, err := c.Subscribe(ctx, "topic")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	other, err := c.Subscribe(ctx, "other")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	payload := []byte("hello")
+	if err := c.Publish(ctx, "topic", payload); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	payload[0] = 'j'
+	msg := <-messages
+	if msg.Topic != "topic" || string(msg.Payload) != "hello" {
+		t.Errorf("message = %+v, want topic %q with payload %q", msg, "topic", "hello")
+	}
+	select {
+	case msg := <-other:
+		t.Errorf("subscriber of other topic received %+v", msg)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-messages; ok {
+		t.Error("subscription channel not closed after the context was canceled")
+	}
+	if _, err := c.Subscribe(ctx, "topic"); err == nil {
+		t.Error("Subscribe() with canceled context error = nil")
+	}
+}
+
+func TestCacheWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, clock := newTestCache(t)
+	events, err := c.Watch(ctx, "user:")
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	_ = c.SetBytes(ctx, "user:1", nil, cache.WithTTL(time.Minute))
+	_ = c.SetBytes(ctx, "other", nil)
+	_ = c.SetBytes(ctx, "user:2", nil)
+	_ = c.Delete(ctx, "user:2")
+	clock.Advance(time.Minute)
+	_, _ = c.GetBytes(ctx, "user:1")
+	_ = c.Flush(ctx)
+
+	want := []cache.Event{
+		{Type: cache.EventSet, Key: "user:1"},
+		{Type: cache.EventSet, Key: "user:2"},
+		{Type: cache.EventDelete, Key: "user:2"},
+		{Type: cache.EventExpire, Key: "user:1"},
+		{Type: cache.EventFlush},
+	}
+	for _, w := range want {
+		if got := <-events; got != w {
+			t.Errorf("event = %+v, want %+v", got, w)
+		}
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("watch channel not closed after the context was canceled")
+	}
+}
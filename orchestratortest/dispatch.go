@@ -0,0 +1,48 @@
+package orchestratortest
+
+import "sync"
+
+// dispatcher calls queued functions one at a time, in the order they were
+// queued. Functions queued while another goroutine is running the dispatcher,
+// including functions queued by a function being called, are called by that
+// goroutine, so run neither blocks on nor deadlocks with a running dispatcher.
+type dispatcher struct {
+	mu      sync.Mutex
+	queue   []func()
+	running bool
+}
+
+// enqueue queues fns. Callers that must preserve an order across goroutines
+// call enqueue while holding the lock that establishes that order, and run
+// after releasing it.
+func (d *dispatcher) enqueue(fns ...func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queue = append(d.queue, fns...)
+}
+
+// run calls the queued functions unless another goroutine is already doing so.
+func (d *dispatcher) run() {
+	d.mu.Lock()
+	if d.running {
+		d.mu.Unlock()
+		return
+	}
+	d.running = true
+	defer func() {
+		d.running = false
+		d.mu.Unlock()
+	}()
+	for len(d.queue) > 0 {
+		fn := d.queue[0]
+		d.queue[0] = nil
+		d.queue = d.queue[1:]
+		d.mu.Unlock()
+		func() {
+			// Reacquire the lock even if fn panics, so that the dispatcher can
+			// be run again.
+			defer d.mu.Lock()
+			fn()
+		}()
+	}
+}
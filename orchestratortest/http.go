@@ -0,0 +1,79 @@
+package orchestratortest
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	shttp "github.com/strata-io/service-extension/http"
+	"github.com/strata-io/service-extension/trace"
+)
+
+// HTTP is an in-memory http.HTTP client store.
+type HTTP struct {
+	// Tracer is used to instrument the clients returned by GetClient and
+	// DefaultClient. If nil, clients are returned unchanged. The HTTP that New
+	// creates if none is configured uses the tracer of the Orchestrator.
+	Tracer trace.Tracer
+
+	mu            sync.RWMutex
+	clients       map[string]*http.Client
+	defaultClient *http.Client
+}
+
+var _ shttp.HTTP = (*HTTP)(nil)
+
+// NewHTTP creates an HTTP client store whose default client is
+// http.DefaultClient.
+func NewHTTP() *HTTP {
+	return &HTTP{
+		clients:       make(map[string]*http.Client),
+		defaultClient: http.DefaultClient,
+	}
+}
+
+// GetClient returns the HTTP client based on the provided name. If the client
+// does not exist, an error will be returned.
+func (h *HTTP) GetClient(name string) (*http.Client, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	c, ok := h.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("http client '%s' not found", name)
+	}
+	return h.instrument(c), nil
+}
+
+// SetClient adds a client to the HTTP client store based on the provided name.
+func (h *HTTP) SetClient(name string, client *http.Client) error {
+	if client == nil {
+		return fmt.Errorf("http client '%s' must not be nil", name)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[name] = client
+	return nil
+}
+
+// DefaultClient returns the default HTTP client.
+func (h *HTTP) DefaultClient() *http.Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.instrument(h.defaultClient)
+}
+
+// SetDefaultClient replaces the default HTTP client, e.g. with the client of an
+// httptest.Server.
+func (h *HTTP) SetDefaultClient(client *http.Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.defaultClient = client
+}
+
+// instrument returns c instrumented with the configured tracer, if any.
+func (h *HTTP) instrument(c *http.Client) *http.Client {
+	if h.Tracer == nil {
+		return c
+	}
+	return trace.InstrumentClient(h.Tracer, c)
+}
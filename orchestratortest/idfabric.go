@@ -0,0 +1,116 @@
+package orchestratortest
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/strata-io/service-extension/idfabric"
+)
+
+// IdentityProvider is an in-memory idfabric.IdentityProvider. Every call to
+// Login is recorded and can be inspected with Logins.
+//
+// Example:
+//
+//	idp := &orchestratortest.IdentityProvider{
+//		LoginFunc: func(rw http.ResponseWriter, req *http.Request, opts idfabric.LoginOptions) {
+//			opts.LoginResult.AccessToken = "token"
+//		},
+//	}
+//	api := orchestratortest.New(orchestratortest.WithIdentityProvider("azure", idp))
+type IdentityProvider struct {
+	// LoginFunc, if set, is called by Login with the resolved login options.
+	// If LoginFunc is nil, Login redirects to the configured redirect URL, if
+	// any, and otherwise does nothing.
+	LoginFunc func(rw http.ResponseWriter, req *http.Request, opts idfabric.LoginOptions)
+
+	// Unavailable causes IsAvailable to report the IDP as unhealthy.
+	Unavailable bool
+
+	mu     sync.Mutex
+	logins []idfabric.LoginOptions
+}
+
+var _ idfabric.IdentityProvider = (*IdentityProvider)(nil)
+
+// Login records the login attempt and invokes LoginFunc.
+func (i *IdentityProvider) Login(rw http.ResponseWriter, req *http.Request, opts ...idfabric.LoginOpt) {
+	cfg := idfabric.LoginOptions{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	i.mu.Lock()
+	i.logins = append(i.logins, cfg)
+	i.mu.Unlock()
+
+	if i.LoginFunc != nil {
+		i.LoginFunc(rw, req, cfg)
+		return
+	}
+	if cfg.RedirectURL != "" && rw != nil {
+		http.Redirect(rw, req, cfg.RedirectURL, http.StatusFound)
+	}
+}
+
+// IsAvailable reports whether the IDP is healthy.
+func (i *IdentityProvider) IsAvailable() bool {
+	return !i.Unavailable
+}
+
+// Logins returns the options of every recorded login attempt.
+func (i *IdentityProvider) Logins() []idfabric.LoginOptions {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]idfabric.LoginOptions(nil), i.logins...)
+}
+
+// AttributeProvider is an in-memory idfabric.AttributeProvider serving
+// attributes keyed by subject.
+type AttributeProvider struct {
+	mu         sync.RWMutex
+	attributes map[string]map[string]string
+}
+
+var _ idfabric.AttributeProvider = (*AttributeProvider)(nil)
+
+// NewAttributeProvider creates an AttributeProvider serving the given
+// attributes, keyed by subject and then by attribute name.
+func NewAttributeProvider(attributes map[string]map[string]string) *AttributeProvider {
+	p := &AttributeProvider{attributes: make(map[string]map[string]string, len(attributes))}
+	for subject, attrs := range attributes {
+		p.SetAttributes(subject, attrs)
+	}
+	return p
+}
+
+// Query returns the requested attributes for the subject. Attributes that are
+// not known for the subject are omitted. An error is returned if the subject
+// is unknown.
+func (p *AttributeProvider) Query(subject string, attributes []string) (map[string]string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	attrs, ok := p.attributes[subject]
+	if !ok {
+		return nil, fmt.Errorf("subject '%s' not found", subject)
+	}
+	result := make(map[string]string, len(attributes))
+	for _, name := range attributes {
+		if v, ok := attrs[name]; ok {
+			result[name] = v
+		}
+	}
+	return result, nil
+}
+
+// SetAttributes replaces the attributes of the given subject.
+func (p *AttributeProvider) SetAttributes(subject string, attributes map[string]string) {
+	cp := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		cp[k] = v
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attributes[subject] = cp
+}
@@ -0,0 +1,85 @@
+package orchestratortest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/strata-io/service-extension/tai"
+	"github.com/strata-io/service-extension/weblogic"
+)
+
+// TAI is a tai.Provider that signs RS256 JWTs locally.
+type TAI struct {
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+var _ tai.Provider = TAI{}
+
+// NewSignedJWT returns a JWT signed with cfg.RSAPrivateKeyPEM.
+func (t TAI) NewSignedJWT(cfg tai.Config) (string, error) {
+	return signJWT(t.Now, cfg.RSAPrivateKeyPEM, cfg.Subject, cfg.Lifetime)
+}
+
+// WebLogic is a weblogic.Provider that signs RS256 JWTs locally.
+type WebLogic struct {
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+var _ weblogic.Provider = WebLogic{}
+
+// NewSignedJWT returns a JWT signed with cfg.RSAPrivateKeyPEM.
+func (w WebLogic) NewSignedJWT(cfg weblogic.Config) (string, error) {
+	return signJWT(w.Now, cfg.RSAPrivateKeyPEM, cfg.Subject, cfg.Lifetime)
+}
+
+// signJWT creates an RS256 JWT carrying the 'sub', 'iat' and 'exp' claims.
+func signJWT(now func() time.Time, keyPEM, subject string, lifetime time.Duration) (string, error) {
+	if now == nil {
+		now = time.Now
+	}
+	if subject == "" {
+		return "", errors.New("subject must not be empty")
+	}
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return "", errors.New("unable to decode RSA private key PEM")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse RSA private key: %w", err)
+	}
+
+	iat := now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"sub": subject,
+		"iat": iat.Unix(),
+		"exp": iat.Add(lifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to sign JWT: %w", err)
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,158 @@
+package orchestratortest
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/strata-io/service-extension/log"
+)
+
+// LogRecord is a single log entry captured by Logger.
+type LogRecord struct {
+	// Time is the time the record was logged.
+	Time time.Time
+
+	// Level is the level the record was logged at, e.g. "debug", "info", "warn"
+	// or "error".
+	Level string
+
+	// KeyPairs are the key-value pairs passed to the logger, preceded by the
+	// key-value pairs of the loggers it was derived from with With. Sensitive
+	// values are redacted with log.Redact, exactly as they would be by the
+	// Orchestrator.
+	KeyPairs []any
+
+	// Err reports malformed key-value pairs as returned by log.Validate. It is nil
+	// if the key-value pairs are well-formed.
+	Err error
+
+	// Request is the request passed to Orchestrator.Logger with log.WithRequest
+	// to retrieve the logger, if any.
+	Request *http.Request
+}
+
+// Value returns the value associated with the given key in the record's
+// key-value pairs, including keys of slog.Attr elements. The second return
+// value reports whether the key was found.
+func (r LogRecord) Value(key string) (any, bool) {
+	for i := 0; i < len(r.KeyPairs); i++ {
+		switch k := r.KeyPairs[i].(type) {
+		case slog.Attr:
+			if k.Key == key {
+				return k.Value.Any(), true
+			}
+		case string:
+			if k == key && i+1 < len(r.KeyPairs) {
+				return r.KeyPairs[i+1], true
+			}
+			i++
+		}
+	}
+	return nil, false
+}
+
+// Logger is a log.Extended that captures all records in memory. Loggers derived
+// with With share the records of the Logger they were derived from.
+type Logger struct {
+	sink *logSink
+	with []any
+	req  *http.Request
+}
+
+// logSink holds the records of a Logger and the loggers derived from it.
+type logSink struct {
+	mu      sync.Mutex
+	level   log.Level
+	records []LogRecord
+}
+
+var _ log.Extended = (*Logger)(nil)
+
+// NewLogger creates a Logger that captures all records in memory.
+func NewLogger() *Logger {
+	return &Logger{sink: &logSink{level: log.LevelDebug}}
+}
+
+// Debug captures a record at debug level.
+func (l *Logger) Debug(keyPairs ...any) {
+	l.record(log.LevelDebug, keyPairs)
+}
+
+// Info captures a record at info level.
+func (l *Logger) Info(keyPairs ...any) {
+	l.record(log.LevelInfo, keyPairs)
+}
+
+// Warn captures a record at warn level.
+func (l *Logger) Warn(keyPairs ...any) {
+	l.record(log.LevelWarn, keyPairs)
+}
+
+// Error captures a record at error level.
+func (l *Logger) Error(keyPairs ...any) {
+	l.record(log.LevelError, keyPairs)
+}
+
+// With returns a Logger that prepends the given key-value pairs to every
+// record it captures.
+func (l *Logger) With(keyPairs ...any) log.Extended {
+	with := make([]any, 0, len(l.with)+len(keyPairs))
+	with = append(with, l.with...)
+	return &Logger{sink: l.sink, with: append(with, keyPairs...), req: l.req}
+}
+
+// forRequest returns a Logger that records req in every record it captures.
+func (l *Logger) forRequest(req *http.Request) *Logger {
+	return &Logger{sink: l.sink, with: l.with, req: req}
+}
+
+// Enabled reports whether records at the given level are captured.
+func (l *Logger) Enabled(level log.Level) bool {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	return level >= l.sink.level
+}
+
+// SetLevel configures the minimum level of captured records. Defaults to
+// log.LevelDebug.
+func (l *Logger) SetLevel(level log.Level) {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	l.sink.level = level
+}
+
+// Records returns a copy of all captured records in the order they were
+// logged.
+func (l *Logger) Records() []LogRecord {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	return append([]LogRecord(nil), l.sink.records...)
+}
+
+// Reset discards all captured records.
+func (l *Logger) Reset() {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	l.sink.records = nil
+}
+
+func (l *Logger) record(level log.Level, keyPairs []any) {
+	all := make([]any, 0, len(l.with)+len(keyPairs))
+	all = append(all, l.with...)
+	all = append(all, keyPairs...)
+
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	if level < l.sink.level {
+		return
+	}
+	l.sink.records = append(l.sink.records, LogRecord{
+		Time:     time.Now(),
+		Level:    level.String(),
+		KeyPairs: log.Redact(all...),
+		Err:      log.Validate(all...),
+		Request:  l.req,
+	})
+}
@@ -0,0 +1,29 @@
+package orchestratortest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/strata-io/service-extension/log"
+)
+
+func TestLoggerWithRequest(t *testing.T) {
+	logger := NewLogger()
+	api := New(WithLogger(logger))
+	req := httptest.NewRequest(http.MethodGet, "/callback", nil)
+
+	api.Logger().Info("msg", "without request")
+	api.Logger(log.WithRequest(req)).Info("msg", "with request")
+
+	records := logger.Records()
+	if len(records) != 2 {
+		t.Fatalf("captured %d records, want 2", len(records))
+	}
+	if records[0].Request != nil {
+		t.Error("record of the default logger references a request")
+	}
+	if records[1].Request != req {
+		t.Error("record of the request logger does not reference the request")
+	}
+}
@@ -0,0 +1,56 @@
+package orchestratortest
+
+import (
+	"maps"
+
+	"github.com/strata-io/service-extension/metrics"
+)
+
+// MetricsRecorder is a metrics.Provider that records metrics in memory, so that
+// tests can assert on the metrics emitted by a service extension.
+//
+// Example:
+//
+//	recorder := orchestratortest.NewMetricsRecorder()
+//	api := orchestratortest.New(orchestratortest.WithMetrics(recorder))
+//	// ...
+//	v, _ := recorder.Value("idp_selection_failures_total", metrics.Labels{"reason": "unknown_idp"})
+//	if v != 1 {
+//		t.Fatalf("expected 1 selection failure, got %v", v)
+//	}
+type MetricsRecorder struct {
+	*metrics.Registry
+}
+
+// NewMetricsRecorder creates an empty MetricsRecorder.
+func NewMetricsRecorder() *MetricsRecorder {
+	return &MetricsRecorder{Registry: metrics.NewRegistry()}
+}
+
+// Series returns the series of the metric with the given name whose labels
+// are exactly labels. The second return value reports whether the series
+// exists.
+func (r *MetricsRecorder) Series(name string, labels metrics.Labels) (metrics.Series, bool) {
+	for _, f := range r.Gather() {
+		if f.Name != name {
+			continue
+		}
+		for _, s := range f.Series {
+			if maps.Equal(s.Labels, labels) {
+				return s, true
+			}
+		}
+	}
+	return metrics.Series{}, false
+}
+
+// Value returns the value of the counter or gauge series with the given name
+// and labels, or the number of observations of a histogram series. The second
+// return value reports whether the series exists.
+func (r *MetricsRecorder) Value(name string, labels metrics.Labels) (float64, bool) {
+	s, ok := r.Series(name, labels)
+	if s.Buckets != nil {
+		return float64(s.Count), ok
+	}
+	return s.Value, ok
+}
@@ -0,0 +1,537 @@
+// Package orchestratortest provides an in-memory implementation of
+// orchestrator.Orchestrator for unit testing service extensions without a
+// running Maverics Orchestrator.
+//
+// Example:
+//
+//	logger := orchestratortest.NewLogger()
+//	api := orchestratortest.New(
+//		orchestratortest.WithLogger(logger),
+//		orchestratortest.WithMetadata(map[string]any{"idps": "azure,auth0"}),
+//		orchestratortest.WithSessionValues(map[string]any{"azure.authenticated": "true"}),
+//	)
+//
+//	if !IsAuthenticated(api, httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)) {
+//		t.Fatal("expected user to be authenticated")
+//	}
+//	for _, r := range logger.Records() {
+//		t.Log(r.Level, r.KeyPairs)
+//	}
+package orchestratortest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/strata-io/service-extension/app"
+	"github.com/strata-io/service-extension/audit"
+	"github.com/strata-io/service-extension/bundle"
+	"github.com/strata-io/service-extension/cache"
+	shttp "github.com/strata-io/service-extension/http"
+	"github.com/strata-io/service-extension/idfabric"
+	"github.com/strata-io/service-extension/keys"
+	"github.com/strata-io/service-extension/lock"
+	"github.com/strata-io/service-extension/log"
+	"github.com/strata-io/service-extension/metrics"
+	"github.com/strata-io/service-extension/orchestrator"
+	"github.com/strata-io/service-extension/router"
+	"github.com/strata-io/service-extension/secret"
+	"github.com/strata-io/service-extension/session"
+	"github.com/strata-io/service-extension/tai"
+	"github.com/strata-io/service-extension/trace"
+	"github.com/strata-io/service-extension/weblogic"
+)
+
+// Options are used to configure an Orchestrator created by New.
+type Options struct {
+	Logger             log.Logger
+	Audit              audit.Logger
+	Metrics            metrics.Provider
+	Tracer             trace.Tracer
+	SecretProvider     secret.Provider
+	Secrets            map[string]any
+	IdentityProviders  map[string]idfabric.IdentityProvider
+	AttributeProviders map[string]idfabric.AttributeProvider
+	Metadata           map[string]any
+	Router             router.Router
+	App                app.App
+	TAI                tai.Provider
+	WebLogic           weblogic.Provider
+	Context            context.Context
+	Assets             bundle.SEAssets
+	HTTP               shttp.HTTP
+	SessionValues      map[string]any
+
+	// SessionLifetime is the absolute lifetime of new sessions. SessionIdleTimeout
+	// is the duration of inactivity after which sessions expire. Zero values
+	// disable the respective expiry.
+	SessionLifetime    time.Duration
+	SessionIdleTimeout time.Duration
+
+	// Now returns the current time. It is used to evaluate cache TTLs, session
+	// expiry and token lifetimes, and by the fakes New creates for dependencies
+	// that are not configured, such as the SecretProvider serving the secrets
+	// passed to WithSecrets. Fakes passed to New are used as is. Defaults to
+	// time.Now.
+	Now func() time.Time
+}
+
+// Option is an option used to configure an Orchestrator created by New.
+//
+// Example:
+//
+//	api := orchestratortest.New(orchestratortest.WithSecrets(map[string]any{
+//		"serviceAccountPassword": "password",
+//	}))
+type Option func(*Options)
+
+// WithLogger configures the logger returned by Orchestrator.Logger. By default a
+// Logger that captures all records is used.
+func WithLogger(l log.Logger) Option {
+	return func(o *Options) {
+		o.Logger = l
+	}
+}
+
+// WithAudit configures the audit logger returned by Orchestrator.Audit. By
+// default an AuditLog that captures all events is used.
+func WithAudit(l audit.Logger) Option {
+	return func(o *Options) {
+		o.Audit = l
+	}
+}
+
+// WithMetrics configures the metrics provider returned by Orchestrator.Metrics.
+// By default a MetricsRecorder is used.
+func WithMetrics(p metrics.Provider) Option {
+	return func(o *Options) {
+		o.Metrics = p
+	}
+}
+
+// WithTracer configures the tracer returned by Orchestrator.Tracer, which is
+// also used to instrument the clients of the default HTTP utilities. By default
+// a tracer without exporters is used.
+//
+// Example:
+//
+//	exporter := trace.NewInMemoryExporter()
+//	api := orchestratortest.New(orchestratortest.WithTracer(
+//		trace.NewTracer(trace.WithExporter(exporter)),
+//	))
+func WithTracer(t trace.Tracer) Option {
+	return func(o *Options) {
+		o.Tracer = t
+	}
+}
+
+// WithSecretProvider configures the secret provider returned by
+// Orchestrator.SecretProvider. It replaces any secrets configured with
+// WithSecrets.
+func WithSecretProvider(p secret.Provider) Option {
+	return func(o *Options) {
+		o.SecretProvider = p
+		o.Secrets = nil
+	}
+}
+
+// WithSecrets configures an in-memory SecretProvider serving the given secrets,
+// whose versions are created with the clock of the Orchestrator. It replaces
+// any secret provider configured with WithSecretProvider. If no secret provider
+// is configured, Orchestrator.SecretProvider returns an error.
+func WithSecrets(secrets map[string]any) Option {
+	return func(o *Options) {
+		o.Secrets = secrets
+		o.SecretProvider = nil
+	}
+}
+
+// WithIdentityProvider registers an identity provider under the given name.
+func WithIdentityProvider(name string, idp idfabric.IdentityProvider) Option {
+	return func(o *Options) {
+		if o.IdentityProviders == nil {
+			o.IdentityProviders = make(map[string]idfabric.IdentityProvider)
+		}
+		o.IdentityProviders[name] = idp
+	}
+}
+
+// WithAttributeProvider registers an attribute provider under the given name.
+func WithAttributeProvider(name string, ap idfabric.AttributeProvider) Option {
+	return func(o *Options) {
+		if o.AttributeProviders == nil {
+			o.AttributeProviders = make(map[string]idfabric.AttributeProvider)
+		}
+		o.AttributeProviders[name] = ap
+	}
+}
+
+// WithMetadata configures the metadata returned by Orchestrator.Metadata.
+func WithMetadata(metadata map[string]any) Option {
+	return func(o *Options) {
+		o.Metadata = metadata
+	}
+}
+
+// WithRouter configures the router returned by Orchestrator.Router. By default a
+// Router backed by http.ServeMux is used.
+func WithRouter(r router.Router) Option {
+	return func(o *Options) {
+		o.Router = r
+	}
+}
+
+// WithApp configures an app with the given name. If no app is configured,
+// Orchestrator.App returns an error.
+func WithApp(name string) Option {
+	return func(o *Options) {
+		o.App = App{AppName: name}
+	}
+}
+
+// WithTAI configures the TAI provider returned by Orchestrator.TAI.
+func WithTAI(p tai.Provider) Option {
+	return func(o *Options) {
+		o.TAI = p
+	}
+}
+
+// WithWebLogic configures the WebLogic provider returned by
+// Orchestrator.WebLogic.
+func WithWebLogic(p weblogic.Provider) Option {
+	return func(o *Options) {
+		o.WebLogic = p
+	}
+}
+
+// WithContext configures the context returned by Orchestrator.Context. Defaults
+// to context.Background.
+func WithContext(ctx context.Context) Option {
+	return func(o *Options) {
+		o.Context = ctx
+	}
+}
+
+// WithAssets configures the service extension assets served from fsys.
+func WithAssets(fsys fs.FS) Option {
+	return func(o *Options) {
+		o.Assets = NewAssets(fsys)
+	}
+}
+
+// WithHTTP configures the HTTP utilities returned by Orchestrator.HTTP.
+func WithHTTP(h shttp.HTTP) Option {
+	return func(o *Options) {
+		o.HTTP = h
+	}
+}
+
+// WithSessionValues seeds the session with the given values. Values may be of
+// type string, bool, int, float64, []byte or time.Time. Any other value is
+// stored as JSON.
+func WithSessionValues(values map[string]any) Option {
+	return func(o *Options) {
+		o.SessionValues = values
+	}
+}
+
+// WithSessionLifetime configures the absolute lifetime and the idle timeout of
+// sessions. A zero value disables the respective expiry.
+func WithSessionLifetime(lifetime, idleTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.SessionLifetime = lifetime
+		o.SessionIdleTimeout = idleTimeout
+	}
+}
+
+// WithClock configures the function used to determine the current time.
+func WithClock(now func() time.Time) Option {
+	return func(o *Options) {
+		o.Now = now
+	}
+}
+
+// Orchestrator is an in-memory implementation of orchestrator.Orchestrator.
+type Orchestrator struct {
+	opts  Options
+	ctx   context.Context
+	state *state
+}
+
+// state is shared between an Orchestrator and the copies returned by
+// WithContext.
+type state struct {
+	sessions *sessionStore
+
+	mu     sync.Mutex
+	caches map[string]cache.Extended
+}
+
+var (
+	_ orchestrator.LockerProvider  = (*Orchestrator)(nil)
+	_ orchestrator.KeyringProvider = (*Orchestrator)(nil)
+	_ orchestrator.AuditProvider   = (*Orchestrator)(nil)
+	_ orchestrator.MetricsProvider = (*Orchestrator)(nil)
+	_ orchestrator.TracerProvider  = (*Orchestrator)(nil)
+)
+
+// New creates an in-memory Orchestrator. Any dependency that is not configured
+// via an Option is backed by an in-memory fake from this package.
+func New(opts ...Option) *Orchestrator {
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	if o.Logger == nil {
+		o.Logger = NewLogger()
+	}
+	if o.Audit == nil {
+		al := NewAuditLog()
+		al.Now = o.Now
+		o.Audit = al
+	}
+	if o.Metrics == nil {
+		o.Metrics = NewMetricsRecorder()
+	}
+	if o.SecretProvider == nil && o.Secrets != nil {
+		o.SecretProvider = newSecretProvider(o.Secrets, o.Now)
+	}
+	if o.Router == nil {
+		r := NewRouter()
+		if o.App != nil {
+			r.Owner = o.App.Name()
+		}
+		o.Router = r
+	}
+	if o.TAI == nil {
+		o.TAI = TAI{Now: o.Now}
+	}
+	if o.WebLogic == nil {
+		o.WebLogic = WebLogic{Now: o.Now}
+	}
+	if o.Context == nil {
+		o.Context = context.Background()
+	}
+	if o.Assets == nil {
+		o.Assets = NewAssets(nil)
+	}
+	if o.Tracer == nil {
+		o.Tracer = trace.NewTracer(trace.WithClock(o.Now))
+	}
+	if o.HTTP == nil {
+		h := NewHTTP()
+		h.Tracer = o.Tracer
+		o.HTTP = h
+	}
+	if o.Metadata == nil {
+		o.Metadata = make(map[string]any)
+	}
+
+	return &Orchestrator{
+		opts: o,
+		ctx:  o.Context,
+		state: &state{
+			sessions: newSessionStore(o.Now, o.SessionLifetime, o.SessionIdleTimeout, o.SessionValues),
+			caches:   make(map[string]cache.Extended),
+		},
+	}
+}
+
+// Logger gets the configured logger. If the logger is a *Logger and a request
+// is passed with log.WithRequest, the records captured by the returned logger
+// reference the request.
+func (o *Orchestrator) Logger(opts ...log.Option) log.Logger {
+	lo := log.Options{}
+	for _, opt := range opts {
+		opt(&lo)
+	}
+	if l, ok := o.opts.Logger.(*Logger); ok && lo.Request != nil {
+		return l.forRequest(lo.Request)
+	}
+	return o.opts.Logger
+}
+
+// Audit gets the configured audit logger.
+func (o *Orchestrator) Audit() (audit.Logger, error) {
+	return o.opts.Audit, nil
+}
+
+// Metrics gets the configured metrics provider.
+func (o *Orchestrator) Metrics() metrics.Provider {
+	return o.opts.Metrics
+}
+
+// Tracer gets the configured tracer.
+func (o *Orchestrator) Tracer() trace.Tracer {
+	return o.opts.Tracer
+}
+
+// Session returns a handle to an in-memory session. If a request is passed with
+// session.WithRequest and has a SessionCookieName cookie, the session with the
+// ID held by the cookie is returned. Otherwise, the default session, which is
+// seeded with the values passed to WithSessionValues, is returned. A new
+// session is created if the session does not exist, has been invalidated or has
+// expired. All handles share the same underlying session store, but each handle
+// tracks its own changelog until Save is called.
+func (o *Orchestrator) Session(opts ...session.SessionOpt) (session.Provider, error) {
+	so := session.Options{}
+	for _, opt := range opts {
+		opt(&so)
+	}
+	return o.state.sessions.open(so.Request), nil
+}
+
+// SecretProvider gets the configured secret provider. An error is returned if
+// a secret provider is not configured.
+func (o *Orchestrator) SecretProvider() (secret.Provider, error) {
+	if o.opts.SecretProvider == nil {
+		return nil, errors.New("secret provider not configured")
+	}
+	return o.opts.SecretProvider, nil
+}
+
+// Keyring gets a keyring backed by the configured secret provider. An error is
+// returned if a secret provider is not configured, it does not implement
+// secret.Versioned or the secret does not exist.
+func (o *Orchestrator) Keyring(name string) (keys.Keyring, error) {
+	sp, err := o.SecretProvider()
+	if err != nil {
+		return nil, err
+	}
+	vp, ok := sp.(secret.Versioned)
+	if !ok {
+		return nil, errors.New("unable to get keyring: secret provider does not support versions")
+	}
+	if _, err := vp.Metadata(name); err != nil {
+		return nil, fmt.Errorf("unable to get keyring: %w", err)
+	}
+	return keys.New(vp, name), nil
+}
+
+// IdentityProvider gets an identity provider by name. An error is returned if
+// the identity provider is not found.
+func (o *Orchestrator) IdentityProvider(name string) (idfabric.IdentityProvider, error) {
+	idp, ok := o.opts.IdentityProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("identity provider '%s' not found", name)
+	}
+	return idp, nil
+}
+
+// AttributeProvider gets an attribute provider by name. An error is returned if
+// the attribute provider is not found.
+func (o *Orchestrator) AttributeProvider(name string) (idfabric.AttributeProvider, error) {
+	ap, ok := o.opts.AttributeProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("attribute provider '%s' not found", name)
+	}
+	return ap, nil
+}
+
+// Metadata gets the configured metadata.
+func (o *Orchestrator) Metadata() map[string]any {
+	return o.opts.Metadata
+}
+
+// Router gets the configured router.
+func (o *Orchestrator) Router() router.Router {
+	return o.opts.Router
+}
+
+// App gets the configured app. An error is returned if an app is not
+// configured.
+func (o *Orchestrator) App() (app.App, error) {
+	if o.opts.App == nil {
+		return nil, errors.New("app not configured")
+	}
+	return o.opts.App, nil
+}
+
+// TAI gets the configured TAI provider.
+func (o *Orchestrator) TAI() tai.Provider {
+	return o.opts.TAI
+}
+
+// WebLogic gets the configured WebLogic provider.
+func (o *Orchestrator) WebLogic() weblogic.Provider {
+	return o.opts.WebLogic
+}
+
+// Context gets the context associated with the Orchestrator.
+func (o *Orchestrator) Context() context.Context {
+	return o.ctx
+}
+
+// WithContext returns a shallow copy of the Orchestrator with the provided
+// context. The copy shares its session, caches and other dependencies with the
+// original.
+func (o *Orchestrator) WithContext(ctx context.Context) orchestrator.Orchestrator {
+	cp := *o
+	cp.ctx = ctx
+	return &cp
+}
+
+// Cache returns the in-memory cache for the given namespace and name. Caches
+// are created on first use with the capacity and eviction policy of the given
+// constraints, backed by a cache.MemoryBackend, and persist for the lifetime of
+// the Orchestrator. The returned caches implement cache.Extended.
+func (o *Orchestrator) Cache(namespace string, opts ...cache.Constraint) (cache.Cache, error) {
+	return o.cache(namespace, opts...)
+}
+
+func (o *Orchestrator) cache(namespace string, opts ...cache.Constraint) (cache.Extended, error) {
+	if namespace == "" {
+		return nil, errors.New("cache namespace must not be empty")
+	}
+	c := cache.Constraints{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	id := namespace + "/" + c.Name
+	o.state.mu.Lock()
+	defer o.state.mu.Unlock()
+	if existing, ok := o.state.caches[id]; ok {
+		return existing, nil
+	}
+	nc := cache.NewLocal(cache.NewMemoryBackend(opts...), cache.WithClock(o.opts.Now))
+	o.state.caches[id] = nc
+	return nc, nil
+}
+
+// Locker returns a Locker whose locks are stored in the in-memory cache for the
+// given namespace and name.
+func (o *Orchestrator) Locker(namespace string, opts ...cache.Constraint) (lock.Locker, error) {
+	c, err := o.cache(namespace, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return lock.NewCacheLocker(c, lock.WithClock(o.opts.Now)), nil
+}
+
+// ServiceExtensionAssets gets the configured service extension assets.
+func (o *Orchestrator) ServiceExtensionAssets() bundle.SEAssets {
+	return o.opts.Assets
+}
+
+// HTTP gets the configured HTTP utilities.
+func (o *Orchestrator) HTTP() shttp.HTTP {
+	return o.opts.HTTP
+}
+
+// App is an in-memory app.App.
+type App struct {
+	AppName string
+}
+
+// Name returns the name of the application.
+func (a App) Name() string {
+	return a.AppName
+}
@@ -0,0 +1,196 @@
+package orchestratortest
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/strata-io/service-extension/router"
+)
+
+// Router is a router.Router backed by an http.ServeMux, which matches methods,
+// hosts and wildcards and responds with 405 Method Not Allowed exactly as the
+// Orchestrator does. Router implements http.Handler so registered routes can be
+// exercised with net/http/httptest.
+//
+// Example:
+//
+//	r := orchestratortest.NewRouter()
+//	api := orchestratortest.New(orchestratortest.WithRouter(r))
+//	_ = Serve(api)
+//
+//	rec := httptest.NewRecorder()
+//	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback", nil))
+type Router struct {
+	// Owner is recorded as the owner of routes registered through the Router.
+	// The Router that New creates if none is configured is owned by the name of
+	// the app of the Orchestrator.
+	Owner string
+
+	mu         sync.RWMutex
+	mux        *http.ServeMux
+	routes     map[string]route
+	middleware []router.Middleware
+}
+
+// route is a registered route and its handler, including middleware.
+type route struct {
+	router.Route
+	handler http.Handler
+}
+
+var (
+	_ router.Grouper  = (*Router)(nil)
+	_ router.Registry = (*Router)(nil)
+)
+
+// NewRouter creates an empty Router.
+func NewRouter() *Router {
+	return &Router{
+		mux:    http.NewServeMux(),
+		routes: make(map[string]route),
+	}
+}
+
+// HandleFunc registers the handler function for the given pattern. An error is
+// returned if the pattern is already registered, is invalid or conflicts with a
+// registered pattern.
+func (r *Router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) error {
+	return r.register(pattern, handler, false)
+}
+
+// Replace registers the handler function for the given pattern, replacing the
+// handler of the route if it is already registered. An error is returned if the
+// pattern is invalid, conflicts with another registered pattern or the route is
+// owned by a different owner.
+func (r *Router) Replace(pattern string, handler func(http.ResponseWriter, *http.Request)) error {
+	return r.register(pattern, handler, true)
+}
+
+// Unregister removes the route registered for the given pattern. An error is
+// returned if the route is not registered or is owned by a different owner.
+func (r *Router) Unregister(pattern string) error {
+	p, err := router.ParsePattern(pattern)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.routes[p.String()]
+	if !ok {
+		return fmt.Errorf("route '%s' is not registered", pattern)
+	}
+	if existing.Owner != r.Owner {
+		return fmt.Errorf("route '%s' is owned by '%s'", pattern, existing.Owner)
+	}
+
+	routes := maps.Clone(r.routes)
+	delete(routes, p.String())
+	mux, err := newServeMux(routes)
+	if err != nil {
+		return err
+	}
+	r.mux, r.routes = mux, routes
+	return nil
+}
+
+// Routes returns all registered routes, ordered by pattern.
+func (r *Router) Routes() []router.Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := make([]router.Route, 0, len(r.routes))
+	for _, k := range slices.Sorted(maps.Keys(r.routes)) {
+		routes = append(routes, r.routes[k].Route)
+	}
+	return routes
+}
+
+func (r *Router) register(pattern string, handler func(http.ResponseWriter, *http.Request), replace bool) error {
+	p, err := router.ParsePattern(pattern)
+	if err != nil {
+		return err
+	}
+	key := p.String()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.routes[key]; ok {
+		if !replace {
+			return fmt.Errorf("route '%s' is already registered", pattern)
+		}
+		if existing.Owner != r.Owner {
+			return fmt.Errorf("route '%s' is owned by '%s'", pattern, existing.Owner)
+		}
+	}
+
+	rt := route{
+		Route:   router.Route{Pattern: key, Owner: r.Owner},
+		handler: router.Chain(http.HandlerFunc(handler), r.middleware...),
+	}
+	if _, ok := r.routes[key]; !ok {
+		// Adding a route does not require rebuilding the mux.
+		if err := handle(r.mux, rt); err != nil {
+			return err
+		}
+		r.routes[key] = rt
+		return nil
+	}
+
+	// http.ServeMux does not support removing patterns, so replacing a route
+	// requires a new mux.
+	routes := maps.Clone(r.routes)
+	routes[key] = rt
+	mux, err := newServeMux(routes)
+	if err != nil {
+		return err
+	}
+	r.mux, r.routes = mux, routes
+	return nil
+}
+
+// newServeMux returns an http.ServeMux serving routes.
+func newServeMux(routes map[string]route) (*http.ServeMux, error) {
+	mux := http.NewServeMux()
+	for _, rt := range routes {
+		if err := handle(mux, rt); err != nil {
+			return nil, err
+		}
+	}
+	return mux, nil
+}
+
+// handle registers rt with mux. http.ServeMux panics on invalid or conflicting
+// patterns, which is reported as an error.
+func handle(mux *http.ServeMux, rt route) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("unable to register route '%s': %v", rt.Pattern, rec)
+		}
+	}()
+	mux.Handle(rt.Pattern, rt.handler)
+	return nil
+}
+
+// Use appends middleware to the chain applied to handlers registered
+// afterwards.
+func (r *Router) Use(mw ...router.Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Group returns a Router registering routes under the path prefix with the
+// given middleware.
+func (r *Router) Group(prefix string, mw ...router.Middleware) router.Grouper {
+	return router.NewGroup(r, prefix, mw...)
+}
+
+// ServeHTTP dispatches the request to the handler whose pattern most closely
+// matches the request URL.
+func (r *Router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	mux := r.mux
+	r.mu.RUnlock()
+	mux.ServeHTTP(rw, req)
+}
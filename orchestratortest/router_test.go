@@ -0,0 +1,205 @@
+package orchestratortest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/strata-io/service-extension/router"
+)
+
+func respond(body string) func(http.ResponseWriter, *http.Request) {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(body))
+	}
+}
+
+func serveRouter(r *Router, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestRouterOwners(t *testing.T) {
+	r := NewRouter()
+	r.Owner = "a"
+	if err := r.HandleFunc("GET /a", respond("a")); err != nil {
+		t.Fatalf("HandleFunc() error = %v", err)
+	}
+
+	// Changing the owner stands in for another service extension registering
+	// routes on the Orchestrator.
+	r.Owner = "b"
+	if err := r.HandleFunc("GET /b", respond("b")); err != nil {
+		t.Fatalf("HandleFunc() error = %v", err)
+	}
+	if err := r.Replace("GET /a", respond("b")); err == nil || !strings.Contains(err.Error(), "owned by 'a'") {
+		t.Errorf("Replace() of route of another owner error = %v", err)
+	}
+	if err := r.Unregister("GET /a"); err == nil || !strings.Contains(err.Error(), "owned by 'a'") {
+		t.Errorf("Unregister() of route of another owner error = %v", err)
+	}
+	r.Owner = "a"
+	if err := r.Replace("GET  /a", respond("a2")); err != nil {
+		t.Errorf("Replace() of own route error = %v", err)
+	}
+
+	want := []router.Route{{Pattern: "GET /a", Owner: "a"}, {Pattern: "GET /b", Owner: "b"}}
+	if got := r.Routes(); !slices.Equal(got, want) {
+		t.Errorf("Routes() = %v, want %v", got, want)
+	}
+	if rec := serveRouter(r, http.MethodGet, "/a"); rec.Body.String() != "a2" {
+		t.Errorf("GET /a = %q, want %q", rec.Body, "a2")
+	}
+
+	if err := r.Unregister("GET /a"); err != nil {
+		t.Errorf("Unregister() of own route error = %v", err)
+	}
+	if err := r.Unregister("GET /a"); err == nil {
+		t.Error("Unregister() of unregistered route succeeded")
+	}
+	if rec := serveRouter(r, http.MethodGet, "/a"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /a after Unregister = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := serveRouter(r, http.MethodGet, "/b"); rec.Body.String() != "b" {
+		t.Errorf("GET /b after Unregister = %q, want %q", rec.Body, "b")
+	}
+}
+
+func TestRouterConflicts(t *testing.T) {
+	r := NewRouter()
+	if err := r.HandleFunc("GET /users/{id}", respond("user")); err != nil {
+		t.Fatalf("HandleFunc() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{"duplicate", "GET /users/{id}"},
+		{"duplicate in other form", "GET  /users/{id}"},
+		// http.ServeMux panics on patterns that conflict with registered ones.
+		{"conflict", "GET /users/{name}"},
+		{"invalid", "GET users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.HandleFunc(tt.pattern, respond("x")); err == nil {
+				t.Error("HandleFunc() succeeded")
+			}
+		})
+	}
+	if err := r.Replace("GET /users/{name}", respond("x")); err == nil {
+		t.Error("Replace() with conflicting pattern succeeded")
+	}
+
+	// A failed registration leaves the registered routes in place.
+	if got := r.Routes(); len(got) != 1 || got[0].Pattern != "GET /users/{id}" {
+		t.Errorf("Routes() = %v, want only GET /users/{id}", got)
+	}
+	if rec := serveRouter(r, http.MethodGet, "/users/1"); rec.Body.String() != "user" {
+		t.Errorf("GET /users/1 = %q, want %q", rec.Body, "user")
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	r := NewRouter()
+	for _, p := range []string{"GET /a", "POST /a"} {
+		if err := r.HandleFunc(p, respond(p)); err != nil {
+			t.Fatalf("HandleFunc(%q) error = %v", p, err)
+		}
+	}
+
+	tests := []struct {
+		method string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, http.StatusOK, "GET /a"},
+		{http.MethodHead, http.StatusOK, ""},
+		{http.MethodPost, http.StatusOK, "POST /a"},
+		{http.MethodDelete, http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		rec := serveRouter(r, tt.method, "/a")
+		if rec.Code != tt.code || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("%s /a = %d %q, want %d %q", tt.method, rec.Code, rec.Body, tt.code, tt.body)
+		}
+	}
+	if allow := serveRouter(r, http.MethodDelete, "/a").Header().Values("Allow"); len(allow) == 0 {
+		t.Error("405 response has no Allow header")
+	}
+}
+
+func TestRouterReplaceRebuildsMux(t *testing.T) {
+	r := NewRouter()
+	for _, p := range []string{"GET /a", "POST /a", "/b/"} {
+		if err := r.HandleFunc(p, respond(p)); err != nil {
+			t.Fatalf("HandleFunc(%q) error = %v", p, err)
+		}
+	}
+	if err := r.Replace("POST /a", respond("replaced")); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	// Replace registers routes that are not registered yet.
+	if err := r.Replace("GET /c", respond("GET /c")); err != nil {
+		t.Fatalf("Replace() of new route error = %v", err)
+	}
+
+	tests := []struct {
+		method, target string
+		code           int
+		body           string
+	}{
+		{http.MethodGet, "/a", http.StatusOK, "GET /a"},
+		{http.MethodPost, "/a", http.StatusOK, "replaced"},
+		{http.MethodGet, "/b/x", http.StatusOK, "/b/"},
+		{http.MethodGet, "/c", http.StatusOK, "GET /c"},
+		{http.MethodDelete, "/a", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		rec := serveRouter(r, tt.method, tt.target)
+		if rec.Code != tt.code || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.target, rec.Code, rec.Body, tt.code, tt.body)
+		}
+	}
+	if allow := serveRouter(r, http.MethodDelete, "/a").Header().Get("Allow"); !strings.Contains(allow, "POST") {
+		t.Errorf("Allow = %q, want POST to be allowed", allow)
+	}
+}
+
+func TestRouterMiddleware(t *testing.T) {
+	r := NewRouter()
+	tag := func(name string) router.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.Header().Add("X-Trace", name)
+				next.ServeHTTP(rw, req)
+			})
+		}
+	}
+	_ = r.HandleFunc("/before", respond(""))
+	r.Use(tag("a"))
+	_ = r.Group("/api", tag("b")).HandleFunc("/x", respond(""))
+
+	if got := serveRouter(r, http.MethodGet, "/before").Header().Values("X-Trace"); len(got) != 0 {
+		t.Errorf("middleware of /before = %q, want none", got)
+	}
+	if got := strings.Join(serveRouter(r, http.MethodGet, "/api/x").Header().Values("X-Trace"), ","); got != "a,b" {
+		t.Errorf("middleware of /api/x = %q, want %q", got, "a,b")
+	}
+}
+
+func TestNewRouterOwner(t *testing.T) {
+	api := New(WithApp("my-app"))
+	r := api.Router().(*Router)
+	if r.Owner != "my-app" {
+		t.Errorf("Owner = %q, want %q", r.Owner, "my-app")
+	}
+
+	supplied := NewRouter()
+	if New(WithApp("my-app"), WithRouter(supplied)); supplied.Owner != "" {
+		t.Errorf("Owner of supplied router = %q, want it unchanged", supplied.Owner)
+	}
+}
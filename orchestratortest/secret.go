@@ -0,0 +1,283 @@
+package orchestratortest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strata-io/service-extension/secret"
+)
+
+// secretVersion is a version of a secret held by a SecretProvider.
+type secretVersion struct {
+	value any
+	meta  secret.Metadata
+}
+
+type secretWatch struct {
+	key string
+	fn  func(old, new secret.Secret)
+}
+
+// SecretProvider is an in-memory secret.Lookuper, secret.Versioned and
+// secret.Writer. Every call to Set creates a new version of the secret and
+// notifies watchers of the key. Versions are numbered from "1" and are never
+// reused for a key, even if it is deleted and set again.
+//
+// Watch functions are called one at a time, in the order of the versions, by
+// the goroutine calling Set before it returns. Versions set while watch
+// functions are running, including versions set by a watch function, are
+// instead delivered by the goroutine already running them, so a watch function
+// may call Set without deadlocking.
+type SecretProvider struct {
+	// Now returns the current time, used as the creation time of versions.
+	// Defaults to time.Now. The versions created by NewSecretProvider use
+	// time.Now; use WithSecrets to create them with the clock of an
+	// Orchestrator.
+	Now func() time.Time
+
+	mu       sync.RWMutex
+	versions map[string][]secretVersion
+	// lastVersions holds the last version number assigned to every key.
+	lastVersions map[string]int
+	watches      map[*secretWatch]struct{}
+	// notifications calls watch functions in the order of the versions.
+	notifications dispatcher
+}
+
+var (
+	_ secret.Lookuper  = (*SecretProvider)(nil)
+	_ secret.Versioned = (*SecretProvider)(nil)
+	_ secret.Writer    = (*SecretProvider)(nil)
+)
+
+// NewSecretProvider creates a SecretProvider serving the given secrets.
+func NewSecretProvider(secrets map[string]any) *SecretProvider {
+	return newSecretProvider(secrets, nil)
+}
+
+// newSecretProvider creates a SecretProvider serving the given secrets, whose
+// versions are created at the time returned by now.
+func newSecretProvider(secrets map[string]any, now func() time.Time) *SecretProvider {
+	p := &SecretProvider{
+		Now:          now,
+		versions:     make(map[string][]secretVersion, len(secrets)),
+		lastVersions: make(map[string]int, len(secrets)),
+		watches:      make(map[*secretWatch]struct{}),
+	}
+	for k, v := range secrets {
+		p.versions[k] = []secretVersion{p.newVersion(k, "", v, time.Time{})}
+	}
+	return p
+}
+
+// Get retrieves the key from the secret provider. If the key does not exist,
+// nil is returned.
+func (p *SecretProvider) Get(key string) any {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	v, ok := p.current(key)
+	if !ok {
+		return nil
+	}
+	return v.value
+}
+
+// GetString retrieves the key from the secret provider as a string value. If
+// the key does not exist, an empty string is returned.
+func (p *SecretProvider) GetString(key string) string {
+	v, _ := p.Lookup(key)
+	return v
+}
+
+// Lookup retrieves the key from the secret provider as a string value. The
+// returned bool is false if the key does not exist.
+func (p *SecretProvider) Lookup(key string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	v, ok := p.current(key)
+	if !ok {
+		return "", false
+	}
+	return formatSecret(v.value), true
+}
+
+// GetVersion retrieves the given version of the key. An empty version retrieves
+// the current version.
+func (p *SecretProvider) GetVersion(key, version string) (secret.Secret, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if version == "" {
+		v, ok := p.current(key)
+		if !ok {
+			return secret.Secret{}, fmt.Errorf("secret '%s': %w", key, secret.ErrNotFound)
+		}
+		return toSecret(key, v), nil
+	}
+	for _, v := range p.versions[key] {
+		if v.meta.Version == version {
+			return toSecret(key, v), nil
+		}
+	}
+	return secret.Secret{}, fmt.Errorf("secret '%s' version '%s': %w", key, version, secret.ErrNotFound)
+}
+
+// Metadata retrieves the metadata of the current version of the key.
+func (p *SecretProvider) Metadata(key string) (secret.Metadata, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	v, ok := p.current(key)
+	if !ok {
+		return secret.Metadata{}, fmt.Errorf("secret '%s': %w", key, secret.ErrNotFound)
+	}
+	return v.meta, nil
+}
+
+// Watch registers fn to be called every time a new version of the key is set
+// or the key is deleted.
+func (p *SecretProvider) Watch(key string, fn func(old, new secret.Secret)) (func(), error) {
+	w := &secretWatch{key: key, fn: fn}
+	p.mu.Lock()
+	p.watches[w] = struct{}{}
+	p.mu.Unlock()
+	return func() {
+		p.mu.Lock()
+		delete(p.watches, w)
+		p.mu.Unlock()
+	}, nil
+}
+
+// Set adds a new version of a secret that does not expire. It can be used to
+// simulate secret rotation while a test is running.
+func (p *SecretProvider) Set(key string, value any) {
+	p.SetWithExpiry(key, value, time.Time{})
+}
+
+// SetWithExpiry adds a new version of a secret that expires at the given time,
+// e.g. to simulate a dynamic secret with a lease.
+func (p *SecretProvider) SetWithExpiry(key string, value any, expiresAt time.Time) {
+	p.mu.Lock()
+	var old secret.Secret
+	if prev, ok := p.current(key); ok {
+		old = toSecret(key, prev)
+	}
+	v := p.newVersion(key, old.Version, value, expiresAt)
+	p.versions[key] = append(p.versions[key], v)
+	p.notify(key, old, toSecret(key, v))
+	p.mu.Unlock()
+	p.notifications.run()
+}
+
+// Put adds a new version of a secret. It implements secret.Writer.
+func (p *SecretProvider) Put(key, value string) error {
+	p.Set(key, value)
+	return nil
+}
+
+// Delete removes all versions of a secret. It implements secret.Writer.
+func (p *SecretProvider) Delete(key string) error {
+	p.mu.Lock()
+	prev, ok := p.current(key)
+	if !ok {
+		p.mu.Unlock()
+		return nil
+	}
+	delete(p.versions, key)
+	p.notify(key, toSecret(key, prev), secret.Secret{})
+	p.mu.Unlock()
+	p.notifications.run()
+	return nil
+}
+
+// List returns the keys that begin with the given prefix, in lexicographical
+// order. It implements secret.Writer.
+func (p *SecretProvider) List(prefix string) ([]string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	keys := make([]string, 0)
+	for key := range p.versions {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// notify queues calls to the watch functions of key. The caller must hold p.mu,
+// so that calls are queued in the order of the versions, and run
+// p.notifications after releasing it.
+func (p *SecretProvider) notify(key string, old, new secret.Secret) {
+	for w := range p.watches {
+		if w.key == key {
+			p.notifications.enqueue(func() {
+				if p.watching(w) {
+					w.fn(old, new)
+				}
+			})
+		}
+	}
+}
+
+// watching reports whether w has not been stopped.
+func (p *SecretProvider) watching(w *secretWatch) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.watches[w]
+	return ok
+}
+
+// current returns the current version of key. The caller must hold p.mu.
+func (p *SecretProvider) current(key string) (secretVersion, bool) {
+	versions := p.versions[key]
+	if len(versions) == 0 {
+		return secretVersion{}, false
+	}
+	return versions[len(versions)-1], true
+}
+
+// newVersion returns a new version of key following prev, which is empty for
+// the first version of a secret. The caller must hold p.mu.
+func (p *SecretProvider) newVersion(key, prev string, value any, expiresAt time.Time) secretVersion {
+	p.lastVersions[key]++
+	return secretVersion{
+		value: value,
+		meta: secret.Metadata{
+			Version:         strconv.Itoa(p.lastVersions[key]),
+			PreviousVersion: prev,
+			CreatedAt:       p.now(),
+			ExpiresAt:       expiresAt,
+		},
+	}
+}
+
+func (p *SecretProvider) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+func toSecret(key string, v secretVersion) secret.Secret {
+	return secret.Secret{
+		Metadata: v.meta,
+		Key:      key,
+		Value:    formatSecret(v.value),
+	}
+}
+
+func formatSecret(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,105 @@
+package orchestratortest
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/strata-io/service-extension/secret"
+)
+
+func TestSecretProviderVersionsAreNotReused(t *testing.T) {
+	p := NewSecretProvider(map[string]any{"k": "a"})
+	p.Set("k", "b")
+	if err := p.Delete("k"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := p.Put("k", "c"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	md, err := p.Metadata("k")
+	if err != nil || md.Version != "3" {
+		t.Errorf("Metadata() = %+v, %v, want version 3", md, err)
+	}
+}
+
+func TestSecretProviderSeedVersionsUseClock(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	api := New(WithSecrets(map[string]any{"k": "a"}), WithClock(func() time.Time { return now }))
+	sp, err := api.SecretProvider()
+	if err != nil {
+		t.Fatalf("SecretProvider() error = %v", err)
+	}
+
+	md, err := sp.(secret.Versioned).Metadata("k")
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if !md.CreatedAt.Equal(now) {
+		t.Errorf("CreatedAt = %v, want %v", md.CreatedAt, now)
+	}
+}
+
+func TestNewDoesNotModifySecretProvider(t *testing.T) {
+	p := NewSecretProvider(map[string]any{"k": "a"})
+	New(WithSecretProvider(p), WithClock(time.Now))
+	if p.Now != nil {
+		t.Error("New set Now of the SecretProvider")
+	}
+}
+
+func TestSecretProviderWatchOrder(t *testing.T) {
+	p := NewSecretProvider(nil)
+	var got []string
+	stop, err := p.Watch("k", func(_, s secret.Secret) {
+		got = append(got, s.Version)
+		// Setting the key from a watch function must not deadlock, and the new
+		// version must be delivered after the current one.
+		if s.Value == "a" {
+			p.Set("k", "b")
+		}
+	})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer stop()
+
+	p.Set("k", "a")
+	if want := []string{"1", "2"}; !slices.Equal(got, want) {
+		t.Errorf("versions = %v, want %v", got, want)
+	}
+}
+
+func TestSecretProviderWatchOrderConcurrent(t *testing.T) {
+	p := NewSecretProvider(nil)
+	var (
+		mu   sync.Mutex
+		last int
+		bad  bool
+	)
+	stop, _ := p.Watch("k", func(_, s secret.Secret) {
+		n, _ := strconv.Atoi(s.Version)
+		mu.Lock()
+		defer mu.Unlock()
+		if n <= last {
+			bad = true
+		}
+		last = n
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Set("k", "v")
+		}()
+	}
+	wg.Wait()
+	if bad || last != 50 {
+		t.Errorf("watch calls out of order or missing, last version = %d", last)
+	}
+}
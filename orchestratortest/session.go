@@ -0,0 +1,686 @@
+package orchestratortest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strata-io/service-extension/session"
+)
+
+// SessionCookieName is the name of the cookie identifying the session of a
+// request passed to Orchestrator.Session with session.WithRequest. Its value is
+// the ID of the session.
+const SessionCookieName = "maverics_session"
+
+// sessionStore is the backing store shared by all Session handles opened from
+// the same Orchestrator.
+type sessionStore struct {
+	now         func() time.Time
+	lifetime    time.Duration
+	idleTimeout time.Duration
+
+	mu sync.Mutex
+	// current is the default session, which is used unless a request
+	// identifies another session.
+	current *sessionRecord
+	// sessions holds all sessions by ID.
+	sessions map[string]*sessionRecord
+}
+
+// sessionRecord is the saved state of a single session.
+type sessionRecord struct {
+	id             string
+	values         map[string]any
+	createdAt      time.Time
+	lastAccessedAt time.Time
+	expiresAt      time.Time
+	idleTimeout    time.Duration
+	invalidated    bool
+	version        uint64
+}
+
+func (r *sessionRecord) idleExpiresAt() time.Time {
+	if r.idleTimeout <= 0 {
+		return time.Time{}
+	}
+	return r.lastAccessedAt.Add(r.idleTimeout)
+}
+
+func (r *sessionRecord) expired(now time.Time) bool {
+	if !r.expiresAt.IsZero() && !now.Before(r.expiresAt) {
+		return true
+	}
+	idle := r.idleExpiresAt()
+	return !idle.IsZero() && !now.Before(idle)
+}
+
+func newSessionStore(now func() time.Time, lifetime, idleTimeout time.Duration, seed map[string]any) *sessionStore {
+	s := &sessionStore{
+		now:         now,
+		lifetime:    lifetime,
+		idleTimeout: idleTimeout,
+		sessions:    make(map[string]*sessionRecord),
+	}
+	s.current = s.newRecord()
+	for k, v := range seed {
+		s.current.values[k] = normalizeSessionValue(v)
+	}
+	return s
+}
+
+// newRecord creates and adds an empty session record. The caller must hold s.mu
+// unless the store is still being constructed.
+func (s *sessionStore) newRecord() *sessionRecord {
+	now := s.now()
+	r := &sessionRecord{
+		id:             newSessionID(),
+		values:         make(map[string]any),
+		createdAt:      now,
+		lastAccessedAt: now,
+		idleTimeout:    s.idleTimeout,
+	}
+	if s.lifetime > 0 {
+		r.expiresAt = now.Add(s.lifetime)
+	}
+	s.sessions[r.id] = r
+	return r
+}
+
+// open returns a handle to the session identified by the SessionCookieName
+// cookie of req, or to the default session if req is nil or does not have the
+// cookie. A new session is created if the session does not exist, has been
+// invalidated or has expired.
+func (s *sessionStore) open(req *http.Request) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	r, byCookie := s.current, false
+	if req != nil {
+		if c, err := req.Cookie(SessionCookieName); err == nil {
+			r, byCookie = s.sessions[c.Value], true
+		}
+	}
+	if r == nil || r.invalidated || r.expired(now) {
+		if r != nil {
+			delete(s.sessions, r.id)
+		}
+		r = s.newRecord()
+		if !byCookie {
+			s.current = r
+		}
+	}
+	r.lastAccessedAt = now
+	return &Session{store: s, record: r, version: r.version}
+}
+
+// sessionChange is a single pending mutation recorded in a Session's changelog.
+type sessionChange struct {
+	key     string
+	value   any
+	input   any
+	deleted bool
+	cleared bool
+}
+
+func (c sessionChange) toChange() session.Change {
+	switch {
+	case c.cleared:
+		return session.Change{Op: session.OpClear}
+	case c.deleted:
+		return session.Change{Op: session.OpDelete, Key: c.key}
+	default:
+		return session.Change{Op: session.OpSet, Key: c.key, Value: c.input}
+	}
+}
+
+// Session is an in-memory session.Provider. Setters record changes in a
+// changelog which is applied to the shared session store when Save is called.
+// Getters observe both saved values, including those saved by other handles,
+// and the handle's pending changes.
+type Session struct {
+	store  *sessionStore
+	record *sessionRecord
+
+	mu      sync.Mutex
+	changes []sessionChange
+	version uint64
+}
+
+var (
+	_ session.Store     = (*Session)(nil)
+	_ session.Lifecycle = (*Session)(nil)
+	_ session.Versioned = (*Session)(nil)
+)
+
+// GetString returns a session value based on the provided key.
+func (s *Session) GetString(key string) (string, error) {
+	v, ok, err := s.lookup(key)
+	if err != nil || !ok {
+		return "", err
+	}
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	case json.RawMessage:
+		return string(t), nil
+	case time.Time:
+		return t.Format(time.RFC3339Nano), nil
+	default:
+		return fmt.Sprint(t), nil
+	}
+}
+
+// GetBool returns a session value based on the provided key.
+func (s *Session) GetBool(key string) (bool, error) {
+	v, ok, err := s.lookup(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	switch t := v.(type) {
+	case bool:
+		return t, nil
+	case string:
+		b, err := strconv.ParseBool(t)
+		if err != nil {
+			return false, fmt.Errorf("session value '%s' is not a bool: %w", key, err)
+		}
+		return b, nil
+	default:
+		return false, typeMismatch(key, v, "bool")
+	}
+}
+
+// GetInt returns a session value based on the provided key.
+func (s *Session) GetInt(key string) (int, error) {
+	v, ok, err := s.lookup(key)
+	if err != nil || !ok {
+		return 0, err
+	}
+	switch t := v.(type) {
+	case int:
+		return t, nil
+	case float64:
+		return int(t), nil
+	case string:
+		i, err := strconv.Atoi(t)
+		if err != nil {
+			return 0, fmt.Errorf("session value '%s' is not an int: %w", key, err)
+		}
+		return i, nil
+	default:
+		return 0, typeMismatch(key, v, "int")
+	}
+}
+
+// GetFloat returns a session value based on the provided key.
+func (s *Session) GetFloat(key string) (float64, error) {
+	v, ok, err := s.lookup(key)
+	if err != nil || !ok {
+		return 0, err
+	}
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case int:
+		return float64(t), nil
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return 0, fmt.Errorf("session value '%s' is not a float: %w", key, err)
+		}
+		return f, nil
+	default:
+		return 0, typeMismatch(key, v, "float")
+	}
+}
+
+// GetBytes returns the []byte for a given key from the session data.
+func (s *Session) GetBytes(key string) ([]byte, error) {
+	v, ok, err := s.lookup(key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	switch t := v.(type) {
+	case []byte:
+		return append([]byte(nil), t...), nil
+	case json.RawMessage:
+		return append([]byte(nil), t...), nil
+	case string:
+		return []byte(t), nil
+	default:
+		return nil, typeMismatch(key, v, "[]byte")
+	}
+}
+
+// GetTime returns the time.Time for a given key from the session data.
+func (s *Session) GetTime(key string) (time.Time, error) {
+	v, ok, err := s.lookup(key)
+	if err != nil || !ok {
+		return time.Time{}, err
+	}
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		tm, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("session value '%s' is not a time: %w", key, err)
+		}
+		return tm, nil
+	default:
+		return time.Time{}, typeMismatch(key, v, "time.Time")
+	}
+}
+
+// GetJSON parses the JSON-encoded data for a given key from the session and
+// stores the result in the value pointed to by dest.
+func (s *Session) GetJSON(key string, dest any) error {
+	if dest == nil {
+		return errors.New("destination must be a non-nil pointer")
+	}
+	v, ok, err := s.lookup(key)
+	if err != nil || !ok {
+		return err
+	}
+	var raw []byte
+	switch t := v.(type) {
+	case json.RawMessage:
+		raw = t
+	case []byte:
+		raw = t
+	case string:
+		raw = []byte(t)
+	default:
+		return typeMismatch(key, v, "JSON")
+	}
+	if err := json.Unmarshal(raw, dest); err != nil {
+		return fmt.Errorf("unable to decode session value '%s': %w", key, err)
+	}
+	return nil
+}
+
+// SetString adds a key and the corresponding string value to the session data.
+func (s *Session) SetString(key string, value string) error {
+	return s.set(key, value, value)
+}
+
+// SetInt adds a key and the corresponding int value to the session data.
+func (s *Session) SetInt(key string, value int) error {
+	return s.set(key, value, value)
+}
+
+// SetFloat adds a key and the corresponding float value to the session data.
+func (s *Session) SetFloat(key string, value float64) error {
+	return s.set(key, value, value)
+}
+
+// SetBool adds a key and the corresponding boolean value to the session data.
+func (s *Session) SetBool(key string, value bool) error {
+	return s.set(key, value, value)
+}
+
+// SetBytes adds a key and the corresponding []byte value to the session data.
+func (s *Session) SetBytes(key string, value []byte) error {
+	return s.set(key, append([]byte(nil), value...), value)
+}
+
+// SetTime adds a key and the corresponding time.Time value to the session data.
+func (s *Session) SetTime(key string, value time.Time) error {
+	return s.set(key, value, value)
+}
+
+// SetJSON adds a key and the corresponding value to the session data. The value
+// must be JSON encodable or an error will be returned.
+func (s *Session) SetJSON(key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("unable to encode session value '%s': %w", key, err)
+	}
+	return s.set(key, json.RawMessage(raw), value)
+}
+
+// Save applies all changes from the changelog to the shared session store.
+func (s *Session) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return err
+	}
+
+	s.commit()
+	return nil
+}
+
+// SaveIfUnchanged applies all changes from the changelog to the shared session
+// store if the session has not been saved since this handle observed Version.
+func (s *Session) SaveIfUnchanged() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return err
+	}
+	if s.version != s.record.version {
+		return &session.ConflictError{
+			ID:       s.record.id,
+			Expected: s.version,
+			Actual:   s.record.version,
+		}
+	}
+
+	s.commit()
+	return nil
+}
+
+// commit applies the changelog to the session record. The version is only
+// incremented if the changelog is not empty, so that saving without changes
+// does not cause conflicts for other handles. Either way, the handle observes
+// the current version afterwards. The caller must hold s.mu and s.store.mu.
+func (s *Session) commit() {
+	s.record.lastAccessedAt = s.store.now()
+	if len(s.changes) > 0 {
+		s.record.values = applyChanges(s.record.values, s.changes)
+		s.record.version++
+		s.changes = nil
+	}
+	s.version = s.record.version
+}
+
+// Changes returns the pending changes in the changelog in the order they were
+// made.
+func (s *Session) Changes() []session.Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := make([]session.Change, 0, len(s.changes))
+	for _, c := range s.changes {
+		changes = append(changes, c.toChange())
+	}
+	return changes
+}
+
+// Discard discards all pending changes in the changelog.
+func (s *Session) Discard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = nil
+}
+
+// Version returns the version of the session data observed by this handle.
+func (s *Session) Version() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// Reload discards all pending changes and observes the current version of the
+// session data.
+func (s *Session) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return err
+	}
+	s.changes = nil
+	s.version = s.record.version
+	return nil
+}
+
+// Exists reports whether the key exists in the session data.
+func (s *Session) Exists(key string) (bool, error) {
+	_, ok, err := s.lookup(key)
+	return ok, err
+}
+
+// Delete removes the key and its value from the session data.
+func (s *Session) Delete(key string) error {
+	if key == "" {
+		return errors.New("session key must not be empty")
+	}
+	return s.appendChange(sessionChange{key: key, deleted: true})
+}
+
+// Keys returns the keys in the session data that begin with the provided
+// prefix, sorted in lexicographical order.
+func (s *Session) Keys(prefix string) ([]string, error) {
+	values, err := s.view()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	for k := range values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Clear removes all keys and values from the session data.
+func (s *Session) Clear() error {
+	return s.appendChange(sessionChange{cleared: true})
+}
+
+// ID returns the identifier of the session.
+func (s *Session) ID() string {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	return s.record.id
+}
+
+// Regenerate replaces the session identifier with a newly generated one while
+// retaining the session data.
+func (s *Session) Regenerate() error {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return err
+	}
+	delete(s.store.sessions, s.record.id)
+	s.record.id = newSessionID()
+	s.store.sessions[s.record.id] = s.record
+	return nil
+}
+
+// Invalidate destroys the session and discards any unsaved changes.
+func (s *Session) Invalidate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return err
+	}
+	s.record.invalidated = true
+	s.record.values = make(map[string]any)
+	delete(s.store.sessions, s.record.id)
+	s.changes = nil
+	return nil
+}
+
+// CreatedAt returns the time the session was created.
+func (s *Session) CreatedAt() time.Time {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	return s.record.createdAt
+}
+
+// LastAccessedAt returns the time the session was last accessed.
+func (s *Session) LastAccessedAt() time.Time {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	return s.record.lastAccessedAt
+}
+
+// ExpiresAt returns the absolute expiry of the session.
+func (s *Session) ExpiresAt() time.Time {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	return s.record.expiresAt
+}
+
+// IdleExpiresAt returns the time at which the session expires if it is not
+// accessed again.
+func (s *Session) IdleExpiresAt() time.Time {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	return s.record.idleExpiresAt()
+}
+
+// SetExpiresAt changes the absolute expiry of the session.
+func (s *Session) SetExpiresAt(expiresAt time.Time) error {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return err
+	}
+	s.record.expiresAt = expiresAt
+	return nil
+}
+
+// SetIdleTimeout changes the duration of inactivity after which the session
+// expires.
+func (s *Session) SetIdleTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return errors.New("idle timeout must not be negative")
+	}
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return err
+	}
+	s.record.idleTimeout = timeout
+	return nil
+}
+
+func (s *Session) set(key string, value, input any) error {
+	if key == "" {
+		return errors.New("session key must not be empty")
+	}
+	return s.appendChange(sessionChange{key: key, value: value, input: input})
+}
+
+// appendChange appends a change to the changelog.
+func (s *Session) appendChange(c sessionChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return err
+	}
+	s.changes = append(s.changes, c)
+	return nil
+}
+
+// checkLive returns session.ErrInvalidated if the session has been invalidated
+// or has expired. The caller must hold s.store.mu.
+func (s *Session) checkLive() error {
+	if s.record.invalidated || s.record.expired(s.store.now()) {
+		return session.ErrInvalidated
+	}
+	return nil
+}
+
+// lookup returns the value for key, preferring the most recent pending change
+// over the saved value.
+func (s *Session) lookup(key string) (any, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return nil, false, err
+	}
+
+	for i := len(s.changes) - 1; i >= 0; i-- {
+		c := s.changes[i]
+		if c.cleared {
+			return nil, false, nil
+		}
+		if c.key == key {
+			return c.value, !c.deleted, nil
+		}
+	}
+	v, ok := s.record.values[key]
+	return v, ok, nil
+}
+
+// view returns the saved session values with the pending changes applied.
+func (s *Session) view() (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if err := s.checkLive(); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(s.record.values))
+	for k, v := range s.record.values {
+		values[k] = v
+	}
+	return applyChanges(values, s.changes), nil
+}
+
+// applyChanges applies changes to values in order and returns the result.
+func applyChanges(values map[string]any, changes []sessionChange) map[string]any {
+	for _, c := range changes {
+		switch {
+		case c.cleared:
+			values = make(map[string]any)
+		case c.deleted:
+			delete(values, c.key)
+		default:
+			values[c.key] = c.value
+		}
+	}
+	return values
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// normalizeSessionValue converts a seed value into one of the representations
+// used by the session store.
+func normalizeSessionValue(v any) any {
+	switch t := v.(type) {
+	case string, bool, int, float64, []byte, time.Time, json.RawMessage:
+		return t
+	case int64:
+		return int(t)
+	case int32:
+		return int(t)
+	case float32:
+		return float64(t)
+	default:
+		raw, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t)
+		}
+		return json.RawMessage(raw)
+	}
+}
+
+func typeMismatch(key string, v any, want string) error {
+	return fmt.Errorf("session value '%s' of type %T cannot be read as %s", key, v, want)
+}
@@ -0,0 +1,305 @@
+package orchestratortest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/strata-io/service-extension/session"
+)
+
+// testClock is a manually advanced clock.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func openSession(t *testing.T, api *Orchestrator, opts ...session.SessionOpt) *Session {
+	t.Helper()
+	sess, err := api.Session(opts...)
+	if err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+	return sess.(*Session)
+}
+
+func TestSessionStore(t *testing.T) {
+	api := New(WithSessionValues(map[string]any{
+		"azure.authenticated": true,
+		"azure.email":         "jdoe@example.com",
+		"okta.authenticated":  true,
+	}))
+	sess := openSession(t, api)
+
+	if ok, err := sess.Exists("azure.email"); err != nil || !ok {
+		t.Errorf("Exists() = %v, %v, want true", ok, err)
+	}
+	keys, err := sess.Keys("azure.")
+	if err != nil || !slices.Equal(keys, []string{"azure.authenticated", "azure.email"}) {
+		t.Errorf("Keys() = %v, %v, want the azure keys", keys, err)
+	}
+
+	_ = sess.Delete("azure.email")
+	_ = sess.SetString("azure.name", "John")
+	if ok, _ := sess.Exists("azure.email"); ok {
+		t.Error("Exists() of a deleted key = true")
+	}
+	if keys, _ := sess.Keys("azure."); !slices.Equal(keys, []string{"azure.authenticated", "azure.name"}) {
+		t.Errorf("Keys() with pending changes = %v", keys)
+	}
+	if ok, _ := openSession(t, api).Exists("azure.email"); !ok {
+		t.Error("Delete() was applied before Save")
+	}
+
+	_ = sess.Clear()
+	_ = sess.SetBool("okta.authenticated", false)
+	if err := sess.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if keys, _ := openSession(t, api).Keys(""); !slices.Equal(keys, []string{"okta.authenticated"}) {
+		t.Errorf("Keys() after Clear = %v, want [okta.authenticated]", keys)
+	}
+}
+
+func TestSessionChangesAndDiscard(t *testing.T) {
+	api := New(WithSessionValues(map[string]any{"a": "1"}))
+	sess := openSession(t, api)
+
+	_ = sess.SetString("b", "2")
+	_ = sess.Delete("a")
+	_ = sess.Clear()
+	want := []session.Change{
+		{Op: session.OpSet, Key: "b", Value: "2"},
+		{Op: session.OpDelete, Key: "a"},
+		{Op: session.OpClear},
+	}
+	got := sess.Changes()
+	if len(got) != len(want) {
+		t.Fatalf("Changes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Changes()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	sess.Discard()
+	if got := sess.Changes(); len(got) != 0 {
+		t.Errorf("Changes() after Discard = %v, want none", got)
+	}
+	if v, _ := sess.GetString("a"); v != "1" {
+		t.Errorf("GetString() after Discard = %q, want 1", v)
+	}
+}
+
+func TestSessionSaveIfUnchangedConflict(t *testing.T) {
+	api := New()
+	first := openSession(t, api)
+	second := openSession(t, api)
+
+	_ = first.SetInt("count", 1)
+	if err := first.SaveIfUnchanged(); err != nil {
+		t.Fatalf("SaveIfUnchanged() error = %v", err)
+	}
+	if first.Version() != 1 {
+		t.Errorf("Version() = %d, want 1", first.Version())
+	}
+
+	_ = second.SetInt("count", 2)
+	err := second.SaveIfUnchanged()
+	var conflict *session.ConflictError
+	if !errors.Is(err, session.ErrConflict) || !errors.As(err, &conflict) {
+		t.Fatalf("SaveIfUnchanged() error = %v, want a *ConflictError", err)
+	}
+	if conflict.Expected != 0 || conflict.Actual != 1 || conflict.ID != second.ID() {
+		t.Errorf("ConflictError = %+v, want expected 0 and actual 1", conflict)
+	}
+	if len(second.Changes()) != 1 {
+		t.Error("SaveIfUnchanged() discarded the changelog on conflict")
+	}
+	if v, _ := openSession(t, api).GetInt("count"); v != 1 {
+		t.Errorf("count = %d after conflict, want 1", v)
+	}
+
+	if err := second.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	_ = second.SetInt("count", 2)
+	if err := second.SaveIfUnchanged(); err != nil {
+		t.Fatalf("SaveIfUnchanged() after Reload error = %v", err)
+	}
+	if second.Version() != 2 {
+		t.Errorf("Version() = %d, want 2", second.Version())
+	}
+}
+
+func TestSessionSaveWithoutChangesObservesVersion(t *testing.T) {
+	api := New()
+	first := openSession(t, api)
+	second := openSession(t, api)
+
+	_ = second.SetString("k", "v")
+	_ = second.Save()
+	if err := first.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if first.Version() != 1 {
+		t.Errorf("Version() after saving without changes = %d, want 1", first.Version())
+	}
+	_ = first.SetString("k", "w")
+	if err := first.SaveIfUnchanged(); err != nil {
+		t.Errorf("SaveIfUnchanged() error = %v", err)
+	}
+}
+
+func TestSessionRegenerate(t *testing.T) {
+	api := New(WithSessionValues(map[string]any{"uid": "jdoe"}))
+	sess := openSession(t, api)
+	oldID := sess.ID()
+
+	if err := sess.Regenerate(); err != nil {
+		t.Fatalf("Regenerate() error = %v", err)
+	}
+	if sess.ID() == oldID {
+		t.Error("Regenerate() did not change the ID")
+	}
+	if v, _ := sess.GetString("uid"); v != "jdoe" {
+		t.Errorf("GetString() after Regenerate = %q, want jdoe", v)
+	}
+
+	// The previous ID no longer identifies the session.
+	old := openSession(t, api, session.WithRequest(requestWithSession(oldID)))
+	if old.ID() == oldID || old.ID() == sess.ID() {
+		t.Errorf("session for the previous ID = %s, want a new session", old.ID())
+	}
+	if v, _ := old.GetString("uid"); v != "" {
+		t.Errorf("session for the previous ID has value %q", v)
+	}
+	current := openSession(t, api, session.WithRequest(requestWithSession(sess.ID())))
+	if v, _ := current.GetString("uid"); v != "jdoe" {
+		t.Errorf("session for the new ID has value %q, want jdoe", v)
+	}
+}
+
+func TestSessionInvalidate(t *testing.T) {
+	api := New(WithSessionValues(map[string]any{"uid": "jdoe"}))
+	sess := openSession(t, api)
+	_ = sess.SetString("pending", "x")
+	if err := sess.Invalidate(); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	if _, err := sess.GetString("uid"); !errors.Is(err, session.ErrInvalidated) {
+		t.Errorf("GetString() after Invalidate error = %v, want ErrInvalidated", err)
+	}
+	if err := sess.Save(); !errors.Is(err, session.ErrInvalidated) {
+		t.Errorf("Save() after Invalidate error = %v, want ErrInvalidated", err)
+	}
+
+	next := openSession(t, api)
+	if next.ID() == sess.ID() {
+		t.Error("Session() after Invalidate returned the invalidated session")
+	}
+	if ok, _ := next.Exists("uid"); ok {
+		t.Error("new session contains values of the invalidated session")
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	clock := newTestClock()
+	t0 := clock.Now()
+	api := New(WithClock(clock.Now), WithSessionLifetime(time.Hour, 10*time.Minute))
+	sess := openSession(t, api)
+
+	if got := sess.CreatedAt(); !got.Equal(t0) {
+		t.Errorf("CreatedAt() = %v, want %v", got, t0)
+	}
+	if got := sess.ExpiresAt(); !got.Equal(t0.Add(time.Hour)) {
+		t.Errorf("ExpiresAt() = %v, want %v", got, t0.Add(time.Hour))
+	}
+	if got := sess.IdleExpiresAt(); !got.Equal(t0.Add(10 * time.Minute)) {
+		t.Errorf("IdleExpiresAt() = %v, want %v", got, t0.Add(10*time.Minute))
+	}
+
+	// Saving counts as activity.
+	clock.Advance(5 * time.Minute)
+	_ = sess.Save()
+	if got := sess.LastAccessedAt(); !got.Equal(t0.Add(5 * time.Minute)) {
+		t.Errorf("LastAccessedAt() = %v, want %v", got, t0.Add(5*time.Minute))
+	}
+	clock.Advance(9 * time.Minute)
+	if _, err := sess.GetString("k"); err != nil {
+		t.Errorf("GetString() before idle expiry error = %v", err)
+	}
+	clock.Advance(time.Minute)
+	if _, err := sess.GetString("k"); !errors.Is(err, session.ErrInvalidated) {
+		t.Errorf("GetString() after idle expiry error = %v, want ErrInvalidated", err)
+	}
+
+	sess = openSession(t, api)
+	if err := sess.SetIdleTimeout(0); err != nil {
+		t.Fatalf("SetIdleTimeout() error = %v", err)
+	}
+	if !sess.IdleExpiresAt().IsZero() {
+		t.Errorf("IdleExpiresAt() without idle timeout = %v, want zero", sess.IdleExpiresAt())
+	}
+	if err := sess.SetExpiresAt(clock.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("SetExpiresAt() error = %v", err)
+	}
+	if _, err := sess.GetString("k"); !errors.Is(err, session.ErrInvalidated) {
+		t.Errorf("GetString() after SetExpiresAt in the past error = %v, want ErrInvalidated", err)
+	}
+}
+
+func TestSessionWithRequest(t *testing.T) {
+	api := New(WithSessionValues(map[string]any{"uid": "jdoe"}))
+	def := openSession(t, api)
+
+	plain := openSession(t, api, session.WithRequest(httptest.NewRequest(http.MethodGet, "/", nil)))
+	if plain.ID() != def.ID() {
+		t.Error("request without a session cookie did not return the default session")
+	}
+	byID := openSession(t, api, session.WithRequest(requestWithSession(def.ID())))
+	if byID.ID() != def.ID() {
+		t.Error("request with the ID of the default session returned another session")
+	}
+
+	other := openSession(t, api, session.WithRequest(requestWithSession("unknown")))
+	if other.ID() == def.ID() || other.ID() == "unknown" {
+		t.Errorf("request with an unknown ID returned session %s, want a new session", other.ID())
+	}
+	_ = other.SetString("uid", "asmith")
+	_ = other.Save()
+	if v, _ := openSession(t, api).GetString("uid"); v != "jdoe" {
+		t.Errorf("default session value = %q, want jdoe", v)
+	}
+	again := openSession(t, api, session.WithRequest(requestWithSession(other.ID())))
+	if v, _ := again.GetString("uid"); v != "asmith" {
+		t.Errorf("session value = %q, want asmith", v)
+	}
+}
+
+func requestWithSession(id string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: id})
+	return req
+}
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// NewGroup returns a Grouper registering routes with parent under the path
+// prefix, wrapped with mw. It is intended for implementations of Grouper.Group,
+// but can also be used to group routes of any Router. An empty prefix registers
+// routes with unchanged paths.
+//
+// The returned Grouper also implements Registry. If parent does not implement
+// Registry, Replace and Unregister return an error wrapping ErrUnsupported and
+// Routes returns nil.
+func NewGroup(parent Router, prefix string, mw ...Middleware) Grouper {
+	return &group{parent: parent, prefix: strings.TrimSuffix(prefix, "/"), middleware: slices.Clone(mw)}
+}
+
+var _ Registry = (*group)(nil)
+
+type group struct {
+	parent Router
+	prefix string
+
+	mu         sync.RWMutex
+	middleware []Middleware
+}
+
+func (g *group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) error {
+	p, err := g.pattern(pattern)
+	if err != nil {
+		return err
+	}
+	return g.parent.HandleFunc(p, g.wrap(handler))
+}
+
+func (g *group) Replace(pattern string, handler func(http.ResponseWriter, *http.Request)) error {
+	p, err := g.pattern(pattern)
+	if err != nil {
+		return err
+	}
+	reg, ok := g.parent.(Registry)
+	if !ok {
+		return fmt.Errorf("unable to replace route '%s': %w", pattern, ErrUnsupported)
+	}
+	return reg.Replace(p, g.wrap(handler))
+}
+
+func (g *group) Unregister(pattern string) error {
+	p, err := g.pattern(pattern)
+	if err != nil {
+		return err
+	}
+	reg, ok := g.parent.(Registry)
+	if !ok {
+		return fmt.Errorf("unable to unregister route '%s': %w", pattern, ErrUnsupported)
+	}
+	return reg.Unregister(p)
+}
+
+func (g *group) Routes() []Route {
+	if reg, ok := g.parent.(Registry); ok {
+		return reg.Routes()
+	}
+	return nil
+}
+
+func (g *group) Use(mw ...Middleware) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.middleware = append(g.middleware, mw...)
+}
+
+func (g *group) Group(prefix string, mw ...Middleware) Grouper {
+	return NewGroup(g, prefix, mw...)
+}
+
+// wrap returns handler wrapped with the middleware of the group.
+func (g *group) wrap(handler func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return Chain(http.HandlerFunc(handler), g.middleware...).ServeHTTP
+}
+
+// pattern returns pattern with the prefix of the group prepended to its path.
+func (g *group) pattern(pattern string) (string, error) {
+	p, err := ParsePattern(pattern)
+	if err != nil {
+		return "", err
+	}
+	if g.prefix == "" {
+		return pattern, nil
+	}
+	if !strings.HasPrefix(g.prefix, "/") {
+		return "", fmt.Errorf("group prefix '%s' must start with '/'", g.prefix)
+	}
+	p.Path = g.prefix + p.Path
+	return p.String(), nil
+}
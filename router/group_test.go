@@ -0,0 +1,137 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// muxRouter is a Router implementing neither Grouper nor Registry.
+type muxRouter struct {
+	mux      *http.ServeMux
+	patterns []string
+}
+
+func newMuxRouter() *muxRouter {
+	return &muxRouter{mux: http.NewServeMux()}
+}
+
+func (r *muxRouter) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) error {
+	r.mux.HandleFunc(pattern, handler)
+	r.patterns = append(r.patterns, pattern)
+	return nil
+}
+
+// tag returns middleware appending name to the X-Trace header of the response.
+func tag(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Add("X-Trace", name)
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
+
+func serve(r *muxRouter, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestGroupPrefix(t *testing.T) {
+	r := newMuxRouter()
+	api := NewGroup(r, "/api/")
+	ok := func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(req.PathValue("id")))
+	}
+	if err := api.HandleFunc("GET /users/{id}", ok); err != nil {
+		t.Fatalf("HandleFunc() error = %v", err)
+	}
+	if err := api.Group("/v1").HandleFunc("POST example.com/items/{id}", ok); err != nil {
+		t.Fatalf("HandleFunc() error = %v", err)
+	}
+	if err := NewGroup(r, "").HandleFunc("/health", ok); err != nil {
+		t.Fatalf("HandleFunc() error = %v", err)
+	}
+
+	want := []string{"GET /api/users/{id}", "POST example.com/api/v1/items/{id}", "/health"}
+	if !slices.Equal(r.patterns, want) {
+		t.Errorf("patterns = %q, want %q", r.patterns, want)
+	}
+	if rec := serve(r, http.MethodGet, "/api/users/42"); rec.Body.String() != "42" {
+		t.Errorf("GET /api/users/42 = %d %q, want %q", rec.Code, rec.Body, "42")
+	}
+
+	if err := NewGroup(r, "api").HandleFunc("/x", ok); err == nil {
+		t.Error("HandleFunc() with prefix without leading slash succeeded")
+	}
+	if err := api.HandleFunc("GET users", ok); err == nil {
+		t.Error("HandleFunc() with invalid pattern succeeded")
+	}
+}
+
+func TestGroupMiddlewareOrder(t *testing.T) {
+	r := newMuxRouter()
+	ok := func(rw http.ResponseWriter, req *http.Request) {}
+	outer := NewGroup(r, "", tag("outer"))
+	if err := outer.HandleFunc("/before", ok); err != nil {
+		t.Fatalf("HandleFunc() error = %v", err)
+	}
+	outer.Use(tag("used"))
+	inner := outer.Group("/inner", tag("inner1"), tag("inner2"))
+	inner.Use(tag("inner-used"))
+	if err := inner.HandleFunc("/x", ok); err != nil {
+		t.Fatalf("HandleFunc() error = %v", err)
+	}
+	if err := outer.HandleFunc("/after", ok); err != nil {
+		t.Fatalf("HandleFunc() error = %v", err)
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		// Middleware added with Use does not apply to routes registered before.
+		{"/before", "outer"},
+		{"/after", "outer,used"},
+		{"/inner/x", "outer,used,inner1,inner2,inner-used"},
+	}
+	for _, tt := range tests {
+		rec := serve(r, http.MethodGet, tt.target)
+		if got := strings.Join(rec.Header().Values("X-Trace"), ","); got != tt.want {
+			t.Errorf("middleware of %s = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestGroupWithoutRegistry(t *testing.T) {
+	g := NewGroup(newMuxRouter(), "/api")
+	reg, ok := g.(Registry)
+	if !ok {
+		t.Fatal("group does not implement Registry")
+	}
+	ok2 := func(http.ResponseWriter, *http.Request) {}
+	if err := reg.Replace("/x", ok2); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Replace() error = %v, want ErrUnsupported", err)
+	}
+	if err := reg.Unregister("/x"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Unregister() error = %v, want ErrUnsupported", err)
+	}
+	if routes := reg.Routes(); routes != nil {
+		t.Errorf("Routes() = %v, want nil", routes)
+	}
+}
+
+func TestChain(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Add("X-Trace", "handler")
+	}), tag("a"), tag("b"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.Join(rec.Header().Values("X-Trace"), ","); got != "a,b,handler" {
+		t.Errorf("Chain() order = %q, want %q", got, "a,b,handler")
+	}
+}
@@ -0,0 +1,268 @@
+// Package middleware provides router.Middleware for concerns shared by the
+// handlers of service extensions: requiring an authenticated session, CORS,
+// request size limits, panic recovery and request IDs.
+//
+// Example:
+//
+//	r := router.NewGroup(api.Router(), "")
+//	r.Use(middleware.Recover(api.Logger()), middleware.RequestID())
+//	users := r.Group("/users",
+//		middleware.RequireAuthenticated(api, "azure", "okta"),
+//		middleware.MaxBytes(64<<10),
+//	)
+//	_ = users.HandleFunc("GET /{id}", getUser)
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/strata-io/service-extension/log"
+	"github.com/strata-io/service-extension/orchestrator"
+	"github.com/strata-io/service-extension/router"
+	"github.com/strata-io/service-extension/session"
+)
+
+// RequireAuthenticated returns middleware that only calls the next handler if
+// the session of the request is authenticated with at least one of the given
+// identity providers, i.e. the session value "<idp>.authenticated" is "true".
+// Otherwise it responds with 401 Unauthorized, or with 500 Internal Server
+// Error if the session cannot be retrieved.
+func RequireAuthenticated(api orchestrator.Orchestrator, idps ...string) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			sess, err := api.Session(session.WithRequest(req))
+			if err != nil {
+				api.Logger(log.WithRequest(req)).Error("se", "unable to retrieve session", "error", err.Error())
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			for _, idp := range idps {
+				if authenticated, _ := sess.GetString(idp + ".authenticated"); authenticated == "true" {
+					next.ServeHTTP(rw, req)
+					return
+				}
+			}
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		})
+	}
+}
+
+// CORSOptions configure the CORS middleware.
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests, e.g.
+	// "https://app.example.com", or "*" to allow all origins.
+	AllowedOrigins []string
+
+	// AllowedMethods are the methods allowed in cross-origin requests. Defaults
+	// to GET, HEAD and POST.
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers allowed in cross-origin requests.
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers exposed to cross-origin requests.
+	ExposedHeaders []string
+
+	// AllowCredentials allows cross-origin requests with credentials, e.g.
+	// cookies. It cannot be combined with the "*" origin, for which credentials
+	// are never allowed.
+	AllowCredentials bool
+
+	// MaxAge is the number of seconds preflight responses may be cached. Zero
+	// omits the Access-Control-Max-Age header.
+	MaxAge int
+}
+
+// CORS returns middleware implementing Cross-Origin Resource Sharing with the
+// given options. Preflight requests are answered with 204 No Content without
+// calling the next handler. As preflight requests use the OPTIONS method, the
+// middleware must be applied to routes whose pattern matches OPTIONS requests,
+// e.g. "/api/" rather than "GET /api/".
+func CORS(opts CORSOptions) router.Middleware {
+	if len(opts.AllowedMethods) == 0 {
+		opts.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	allowAll := slices.Contains(opts.AllowedOrigins, "*")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			h := rw.Header()
+			h.Add("Vary", "Origin")
+			if origin == "" || (!allowAll && !slices.Contains(opts.AllowedOrigins, origin)) {
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			if allowAll {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				if opts.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if req.Method != http.MethodOptions || req.Header.Get("Access-Control-Request-Method") == "" {
+				if len(opts.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(opts.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", strings.Join(opts.AllowedMethods, ", "))
+			if len(opts.AllowedHeaders) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
+			}
+			if opts.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// MaxBytes returns middleware limiting request bodies to n bytes. Requests
+// declaring a larger Content-Length are rejected with 413 Request Entity Too
+// Large; reading beyond n bytes of other bodies fails with an
+// *http.MaxBytesError.
+func MaxBytes(n int64) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.ContentLength > n {
+				http.Error(rw, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			req.Body = http.MaxBytesReader(rw, req.Body, n)
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// Recover returns middleware that recovers from panics in the next handler,
+// logs them with their stack trace to logger and responds with 500 Internal
+// Server Error. If the handler had already started writing the response, the
+// panic is only logged, since the status can no longer be changed. Panics with
+// http.ErrAbortHandler are not recovered, so that handlers can still abort
+// responses.
+func Recover(logger log.Logger) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			tw := &trackingWriter{ResponseWriter: rw}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				logger.Error(
+					"se", "recovered from panic in handler",
+					"method", req.Method,
+					"path", req.URL.Path,
+					"panic", fmt.Sprint(rec),
+					"stack", string(debug.Stack()),
+				)
+				if !tw.written {
+					http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(tw, req)
+		})
+	}
+}
+
+// trackingWriter is an http.ResponseWriter recording whether the response has
+// been started. Unwrap exposes the underlying ResponseWriter to
+// http.ResponseController.
+type trackingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *trackingWriter) WriteHeader(code int) {
+	// Informational responses do not start the final response.
+	if code >= 200 {
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *trackingWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *trackingWriter) Flush() {
+	w.written = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *trackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// RequestIDHeader is the header carrying the ID of a request.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestID returns middleware that assigns an ID to each request. The ID of an
+// incoming X-Request-ID header is used if it consists of at most 128
+// printable ASCII characters; otherwise a random ID is generated. The ID is
+// set on the response header and can be retrieved by handlers with
+// RequestIDFromContext.
+func RequestID() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			rw.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(rw, req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the ID assigned to the request by the RequestID
+// middleware, or an empty string if there is none.
+//
+// Example:
+//
+//	logger.Info("se", "creating user", "requestID", middleware.RequestIDFromContext(req.Context()))
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
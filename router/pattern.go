@@ -0,0 +1,121 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Pattern is a parsed route pattern. See Router for the pattern syntax.
+type Pattern struct {
+	// Method is the method matched by the pattern, or empty if the pattern
+	// matches all methods.
+	Method string
+
+	// Host is the host matched by the pattern, or empty if the pattern matches
+	// all hosts.
+	Host string
+
+	// Path is the path of the pattern, including wildcard segments.
+	Path string
+
+	// Wildcards are the names of the wildcard segments of the path, in order.
+	Wildcards []string
+}
+
+// ParsePattern parses and validates a route pattern, e.g. to report invalid
+// patterns before registering them. An error is returned if the pattern is
+// invalid.
+//
+// Example:
+//
+//	p, err := router.ParsePattern("POST login.example.com/users/{id}")
+//	// p.Method == "POST", p.Host == "login.example.com", p.Wildcards == []string{"id"}
+func ParsePattern(s string) (Pattern, error) {
+	var p Pattern
+	rest := strings.TrimLeft(s, " \t")
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		p.Method, rest = rest[:i], strings.TrimLeft(rest[i:], " \t")
+		if !isToken(p.Method) {
+			return Pattern{}, fmt.Errorf("invalid method '%s' in pattern '%s'", p.Method, s)
+		}
+	}
+	i := strings.IndexByte(rest, '/')
+	if i < 0 {
+		return Pattern{}, fmt.Errorf("pattern '%s' has no path starting with '/'", s)
+	}
+	p.Host, p.Path = rest[:i], rest[i:]
+	if strings.ContainsAny(p.Host, "{}") {
+		return Pattern{}, fmt.Errorf("host of pattern '%s' must not contain wildcards", s)
+	}
+
+	segments := strings.Split(p.Path[1:], "/")
+	seen := make(map[string]bool)
+	for n, seg := range segments {
+		last := n == len(segments)-1
+		if !strings.ContainsAny(seg, "{}") {
+			continue
+		}
+		if seg[0] != '{' || seg[len(seg)-1] != '}' || strings.Count(seg, "{") != 1 || strings.Count(seg, "}") != 1 {
+			return Pattern{}, fmt.Errorf("wildcard in pattern '%s' must be an entire path segment", s)
+		}
+		name := seg[1 : len(seg)-1]
+		if name == "$" {
+			if !last {
+				return Pattern{}, fmt.Errorf("{$} in pattern '%s' must be at the end", s)
+			}
+			continue
+		}
+		if strings.HasSuffix(name, "...") {
+			if !last {
+				return Pattern{}, fmt.Errorf("{%s} in pattern '%s' must be at the end", name, s)
+			}
+			name = strings.TrimSuffix(name, "...")
+		}
+		if !isIdentifier(name) {
+			return Pattern{}, fmt.Errorf("invalid wildcard name '%s' in pattern '%s'", name, s)
+		}
+		if seen[name] {
+			return Pattern{}, fmt.Errorf("duplicate wildcard name '%s' in pattern '%s'", name, s)
+		}
+		seen[name] = true
+		p.Wildcards = append(p.Wildcards, name)
+	}
+	return p, nil
+}
+
+// String returns the pattern in canonical form, with a single space between
+// the method and the host and path.
+func (p Pattern) String() string {
+	if p.Method == "" {
+		return p.Host + p.Path
+	}
+	return p.Method + " " + p.Host + p.Path
+}
+
+// isToken reports whether s is an HTTP token as defined by RFC 9110.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentifier reports whether s is a valid Go identifier, as required for
+// wildcard names.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    Pattern
+		str     string
+	}{
+		{"/users", Pattern{Path: "/users"}, "/users"},
+		{"GET /users/{id}", Pattern{Method: "GET", Path: "/users/{id}", Wildcards: []string{"id"}}, "GET /users/{id}"},
+		{"  POST \t /x", Pattern{Method: "POST", Path: "/x"}, "POST /x"},
+		{"login.example.com/", Pattern{Host: "login.example.com", Path: "/"}, "login.example.com/"},
+		{
+			"DELETE api.example.com/users/{id}/sessions/{rest...}",
+			Pattern{Method: "DELETE", Host: "api.example.com", Path: "/users/{id}/sessions/{rest...}", Wildcards: []string{"id", "rest"}},
+			"DELETE api.example.com/users/{id}/sessions/{rest...}",
+		},
+		{"GET /users/{$}", Pattern{Method: "GET", Path: "/users/{$}"}, "GET /users/{$}"},
+		{"GET /{a}/{b_2}", Pattern{Method: "GET", Path: "/{a}/{b_2}", Wildcards: []string{"a", "b_2"}}, "GET /{a}/{b_2}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := ParsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParsePattern() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePattern() = %+v, want %+v", got, tt.want)
+			}
+			if s := got.String(); s != tt.str {
+				t.Errorf("String() = %q, want %q", s, tt.str)
+			}
+		})
+	}
+}
+
+func TestParsePatternInvalid(t *testing.T) {
+	for _, pattern := range []string{
+		"",
+		"users",
+		"GET",
+		"G(T /x",
+		"GET example.com",
+		"{host}/x",
+		"/x{id}",
+		"/{id}x",
+		"/{a}{b}",
+		"/{}",
+		"/{1id}",
+		"/{a-b}",
+		"/{id}/{id}",
+		"/{rest...}/x",
+		"/{$}/x",
+	} {
+		t.Run(pattern, func(t *testing.T) {
+			if p, err := ParsePattern(pattern); err == nil {
+				t.Errorf("ParsePattern() = %+v, want an error", p)
+			}
+		})
+	}
+}
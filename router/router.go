@@ -1,10 +1,126 @@
 package router
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+)
 
 // Router is used to register HTTP endpoints on the Orchestrator.
+//
+// Patterns follow the syntax of http.ServeMux in Go 1.22 and later:
+//
+//	[METHOD ][HOST]/[PATH]
+//
+// A pattern with a method only matches requests with that method; a GET
+// pattern also matches HEAD requests. A pattern with a host only matches
+// requests for that host. Path segments of the form {name} match a single
+// segment, and a final segment of the form {name...} matches the remainder of
+// the path. Matched segments are available to handlers with req.PathValue. A
+// pattern ending in a slash matches all paths with that prefix, unless it ends
+// in {$}, which matches only the path ending in the slash. When a request path
+// matches registered patterns, but none for its method, the Router responds
+// with 405 Method Not Allowed and an Allow header listing the methods that are
+// registered.
+//
+// Routers returned by the Orchestrator may support middleware and route groups
+// through the optional Grouper interface, and replacing, removing and listing
+// routes through the optional Registry interface. Use a type assertion to find
+// out whether an operation is supported.
+//
+// Example:
+//
+//	_ = api.Router().HandleFunc("GET /users/{id}", func(rw http.ResponseWriter, req *http.Request) {
+//		id := req.PathValue("id")
+//		// ...
+//	})
+//	_ = api.Router().HandleFunc("POST /users/{id}/sessions/{rest...}", revokeSessions)
 type Router interface {
 	// HandleFunc registers the handler function for the given pattern in the
-	// Router. An error is returned if a given route is already registered.
+	// Router. An error is returned if a given route is already registered, the
+	// pattern is invalid or it conflicts with a registered pattern.
 	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) error
 }
+
+// Grouper is implemented by routers that support middleware and route groups.
+//
+// Example:
+//
+//	if g, ok := api.Router().(router.Grouper); ok {
+//		g.Use(middleware.Recover(api.Logger()))
+//	}
+type Grouper interface {
+	Router
+
+	// Use appends middleware to the chain applied to handlers registered with
+	// this Router afterwards. Routes registered before Use are not affected, nor
+	// are routes registered by other service extensions.
+	Use(mw ...Middleware)
+
+	// Group returns a Router registering routes under the path prefix, e.g.
+	// "/api", with the middleware of this Router followed by mw. Middleware added
+	// to the group with Use only applies to routes of the group.
+	//
+	// Example:
+	//
+	//	api := r.Group("/api", middleware.RequireAuthenticated(orch, "azure"))
+	//	_ = api.HandleFunc("GET /users/{id}", getUser) // matches GET /api/users/{id}
+	Group(prefix string, mw ...Middleware) Grouper
+}
+
+// Registry is implemented by routers that support replacing, removing and
+// listing routes.
+//
+// Example:
+//
+//	if reg, ok := api.Router().(router.Registry); ok {
+//		err = reg.Replace("GET /callback", callback)
+//	}
+type Registry interface {
+	Router
+
+	// Replace registers the handler function for the given pattern, replacing
+	// the handler of the route if it is already registered, e.g. when a service
+	// extension is reloaded. An error is returned if the pattern is invalid, it
+	// conflicts with another registered pattern or the route is owned by a
+	// different service extension.
+	Replace(pattern string, handler func(http.ResponseWriter, *http.Request)) error
+
+	// Unregister removes the route registered for the given pattern. Patterns are
+	// compared in canonical form, so "GET  /x" unregisters "GET /x". An error is
+	// returned if the route is not registered or is owned by a different service
+	// extension.
+	Unregister(pattern string) error
+
+	// Routes returns all routes registered on the Orchestrator, including those
+	// of other service extensions, ordered by pattern.
+	Routes() []Route
+}
+
+// ErrUnsupported is returned when an operation is not supported by a Router.
+// Implementations may wrap ErrUnsupported, so callers should use errors.Is to
+// check for it.
+var ErrUnsupported = errors.New("operation not supported by router")
+
+// Route is a registered route.
+type Route struct {
+	// Pattern is the pattern of the route in canonical form, see
+	// Pattern.String.
+	Pattern string
+
+	// Owner identifies the service extension that registered the route, e.g. the
+	// name of its app.
+	Owner string
+}
+
+// Middleware wraps a handler, e.g. to check the session or recover from
+// panics before or after calling it.
+type Middleware func(http.Handler) http.Handler
+
+// Chain returns h wrapped with mw. The first middleware is the outermost, i.e.
+// it is called first.
+func Chain(h http.Handler, mw ...Middleware) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
@@ -0,0 +1,417 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileStoreMagic is the header of every file written by FileStore. It is also
+// used as additional authenticated data when encrypting the file.
+const fileStoreMagic = "sxsecret1"
+
+// FileStoreKeySize is the size in bytes of the key used to encrypt a FileStore.
+const FileStoreKeySize = 32
+
+// FileStoreOptions are the options used to configure a FileStore.
+type FileStoreOptions struct {
+	// MaxVersions is the number of versions retained per key. Older versions are
+	// discarded when a new version is put. Defaults to 10.
+	MaxVersions int
+
+	// Now returns the current time, used as the creation time of versions.
+	// Defaults to time.Now.
+	Now func() time.Time
+}
+
+// FileStoreOption is an option used to configure a FileStore.
+type FileStoreOption func(*FileStoreOptions)
+
+// WithMaxVersions configures the number of versions retained per key.
+func WithMaxVersions(n int) FileStoreOption {
+	return func(o *FileStoreOptions) {
+		o.MaxVersions = n
+	}
+}
+
+// WithFileStoreClock configures the function used to determine the current
+// time.
+func WithFileStoreClock(now func() time.Time) FileStoreOption {
+	return func(o *FileStoreOptions) {
+		o.Now = now
+	}
+}
+
+// fileStoreVersion is a version of a secret as stored in the file.
+type fileStoreVersion struct {
+	Value           string    `json:"value"`
+	Version         string    `json:"version"`
+	PreviousVersion string    `json:"previousVersion,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// fileStoreContents is the decrypted content of the file.
+type fileStoreContents struct {
+	Secrets map[string][]fileStoreVersion `json:"secrets"`
+	// LastVersions holds the last version number assigned to every key that was
+	// ever put, including deleted keys, so that versions are never reused.
+	LastVersions map[string]uint64 `json:"lastVersions"`
+}
+
+type fileStoreWatch struct {
+	key string
+	fn  func(old, new Secret)
+}
+
+// FileStore is a Lookuper, Versioned and Writer storing secrets in a single file
+// encrypted with AES-256-GCM. It is intended for local development and tests rather than
+// production use: the whole file is rewritten on every write, and a file must
+// not be opened by more than one FileStore at a time.
+//
+// Example:
+//
+//	key, _ := base64.StdEncoding.DecodeString(os.Getenv("SECRETS_KEY"))
+//	store, err := secret.OpenFileStore("secrets.enc", key)
+//	if err != nil {
+//		return err
+//	}
+//	_ = store.Put("formfill/"+uid, generatedPassword)
+type FileStore struct {
+	path string
+	aead cipher.AEAD
+	opts FileStoreOptions
+
+	mu      sync.RWMutex
+	secrets map[string][]fileStoreVersion
+	// lastVersions holds the last version number assigned to every key.
+	lastVersions map[string]uint64
+	watches      map[*fileStoreWatch]struct{}
+	// notifications calls watch functions in the order of the versions.
+	notifications dispatcher
+}
+
+var (
+	_ Lookuper  = (*FileStore)(nil)
+	_ Versioned = (*FileStore)(nil)
+	_ Writer    = (*FileStore)(nil)
+)
+
+// NewFileStoreKey generates a random key suitable for OpenFileStore.
+func NewFileStoreKey() ([]byte, error) {
+	key := make([]byte, FileStoreKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("unable to generate secret store key: %w", err)
+	}
+	return key, nil
+}
+
+// OpenFileStore opens the FileStore at path, decrypting it with the given key
+// of FileStoreKeySize bytes. If the file does not exist, an empty FileStore is
+// returned and the file is created on the first write.
+func OpenFileStore(path string, key []byte, opts ...FileStoreOption) (*FileStore, error) {
+	o := FileStoreOptions{
+		MaxVersions: 10,
+		Now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(key) != FileStoreKeySize {
+		return nil, fmt.Errorf("secret store key must be %d bytes, got %d", FileStoreKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{
+		path:         path,
+		aead:         aead,
+		opts:         o,
+		secrets:      make(map[string][]fileStoreVersion),
+		lastVersions: make(map[string]uint64),
+		watches:      make(map[*fileStoreWatch]struct{}),
+	}
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("unable to open secret store '%s': %w", path, err)
+	}
+	return s, nil
+}
+
+// Get retrieves the current version of the key as a string. If the key does
+// not exist, nil is returned.
+func (s *FileStore) Get(key string) any {
+	v, ok := s.Lookup(key)
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+// GetString retrieves the current version of the key. If the key does not
+// exist, an empty string is returned.
+func (s *FileStore) GetString(key string) string {
+	v, _ := s.Lookup(key)
+	return v
+}
+
+// Lookup retrieves the current version of the key. The returned bool is false
+// if the key does not exist.
+func (s *FileStore) Lookup(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.current(key)
+	return v.Value, ok
+}
+
+// GetVersion retrieves the given version of the key. An empty version retrieves
+// the current version.
+func (s *FileStore) GetVersion(key, version string) (Secret, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if version == "" {
+		v, ok := s.current(key)
+		if !ok {
+			return Secret{}, fmt.Errorf("secret '%s': %w", key, ErrNotFound)
+		}
+		return v.secret(key), nil
+	}
+	for _, v := range s.secrets[key] {
+		if v.Version == version {
+			return v.secret(key), nil
+		}
+	}
+	return Secret{}, fmt.Errorf("secret '%s' version '%s': %w", key, version, ErrNotFound)
+}
+
+// Metadata retrieves the metadata of the current version of the key.
+func (s *FileStore) Metadata(key string) (Metadata, error) {
+	sec, err := s.GetVersion(key, "")
+	if err != nil {
+		return Metadata{}, err
+	}
+	return sec.Metadata, nil
+}
+
+// Watch registers fn to be called every time the key is put or deleted. The
+// watch functions of a FileStore are called one at a time, in the order of the
+// changes, by the goroutine calling Put or Delete before it returns. Changes
+// made while watch functions are running, including changes made by a watch
+// function, are instead delivered by the goroutine already running them, so a
+// watch function may call Put or Delete without deadlocking.
+func (s *FileStore) Watch(key string, fn func(old, new Secret)) (func(), error) {
+	w := &fileStoreWatch{key: key, fn: fn}
+	s.mu.Lock()
+	s.watches[w] = struct{}{}
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		delete(s.watches, w)
+		s.mu.Unlock()
+	}, nil
+}
+
+// Put stores value as a new version of the key and writes the file. Versions
+// are numbered from "1" and are never reused for a key, even if it is deleted
+// and put again.
+func (s *FileStore) Put(key, value string) error {
+	s.mu.Lock()
+	old, ok := s.current(key)
+	n := s.lastVersions[key] + 1
+	v := fileStoreVersion{
+		Value:           value,
+		Version:         strconv.FormatUint(n, 10),
+		PreviousVersion: old.Version,
+		CreatedAt:       s.opts.Now(),
+	}
+	versions := append(s.secrets[key], v)
+	if s.opts.MaxVersions > 0 && len(versions) > s.opts.MaxVersions {
+		versions = versions[len(versions)-s.opts.MaxVersions:]
+	}
+	prev := s.secrets[key]
+	s.secrets[key] = versions
+	s.lastVersions[key] = n
+	if err := s.save(); err != nil {
+		s.secrets[key] = prev
+		s.lastVersions[key] = n - 1
+		s.mu.Unlock()
+		return err
+	}
+
+	var oldSecret Secret
+	if ok {
+		oldSecret = old.secret(key)
+	}
+	s.notify(key, oldSecret, v.secret(key))
+	s.mu.Unlock()
+	s.notifications.run()
+	return nil
+}
+
+// Delete removes all versions of the key and writes the file.
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	old, ok := s.current(key)
+	if !ok {
+		s.mu.Unlock()
+		return nil
+	}
+	prev := s.secrets[key]
+	delete(s.secrets, key)
+	if err := s.save(); err != nil {
+		s.secrets[key] = prev
+		s.mu.Unlock()
+		return err
+	}
+	s.notify(key, old.secret(key), Secret{})
+	s.mu.Unlock()
+	s.notifications.run()
+	return nil
+}
+
+// List returns the keys that begin with the given prefix, in lexicographical
+// order.
+func (s *FileStore) List(prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0)
+	for key := range s.secrets {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// notify queues calls to the watch functions of key. The caller must hold s.mu,
+// so that calls are queued in the order of the changes, and run
+// s.notifications after releasing it.
+func (s *FileStore) notify(key string, old, new Secret) {
+	for w := range s.watches {
+		if w.key == key {
+			s.notifications.enqueue(func() {
+				if s.watching(w) {
+					w.fn(old, new)
+				}
+			})
+		}
+	}
+}
+
+// watching reports whether w has not been stopped.
+func (s *FileStore) watching(w *fileStoreWatch) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.watches[w]
+	return ok
+}
+
+// current returns the current version of key. The caller must hold s.mu.
+func (s *FileStore) current(key string) (fileStoreVersion, bool) {
+	versions := s.secrets[key]
+	if len(versions) == 0 {
+		return fileStoreVersion{}, false
+	}
+	return versions[len(versions)-1], true
+}
+
+// load reads and decrypts the file.
+func (s *FileStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(data) < len(fileStoreMagic)+nonceSize || string(data[:len(fileStoreMagic)]) != fileStoreMagic {
+		return errors.New("not a secret store file")
+	}
+	nonce := data[len(fileStoreMagic) : len(fileStoreMagic)+nonceSize]
+	ciphertext := data[len(fileStoreMagic)+nonceSize:]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(fileStoreMagic))
+	if err != nil {
+		return errors.New("unable to decrypt secret store, the key may be incorrect")
+	}
+	var contents fileStoreContents
+	if err := json.Unmarshal(plaintext, &contents); err != nil {
+		return err
+	}
+	if contents.Secrets != nil {
+		s.secrets = contents.Secrets
+	}
+	if contents.LastVersions != nil {
+		s.lastVersions = contents.LastVersions
+	}
+	return nil
+}
+
+// save encrypts and writes the file. The file is written next to the existing
+// file and renamed over it, so a failed write leaves the existing file intact.
+// The caller must hold s.mu.
+func (s *FileStore) save() error {
+	plaintext, err := json.Marshal(fileStoreContents{
+		Secrets:      s.secrets,
+		LastVersions: s.lastVersions,
+	})
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data := make([]byte, 0, len(fileStoreMagic)+len(nonce)+len(plaintext)+s.aead.Overhead())
+	data = append(data, fileStoreMagic...)
+	data = append(data, nonce...)
+	data = s.aead.Seal(data, nonce, plaintext, []byte(fileStoreMagic))
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("unable to write secret store '%s': %w", s.path, err)
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("unable to write secret store '%s': %w", s.path, err)
+	}
+	return nil
+}
+
+func (v fileStoreVersion) secret(key string) Secret {
+	return Secret{
+		Metadata: Metadata{
+			Version:         v.Version,
+			PreviousVersion: v.PreviousVersion,
+			CreatedAt:       v.CreatedAt,
+		},
+		Key:   key,
+		Value: v.Value,
+	}
+}
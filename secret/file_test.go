@@ -0,0 +1,102 @@
+package secret
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func openTestFileStore(t *testing.T, path string, key []byte) *FileStore {
+	t.Helper()
+	s, err := OpenFileStore(path, key)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	return s
+}
+
+func TestFileStoreVersionsAreNotReused(t *testing.T) {
+	key, err := NewFileStoreKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	s := openTestFileStore(t, path, key)
+
+	for _, v := range []string{"a", "b"} {
+		if err := s.Put("k", v); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	if err := s.Delete("k"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Put("k", "c"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if md, err := s.Metadata("k"); err != nil || md.Version != "3" || md.PreviousVersion != "" {
+		t.Errorf("Metadata() = %+v, %v, want version 3 without previous version", md, err)
+	}
+	if _, err := s.GetVersion("k", "1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVersion() of deleted version error = %v, want ErrNotFound", err)
+	}
+
+	// The counter survives reopening the file.
+	s = openTestFileStore(t, path, key)
+	if err := s.Delete("k"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Put("k", "d"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	sec, err := s.GetVersion("k", "")
+	if err != nil || sec.Version != "4" || sec.Value != "d" {
+		t.Errorf("GetVersion() = %+v, %v, want version 4 with value d", sec, err)
+	}
+}
+
+func TestFileStoreWrongKey(t *testing.T) {
+	key, _ := NewFileStoreKey()
+	other, _ := NewFileStoreKey()
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := openTestFileStore(t, path, key).Put("k", "v"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := OpenFileStore(path, other); err == nil {
+		t.Error("OpenFileStore() with another key succeeded")
+	}
+}
+
+func TestFileStoreWatchReentrant(t *testing.T) {
+	key, _ := NewFileStoreKey()
+	s := openTestFileStore(t, filepath.Join(t.TempDir(), "secrets.enc"), key)
+
+	var got []string
+	stop, err := s.Watch("k", func(_, sec Secret) {
+		got = append(got, sec.Version)
+		// Putting the key from a watch function must not deadlock, and the new
+		// version must be delivered after the current one.
+		if sec.Value == "a" {
+			if err := s.Put("k", "b"); err != nil {
+				t.Errorf("Put() error = %v", err)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if err := s.Put("k", "a"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Errorf("versions = %v, want [1 2]", got)
+	}
+
+	stop()
+	if err := s.Put("k", "c"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("watch function called after stop, versions = %v", got)
+	}
+}
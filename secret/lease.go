@@ -0,0 +1,240 @@
+package secret
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// LeaseOptions are the options used to configure a Lease.
+type LeaseOptions struct {
+	// RenewBefore is the duration before the current version of the secret
+	// expires at which the Lease starts checking for a new version. Defaults to
+	// one minute.
+	RenewBefore time.Duration
+
+	// RetryInterval is the interval at which the Lease checks for a new version
+	// once the current version is about to expire. Defaults to ten seconds.
+	RetryInterval time.Duration
+
+	// OnRotate is called every time the Lease observes a new version of the
+	// secret. Calls are not concurrent and are made in the order in which the
+	// Lease observed the versions.
+	OnRotate func(old, new Secret)
+
+	// OnError is called when the Lease fails to check for a new version.
+	OnError func(err error)
+}
+
+// LeaseOption is an option used to configure a Lease.
+type LeaseOption func(*LeaseOptions)
+
+// WithRenewBefore configures the duration before the current version of the
+// secret expires at which the Lease starts checking for a new version.
+func WithRenewBefore(d time.Duration) LeaseOption {
+	return func(o *LeaseOptions) {
+		o.RenewBefore = d
+	}
+}
+
+// WithRetryInterval configures the interval at which the Lease checks for a new
+// version once the current version is about to expire.
+func WithRetryInterval(d time.Duration) LeaseOption {
+	return func(o *LeaseOptions) {
+		o.RetryInterval = d
+	}
+}
+
+// WithRotationHandler configures a function that is called every time the Lease
+// observes a new version of the secret.
+func WithRotationHandler(fn func(old, new Secret)) LeaseOption {
+	return func(o *LeaseOptions) {
+		o.OnRotate = fn
+	}
+}
+
+// WithLeaseErrorHandler configures a function that is called when the Lease
+// fails to check for a new version.
+func WithLeaseErrorHandler(fn func(err error)) LeaseOption {
+	return func(o *LeaseOptions) {
+		o.OnError = fn
+	}
+}
+
+// Lease holds the current version of a secret for a long-lived consumer, such
+// as a pooled LDAP connection. The Lease is updated when the provider reports a
+// rotation through Watch, and, for versions with an expiry, by checking for a
+// new version shortly before the current one expires. A version that is older
+// than the current one, i.e. its PreviousVersion or created before it, is
+// ignored, so that a slow check cannot undo a rotation reported by Watch.
+//
+// Example:
+//
+//	versioned, ok := secrets.(secret.Versioned)
+//	if !ok {
+//		return errors.New("secret provider does not support rotation")
+//	}
+//	lease, err := secret.NewLease(versioned, "serviceAccountPassword",
+//		secret.WithRotationHandler(func(_, s secret.Secret) {
+//			pool.Rebind(serviceAccountDN, s.Value)
+//		}),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	defer lease.Close()
+//
+//	conn.Bind(serviceAccountDN, lease.Current().Value)
+type Lease struct {
+	provider Versioned
+	key      string
+	opts     LeaseOptions
+	stop     func()
+
+	mu      sync.Mutex
+	current Secret
+	timer   *time.Timer
+	closed  bool
+
+	// rotations calls OnRotate in the order in which versions were observed.
+	rotations dispatcher
+}
+
+// NewLease creates a Lease for the current version of the given key. If the key
+// does not exist, an error wrapping ErrNotFound is returned. An error is also
+// returned if RetryInterval is not positive or RenewBefore is negative.
+func NewLease(p Versioned, key string, opts ...LeaseOption) (*Lease, error) {
+	o := LeaseOptions{
+		RenewBefore:   time.Minute,
+		RetryInterval: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.RetryInterval <= 0 {
+		return nil, errors.New("lease retry interval must be positive")
+	}
+	if o.RenewBefore < 0 {
+		return nil, errors.New("lease renew before must not be negative")
+	}
+
+	current, err := p.GetVersion(key, "")
+	if err != nil {
+		return nil, err
+	}
+	l := &Lease{
+		provider: p,
+		key:      key,
+		opts:     o,
+		current:  current,
+	}
+	stop, err := p.Watch(key, func(_, s Secret) {
+		l.update(s)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.stop = stop
+	l.schedule(false)
+	l.mu.Unlock()
+
+	// The secret may have been rotated before the watch was registered.
+	if s, err := p.GetVersion(key, ""); err == nil {
+		l.update(s)
+	}
+	return l, nil
+}
+
+// Current returns the most recent version of the secret observed by the Lease.
+func (l *Lease) Current() Secret {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
+
+// Close stops the Lease from observing new versions of the secret.
+func (l *Lease) Close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.mu.Unlock()
+	l.stop()
+}
+
+// update records s as the current version if it is newer than the current
+// version. The last version is kept if the secret is deleted.
+func (l *Lease) update(s Secret) {
+	l.mu.Lock()
+	if l.closed || !newer(s, l.current) {
+		l.mu.Unlock()
+		return
+	}
+	old := l.current
+	l.current = s
+	l.schedule(false)
+	if l.opts.OnRotate != nil {
+		l.rotations.enqueue(func() {
+			l.opts.OnRotate(old, s)
+		})
+	}
+	l.mu.Unlock()
+
+	l.rotations.run()
+}
+
+// newer reports whether s is a newer version of a secret than current.
+func newer(s, current Secret) bool {
+	if s.Version == "" || s.Version == current.Version || s.Version == current.PreviousVersion {
+		return false
+	}
+	return !s.CreatedAt.Before(current.CreatedAt)
+}
+
+// refresh checks the Provider for a new version of the secret.
+func (l *Lease) refresh() {
+	s, err := l.provider.GetVersion(l.key, "")
+	if err != nil {
+		if l.opts.OnError != nil {
+			l.opts.OnError(err)
+		}
+		l.mu.Lock()
+		l.schedule(true)
+		l.mu.Unlock()
+		return
+	}
+
+	l.mu.Lock()
+	stale := !newer(s, l.current)
+	if stale {
+		l.schedule(true)
+	}
+	l.mu.Unlock()
+	if !stale {
+		l.update(s)
+	}
+}
+
+// schedule arranges for refresh to be called before the current version
+// expires, or after RetryInterval if retry is true. The caller must hold l.mu.
+func (l *Lease) schedule(retry bool) {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	if l.closed || l.current.ExpiresAt.IsZero() {
+		return
+	}
+	d := l.opts.RetryInterval
+	if !retry {
+		d = max(time.Until(l.current.ExpiresAt.Add(-l.opts.RenewBefore)), 0)
+	}
+	l.timer = time.AfterFunc(d, l.refresh)
+}
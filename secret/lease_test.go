@@ -0,0 +1,162 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testProvider is a Versioned whose current version is set by the test.
+// Watch functions are called synchronously by set.
+type testProvider struct {
+	mu      sync.Mutex
+	current Secret
+	watches []func(old, new Secret)
+}
+
+func (p *testProvider) Get(key string) any {
+	return p.GetString(key)
+}
+
+func (p *testProvider) GetString(string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current.Value
+}
+
+func (p *testProvider) GetVersion(key, version string) (Secret, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current.Version == "" {
+		return Secret{}, fmt.Errorf("secret '%s': %w", key, ErrNotFound)
+	}
+	return p.current, nil
+}
+
+func (p *testProvider) Metadata(key string) (Metadata, error) {
+	s, err := p.GetVersion(key, "")
+	return s.Metadata, err
+}
+
+func (p *testProvider) Watch(_ string, fn func(old, new Secret)) (func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watches = append(p.watches, fn)
+	return func() {}, nil
+}
+
+// set makes s the current version and, if notify is true, calls the watch
+// functions.
+func (p *testProvider) set(s Secret, notify bool) {
+	p.mu.Lock()
+	old := p.current
+	p.current = s
+	watches := p.watches
+	p.mu.Unlock()
+	if notify {
+		for _, fn := range watches {
+			fn(old, s)
+		}
+	}
+}
+
+func version(v string, createdAt time.Time) Secret {
+	return Secret{Key: "k", Value: "value-" + v, Metadata: Metadata{Version: v, CreatedAt: createdAt}}
+}
+
+func TestLeaseRotation(t *testing.T) {
+	t0 := time.Now()
+	p := &testProvider{}
+	p.set(version("1", t0), false)
+
+	var rotations []string
+	l, err := NewLease(p, "k", WithRotationHandler(func(old, new Secret) {
+		rotations = append(rotations, old.Version+"->"+new.Version)
+	}))
+	if err != nil {
+		t.Fatalf("NewLease() error = %v", err)
+	}
+	defer l.Close()
+
+	p.set(version("2", t0.Add(time.Second)), true)
+	p.set(version("3", t0.Add(2*time.Second)), true)
+	if got := l.Current().Version; got != "3" {
+		t.Errorf("Current().Version = %s, want 3", got)
+	}
+	if len(rotations) != 2 || rotations[0] != "1->2" || rotations[1] != "2->3" {
+		t.Errorf("rotations = %v, want [1->2 2->3]", rotations)
+	}
+}
+
+func TestLeaseIgnoresOlderVersions(t *testing.T) {
+	t0 := time.Now()
+	p := &testProvider{}
+	p.set(version("1", t0), false)
+	l, err := NewLease(p, "k")
+	if err != nil {
+		t.Fatalf("NewLease() error = %v", err)
+	}
+	defer l.Close()
+
+	v2 := version("2", t0.Add(time.Second))
+	v2.PreviousVersion = "1"
+	p.set(v2, true)
+
+	// A version read before the rotation, e.g. by a slow refresh, must not
+	// replace the newer version.
+	l.update(version("1", t0))
+	l.update(version("0", t0.Add(-time.Second)))
+	if got := l.Current().Version; got != "2" {
+		t.Errorf("Current().Version = %s, want 2", got)
+	}
+}
+
+func TestLeaseRefreshesExpiringVersion(t *testing.T) {
+	t0 := time.Now()
+	p := &testProvider{}
+	v1 := version("1", t0)
+	v1.ExpiresAt = t0.Add(time.Hour)
+	p.set(v1, false)
+
+	rotated := make(chan Secret, 1)
+	l, err := NewLease(p, "k",
+		WithRenewBefore(2*time.Hour),
+		WithRetryInterval(time.Millisecond),
+		WithRotationHandler(func(_, new Secret) {
+			rotated <- new
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewLease() error = %v", err)
+	}
+	defer l.Close()
+
+	// The new version is not reported through Watch, so the Lease must find it
+	// by checking the provider.
+	p.set(version("2", t0.Add(time.Second)), false)
+	select {
+	case s := <-rotated:
+		if s.Version != "2" {
+			t.Errorf("rotated to version %s, want 2", s.Version)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease did not refresh the expiring version")
+	}
+}
+
+func TestNewLeaseErrors(t *testing.T) {
+	p := &testProvider{}
+	if _, err := NewLease(p, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("NewLease() of missing key error = %v, want ErrNotFound", err)
+	}
+
+	p.set(version("1", time.Now()), false)
+	if _, err := NewLease(p, "k", WithRetryInterval(0)); err == nil {
+		t.Error("NewLease() with zero retry interval succeeded")
+	}
+	if _, err := NewLease(p, "k", WithRenewBefore(-time.Second)); err == nil {
+		t.Error("NewLease() with negative renew before succeeded")
+	}
+}
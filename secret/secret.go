@@ -1,6 +1,27 @@
 package secret
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a secret does not exist in the secret store.
+// Implementations may wrap ErrNotFound, so callers should use errors.Is to check
+// for it.
+var ErrNotFound = errors.New("secret not found")
+
 // Provider is used to retrieve secrets from the configured secret store.
+//
+// Providers returned by the Orchestrator may implement further operations
+// through the optional Lookuper, Versioned and Writer interfaces. Use a type
+// assertion to find out whether an operation is supported. The functions in
+// this package, such as GetString, work with any Provider.
+//
+// Example:
+//
+//	if v, ok := secrets.(secret.Versioned); ok {
+//		meta, _ := v.Metadata("serviceAccountPassword")
+//	}
 type Provider interface {
 	// Get retrieves the key from the secret provider.
 	Get(key string) any
@@ -8,3 +29,121 @@ type Provider interface {
 	// GetString retrieves the key from the secret provider as a string value.
 	GetString(key string) string
 }
+
+// Lookuper is implemented by providers that can distinguish a missing secret
+// from one whose value is empty.
+type Lookuper interface {
+	Provider
+
+	// Lookup retrieves the key from the secret provider as a string value. The
+	// returned bool is false if the key does not exist.
+	//
+	// Example:
+	//
+	//	password, ok := secrets.Lookup("serviceAccountPassword")
+	//	if !ok {
+	//		return errors.New("serviceAccountPassword secret is not configured")
+	//	}
+	Lookup(key string) (string, bool)
+}
+
+// Versioned is implemented by providers that keep versions of secrets and
+// report when they are rotated.
+type Versioned interface {
+	Provider
+
+	// GetVersion retrieves the given version of the key from the secret provider.
+	// An empty version retrieves the current version. If the key or version does
+	// not exist, an error wrapping ErrNotFound is returned.
+	//
+	// Example (binding with the previous password during a rotation window):
+	//
+	//	meta, _ := secrets.Metadata("serviceAccountPassword")
+	//	prev, err := secrets.GetVersion("serviceAccountPassword", meta.PreviousVersion)
+	GetVersion(key, version string) (Secret, error)
+
+	// Metadata retrieves the metadata of the current version of the key without
+	// retrieving its value. If the key does not exist, an error wrapping
+	// ErrNotFound is returned.
+	Metadata(key string) (Metadata, error)
+
+	// Watch registers fn to be called every time the current version of the key
+	// changes, e.g. because the secret was rotated. old is the zero Secret if the
+	// key did not exist before the change, and new is the zero Secret if the key
+	// was deleted. Calls to fn for a given watch are not concurrent and are made
+	// in the order in which the versions were created. The returned function
+	// stops the watch.
+	//
+	// Example:
+	//
+	//	stop, err := secrets.Watch("serviceAccountPassword", func(old, new secret.Secret) {
+	//		pool.Rebind(new.Value)
+	//	})
+	//	if err != nil {
+	//		return err
+	//	}
+	//	defer stop()
+	Watch(key string, fn func(old, new Secret)) (stop func(), err error)
+}
+
+// Metadata describes a version of a secret.
+type Metadata struct {
+	// Version identifies the version of the secret. Versions are opaque and can
+	// only be compared for equality.
+	Version string
+
+	// PreviousVersion identifies the version the secret had before Version, if
+	// any. It can be used to accept both the old and the new secret while a
+	// rotation is rolled out.
+	PreviousVersion string
+
+	// CreatedAt is the time at which the version was created.
+	CreatedAt time.Time
+
+	// ExpiresAt is the time at which the version expires, e.g. at the end of the
+	// lease of a dynamic secret. The zero time means the version does not expire.
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the version has expired at the given time.
+func (m Metadata) Expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// Secret is a version of a secret along with its value.
+type Secret struct {
+	Metadata
+
+	// Key is the key of the secret.
+	Key string
+
+	// Value is the value of the secret.
+	Value string
+}
+
+// Writer is implemented by providers whose secret store can be written to, e.g.
+// to store credentials generated for a user while enrolling them into an
+// application.
+//
+// Example:
+//
+//	w, ok := secrets.(secret.Writer)
+//	if !ok {
+//		return errors.New("secret provider is read-only")
+//	}
+//	err := w.Put("formfill/"+uid, generatedPassword)
+type Writer interface {
+	Provider
+
+	// Put stores value as a new version of the key. If the provider implements
+	// Versioned, the previous version remains available through GetVersion.
+	Put(key, value string) error
+
+	// Delete removes all versions of the key. Deleting a key that does not exist
+	// is not an error.
+	Delete(key string) error
+
+	// List returns the keys that begin with the given prefix, in lexicographical
+	// order.
+	List(prefix string) ([]string, error)
+}
@@ -0,0 +1,201 @@
+package secret
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Errors returned by the functions in this file never include the value of the
+// secret, so they can be logged safely.
+
+// GetString returns the secret for the given key. If the key does not exist, an
+// error wrapping ErrNotFound is returned. Unless p implements Lookuper, a key
+// is considered missing if p.Get returns nil for it.
+//
+// Example:
+//
+//	password, err := secret.GetString(secrets, "serviceAccountPassword")
+//	if err != nil {
+//		return fmt.Errorf("unable to bind to LDAP: %w", err)
+//	}
+func GetString(p Provider, key string) (string, error) {
+	v, ok := lookup(p, key)
+	if !ok {
+		return "", fmt.Errorf("secret '%s': %w", key, ErrNotFound)
+	}
+	return v, nil
+}
+
+// lookup retrieves the key with Lookup if p implements Lookuper. Otherwise, the
+// key is considered missing if Get returns nil.
+func lookup(p Provider, key string) (string, bool) {
+	if l, ok := p.(Lookuper); ok {
+		return l.Lookup(key)
+	}
+	if p.Get(key) == nil {
+		return "", false
+	}
+	return p.GetString(key), true
+}
+
+// GetBytes returns the secret for the given key as a []byte. If the key does
+// not exist, an error wrapping ErrNotFound is returned.
+func GetBytes(p Provider, key string) ([]byte, error) {
+	v, err := GetString(p, key)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := p.Get(key).([]byte); ok {
+		return b, nil
+	}
+	return []byte(v), nil
+}
+
+// GetInt returns the secret for the given key parsed as a base-10 int. If the
+// key does not exist, an error wrapping ErrNotFound is returned.
+func GetInt(p Provider, key string) (int, error) {
+	v, err := GetString(p, key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("secret '%s' is not an int: %w", key, numError(err))
+	}
+	return n, nil
+}
+
+// GetBool returns the secret for the given key parsed with strconv.ParseBool.
+// If the key does not exist, an error wrapping ErrNotFound is returned.
+func GetBool(p Provider, key string) (bool, error) {
+	v, err := GetString(p, key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("secret '%s' is not a bool: %w", key, numError(err))
+	}
+	return b, nil
+}
+
+// GetDuration returns the secret for the given key parsed with
+// time.ParseDuration, e.g. "90s". If the key does not exist, an error wrapping
+// ErrNotFound is returned.
+func GetDuration(p Provider, key string) (time.Duration, error) {
+	v, err := GetString(p, key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		// The error returned by time.ParseDuration contains the input.
+		return 0, fmt.Errorf("secret '%s' is not a valid duration", key)
+	}
+	return d, nil
+}
+
+// GetCertPool returns a certificate pool containing the PEM-encoded
+// certificates stored in the secret for the given key, e.g. the CA certificates
+// of an LDAP server. If the key does not exist, an error wrapping ErrNotFound
+// is returned.
+//
+// Example:
+//
+//	pool, err := secret.GetCertPool(secrets, "ldapCACert")
+//	if err != nil {
+//		return err
+//	}
+//	tlsConfig := &tls.Config{RootCAs: pool}
+func GetCertPool(p Provider, key string) (*x509.CertPool, error) {
+	v, err := GetBytes(p, key)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(v) {
+		return nil, fmt.Errorf("secret '%s' does not contain a PEM-encoded certificate", key)
+	}
+	return pool, nil
+}
+
+// GetTLSCertificate returns the TLS certificate made of the PEM-encoded
+// certificate chain stored in the secret for certKey and the PEM-encoded
+// private key stored in the secret for keyKey. certKey and keyKey may be the
+// same if both are stored in one secret. If either key does not exist, an
+// error wrapping ErrNotFound is returned.
+//
+// Example:
+//
+//	cert, err := secret.GetTLSCertificate(secrets, "mtlsCert", "mtlsKey")
+//	if err != nil {
+//		return err
+//	}
+//	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+func GetTLSCertificate(p Provider, certKey, keyKey string) (tls.Certificate, error) {
+	certPEM, err := GetBytes(p, certKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := GetBytes(p, keyKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("unable to load TLS certificate from secrets '%s' and '%s': %w", certKey, keyKey, err)
+	}
+	return cert, nil
+}
+
+// GetRSAPrivateKey returns the RSA private key stored PEM-encoded in the secret
+// for the given key, in either PKCS #1 ("RSA PRIVATE KEY") or PKCS #8
+// ("PRIVATE KEY") form. If the key does not exist, an error wrapping
+// ErrNotFound is returned.
+func GetRSAPrivateKey(p Provider, key string) (*rsa.PrivateKey, error) {
+	v, err := GetBytes(p, key)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(v)
+	if block == nil {
+		return nil, fmt.Errorf("secret '%s' is not PEM-encoded", key)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		pk, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse RSA private key from secret '%s': %w", key, err)
+		}
+		return pk, nil
+	case "PRIVATE KEY":
+		pk, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse private key from secret '%s': %w", key, err)
+		}
+		rsaKey, ok := pk.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("secret '%s' contains a %T, not an RSA private key", key, pk)
+		}
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("secret '%s' contains an unsupported PEM block type '%s'", key, block.Type)
+	}
+}
+
+// numError returns the underlying error of a strconv.NumError, which unlike
+// the NumError itself does not contain the parsed input.
+func numError(err error) error {
+	var ne *strconv.NumError
+	if errors.As(err, &ne) {
+		return ne.Err
+	}
+	return err
+}
@@ -0,0 +1,172 @@
+package secret
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mapProvider is a Provider that does not implement Lookuper.
+type mapProvider map[string]any
+
+func (p mapProvider) Get(key string) any {
+	return p[key]
+}
+
+func (p mapProvider) GetString(key string) string {
+	s, _ := p[key].(string)
+	return s
+}
+
+// lookupProvider is a Lookuper.
+type lookupProvider struct {
+	mapProvider
+}
+
+func (p lookupProvider) Lookup(key string) (string, bool) {
+	v, ok := p.mapProvider[key]
+	if !ok {
+		return "", false
+	}
+	s, _ := v.(string)
+	return s, true
+}
+
+func TestGetString(t *testing.T) {
+	values := mapProvider{"empty": "", "password": "hunter2"}
+	providers := map[string]Provider{
+		"Provider": values,
+		"Lookuper": lookupProvider{values},
+	}
+	for name, p := range providers {
+		t.Run(name, func(t *testing.T) {
+			if v, err := GetString(p, "password"); err != nil || v != "hunter2" {
+				t.Errorf("GetString() = %q, %v, want hunter2", v, err)
+			}
+			if v, err := GetString(p, "empty"); err != nil || v != "" {
+				t.Errorf("GetString() of empty secret = %q, %v, want empty value", v, err)
+			}
+			if _, err := GetString(p, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetString() of missing secret error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+	p := mapProvider{"raw": []byte{0, 1, 2}, "text": "abc"}
+	if b, err := GetBytes(p, "raw"); err != nil || string(b) != "\x00\x01\x02" {
+		t.Errorf("GetBytes() = %v, %v, want the stored bytes", b, err)
+	}
+	if b, err := GetBytes(p, "text"); err != nil || string(b) != "abc" {
+		t.Errorf("GetBytes() = %q, %v, want abc", b, err)
+	}
+}
+
+func TestGetParsed(t *testing.T) {
+	p := mapProvider{
+		"port":    "636",
+		"tls":     "true",
+		"timeout": "90s",
+		"bad":     "s3cr3t",
+	}
+	if n, err := GetInt(p, "port"); err != nil || n != 636 {
+		t.Errorf("GetInt() = %d, %v, want 636", n, err)
+	}
+	if b, err := GetBool(p, "tls"); err != nil || !b {
+		t.Errorf("GetBool() = %t, %v, want true", b, err)
+	}
+	if d, err := GetDuration(p, "timeout"); err != nil || d != 90*time.Second {
+		t.Errorf("GetDuration() = %v, %v, want 90s", d, err)
+	}
+
+	// Errors must not contain the value of the secret.
+	_, intErr := GetInt(p, "bad")
+	_, boolErr := GetBool(p, "bad")
+	_, durationErr := GetDuration(p, "bad")
+	for _, err := range []error{intErr, boolErr, durationErr} {
+		if err == nil {
+			t.Error("parsing an invalid secret succeeded")
+		} else if strings.Contains(err.Error(), "s3cr3t") {
+			t.Errorf("error %q contains the secret", err)
+		}
+	}
+	if _, err := GetInt(p, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetInt() of missing secret error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetRSAPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecPKCS8, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+
+	p := mapProvider{
+		"pkcs1": string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"pkcs8": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})),
+		"ecdsa": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecPKCS8})),
+		"plain": "not a key",
+	}
+	for _, k := range []string{"pkcs1", "pkcs8"} {
+		got, err := GetRSAPrivateKey(p, k)
+		if err != nil || !got.Equal(key) {
+			t.Errorf("GetRSAPrivateKey(%s) = %v, want the stored key", k, err)
+		}
+	}
+	for _, k := range []string{"ecdsa", "plain"} {
+		if _, err := GetRSAPrivateKey(p, k); err == nil {
+			t.Errorf("GetRSAPrivateKey(%s) succeeded", k)
+		}
+	}
+}
+
+func TestGetTLSCertificate(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ldap.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	p := mapProvider{"cert": certPEM, "key": keyPEM, "both": certPEM + keyPEM}
+
+	if _, err := GetTLSCertificate(p, "cert", "key"); err != nil {
+		t.Errorf("GetTLSCertificate() error = %v", err)
+	}
+	if _, err := GetTLSCertificate(p, "both", "both"); err != nil {
+		t.Errorf("GetTLSCertificate() of a combined secret error = %v", err)
+	}
+	if _, err := GetTLSCertificate(p, "cert", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTLSCertificate() with missing key error = %v, want ErrNotFound", err)
+	}
+
+	if _, err := GetCertPool(p, "cert"); err != nil {
+		t.Errorf("GetCertPool() error = %v", err)
+	}
+	if _, err := GetCertPool(p, "key"); err == nil {
+		t.Error("GetCertPool() of a private key succeeded")
+	}
+}
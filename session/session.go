@@ -1,6 +1,8 @@
 package session
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 )
@@ -44,6 +46,18 @@ import (
 //
 //	var p2 person
 //	_ := sess.GetJSON("profile", &p2)
+//
+// Sessions returned by the Orchestrator may implement further operations
+// through the optional Store, Lifecycle and Versioned interfaces. Use a type
+// assertion to find out whether an operation is supported.
+//
+// Example (removing values on logout):
+//
+//	sess, _ := api.Session()
+//	if store, ok := sess.(session.Store); ok {
+//		_ = store.Delete("azure.authenticated")
+//		_ = sess.Save()
+//	}
 type Provider interface {
 	// GetString returns a session value based on the provided key. If the key does
 	// not exist, the default or zero value will be returned (i.e, "").
@@ -101,6 +115,202 @@ type Provider interface {
 	Save() error
 }
 
+// Store is implemented by sessions that support checking for, deleting and
+// enumerating keys. Like the setters, Delete and Clear are recorded in the
+// changelog and are only persisted when Save is called.
+type Store interface {
+	Provider
+
+	// Exists reports whether the key exists in the session data. Since the getters
+	// return the zero value for keys that do not exist, Exists can be used to
+	// distinguish a missing key from a key that is set to its zero value.
+	Exists(key string) (bool, error)
+
+	// Delete removes the key and its value from the session data. Deleting a key
+	// that does not exist is not an error.
+	Delete(key string) error
+
+	// Keys returns the keys in the session data that begin with the provided
+	// prefix, sorted in lexicographical order. If the prefix is empty, all keys are
+	// returned.
+	//
+	// Example:
+	//
+	//	azureKeys, _ := sess.Keys("azure.")
+	Keys(prefix string) ([]string, error)
+
+	// Clear removes all keys and values from the session data. Clear is typically
+	// used on logout to ensure no stale values such as '<idp>.authenticated'
+	// remain on the session.
+	Clear() error
+}
+
+// Lifecycle is implemented by sessions whose identifier and expiry can be
+// inspected and controlled.
+//
+// Example (rotating the session identifier after step-up authentication):
+//
+//	sess, _ := api.Session()
+//	_ = sess.SetString("mfa.completed", "true")
+//	_ = sess.Save()
+//	if lc, ok := sess.(session.Lifecycle); ok {
+//		_ = lc.Regenerate()
+//	}
+type Lifecycle interface {
+	Provider
+
+	// ID returns the identifier of the session.
+	ID() string
+
+	// Regenerate replaces the session identifier with a newly generated one while
+	// retaining the session data. The previous identifier is invalidated
+	// immediately. Regenerate should be called whenever the privilege level of the
+	// session changes, e.g. after step-up authentication, to prevent session
+	// fixation.
+	Regenerate() error
+
+	// Invalidate destroys the session in the underlying session store. Any
+	// unsaved changes are discarded. After a session is invalidated, all methods
+	// that return an error will return ErrInvalidated, and a new session will be
+	// created on the next call to Orchestrator.Session.
+	Invalidate() error
+
+	// CreatedAt returns the time the session was created.
+	CreatedAt() time.Time
+
+	// LastAccessedAt returns the time the session was last accessed.
+	LastAccessedAt() time.Time
+
+	// ExpiresAt returns the absolute expiry of the session, i.e. the time after
+	// which the session expires regardless of activity. The zero time is returned
+	// if the session does not have an absolute expiry.
+	ExpiresAt() time.Time
+
+	// IdleExpiresAt returns the time at which the session expires if it is not
+	// accessed again. The zero time is returned if the session does not have an
+	// idle timeout.
+	IdleExpiresAt() time.Time
+
+	// SetExpiresAt extends or shortens the absolute lifetime of the session. The
+	// change takes effect immediately. An expiry in the past expires the session.
+	SetExpiresAt(expiresAt time.Time) error
+
+	// SetIdleTimeout changes the duration of inactivity after which the session
+	// expires. The change takes effect immediately. A timeout of zero disables the
+	// idle timeout.
+	SetIdleTimeout(timeout time.Duration) error
+}
+
+// Versioned is implemented by sessions that track the version of the session
+// data and expose their changelog, e.g. to save with optimistic concurrency.
+type Versioned interface {
+	Provider
+
+	// Changes returns the pending changes in the changelog in the order they were
+	// made. The changelog is emptied by Save, SaveIfUnchanged, Discard and Reload.
+	Changes() []Change
+
+	// Discard discards all pending changes in the changelog.
+	Discard()
+
+	// Version returns the version of the session data as observed when the
+	// session was retrieved or last saved or reloaded. The version is incremented
+	// by the underlying session store every time a non-empty changelog is saved.
+	Version() uint64
+
+	// SaveIfUnchanged saves all changes from the changelog to the underlying
+	// session store only if the session has not been saved by another request
+	// since this provider observed Version. If it has, nothing is saved and a
+	// *ConflictError is returned. The changelog is retained so the changes can be
+	// inspected before retrying.
+	//
+	// Example (retrying on conflict):
+	//
+	//	for attempt := 0; attempt < 3; attempt++ {
+	//		count, _ := sess.GetInt("login.count")
+	//		_ = sess.SetInt("login.count", count+1)
+	//		err = sess.SaveIfUnchanged()
+	//		if !errors.Is(err, session.ErrConflict) {
+	//			break
+	//		}
+	//		_ = sess.Reload()
+	//	}
+	SaveIfUnchanged() error
+
+	// Reload discards all pending changes and reloads the session data from the
+	// underlying session store, updating Version.
+	Reload() error
+}
+
+// ChangeOp is the kind of mutation recorded in a Change.
+type ChangeOp int
+
+const (
+	// OpSet is recorded by the setters.
+	OpSet ChangeOp = iota + 1
+	// OpDelete is recorded by Delete.
+	OpDelete
+	// OpClear is recorded by Clear.
+	OpClear
+)
+
+// String returns the name of the operation.
+func (op ChangeOp) String() string {
+	switch op {
+	case OpSet:
+		return "set"
+	case OpDelete:
+		return "delete"
+	case OpClear:
+		return "clear"
+	default:
+		return fmt.Sprintf("ChangeOp(%d)", int(op))
+	}
+}
+
+// Change is a single pending mutation in the changelog of a Provider.
+type Change struct {
+	// Op is the kind of mutation.
+	Op ChangeOp
+
+	// Key is the affected session key. Key is empty for OpClear.
+	Key string
+
+	// Value is the value passed to the setter. Value is nil for OpDelete and
+	// OpClear.
+	Value any
+}
+
+// ErrConflict is matched by errors returned from SaveIfUnchanged when the
+// session was modified concurrently.
+var ErrConflict = errors.New("session was modified concurrently")
+
+// ConflictError is returned by SaveIfUnchanged when the session was saved by
+// another request after it was retrieved.
+type ConflictError struct {
+	// ID is the identifier of the session.
+	ID string
+
+	// Expected is the version observed by the provider.
+	Expected uint64
+
+	// Actual is the current version in the underlying session store.
+	Actual uint64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: expected version %d, found version %d", ErrConflict, e.Expected, e.Actual)
+}
+
+// Is reports whether target is ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// ErrInvalidated is returned when operating on a session that has been
+// invalidated.
+var ErrInvalidated = errors.New("session has been invalidated")
+
 type Options struct {
 	Request *http.Request
 }
@@ -0,0 +1,278 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned when an operation requires an optional interface,
+// e.g. Store, that the session does not implement.
+var ErrUnsupported = errors.New("operation not supported by session")
+
+// Get returns the session value for the given key as a T. Values of type
+// string, bool, int, float64, []byte and time.Time are retrieved with the
+// corresponding Provider getter. Values of any other type, including []string,
+// are decoded with GetJSON. If the key does not exist, the zero value of T is
+// returned.
+//
+// Example:
+//
+//	age, err := session.Get[int](sess, "profile.age")
+func Get[T any](p Provider, key string) (T, error) {
+	var zero T
+	var (
+		v   any
+		err error
+	)
+	switch any(zero).(type) {
+	case string:
+		v, err = p.GetString(key)
+	case bool:
+		v, err = p.GetBool(key)
+	case int:
+		v, err = p.GetInt(key)
+	case float64:
+		v, err = p.GetFloat(key)
+	case []byte:
+		v, err = p.GetBytes(key)
+	case time.Time:
+		v, err = p.GetTime(key)
+	default:
+		var dest T
+		if err := p.GetJSON(key, &dest); err != nil {
+			return zero, err
+		}
+		return dest, nil
+	}
+	if err != nil {
+		return zero, err
+	}
+	return v.(T), nil
+}
+
+// Set adds a key and the corresponding value of type T to the session data.
+// Values of type string, bool, int, float64, []byte and time.Time are stored
+// with the corresponding Provider setter. Values of any other type are stored
+// with SetJSON.
+//
+// Example:
+//
+//	err := session.Set(sess, "profile.age", 42)
+func Set[T any](p Provider, key string, value T) error {
+	switch v := any(value).(type) {
+	case string:
+		return p.SetString(key, v)
+	case bool:
+		return p.SetBool(key, v)
+	case int:
+		return p.SetInt(key, v)
+	case float64:
+		return p.SetFloat(key, v)
+	case []byte:
+		return p.SetBytes(key, v)
+	case time.Time:
+		return p.SetTime(key, v)
+	default:
+		return p.SetJSON(key, value)
+	}
+}
+
+// GetStrings returns a multivalued session value, such as the group memberships
+// returned by an AttributeProvider, that is stored as a single string joined by
+// the given delimiter. If the key does not exist or is empty, nil is returned.
+//
+// Example:
+//
+//	groups, err := session.GetStrings(sess, "ldap.memberOf", ",")
+func GetStrings(p Provider, key, delimiter string) ([]string, error) {
+	v, err := p.GetString(key)
+	if err != nil {
+		return nil, err
+	}
+	return splitValues(v, delimiter), nil
+}
+
+// SetStrings adds a key and the corresponding multivalued value to the session
+// data. The values are joined using the given delimiter.
+func SetStrings(p Provider, key string, values []string, delimiter string) error {
+	return p.SetString(key, strings.Join(values, delimiter))
+}
+
+func splitValues(v, delimiter string) []string {
+	if v == "" {
+		return nil
+	}
+	if delimiter == "" {
+		return []string{v}
+	}
+	return strings.Split(v, delimiter)
+}
+
+// ValidationError is returned when a session value does not pass the
+// validation of a Key.
+type ValidationError struct {
+	// Key is the name of the session key.
+	Key string
+
+	// Err is the error returned by the validator.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid session value '%s': %v", e.Key, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Key is a typed descriptor of a session key. A Key bundles the name of a
+// session key with the type of its value, an optional default and optional
+// validators so that callers do not have to repeat lookup, parsing and
+// validation logic.
+//
+// Example:
+//
+//	var authenticated = session.NewKey[bool]("azure.authenticated")
+//	var groups = session.NewKey("ldap.memberOf",
+//		session.WithDelimiter(","),
+//		session.WithDefault([]string{"everyone"}),
+//	)
+//
+//	isAuthn, _ := authenticated.Get(sess)
+//	memberOf, _ := groups.Get(sess)
+type Key[T any] struct {
+	name       string
+	def        T
+	validators []func(T) error
+	delimiter  *string
+}
+
+// KeyOpt is an option that allows to configure a Key.
+type KeyOpt[T any] func(*Key[T])
+
+// WithDefault configures the value returned by Key.Get if the key does not
+// exist in the session data.
+func WithDefault[T any](value T) KeyOpt[T] {
+	return func(k *Key[T]) {
+		k.def = value
+	}
+}
+
+// WithValidator adds a validator to the Key. Validators are run on values
+// retrieved with Key.Get and on values passed to Key.Set. If a validator returns
+// an error, a *ValidationError wrapping it is returned.
+func WithValidator[T any](validate func(T) error) KeyOpt[T] {
+	return func(k *Key[T]) {
+		k.validators = append(k.validators, validate)
+	}
+}
+
+// WithDelimiter configures a multivalued Key to be stored as a single string
+// joined by the given delimiter, matching the representation used for
+// multivalued attributes set by identity providers and attribute providers.
+// Without this option, []string values are stored as JSON.
+func WithDelimiter(delimiter string) KeyOpt[[]string] {
+	return func(k *Key[[]string]) {
+		k.delimiter = &delimiter
+	}
+}
+
+// NewKey creates a Key with the given name.
+func NewKey[T any](name string, opts ...KeyOpt[T]) Key[T] {
+	k := Key[T]{name: name}
+	for _, opt := range opts {
+		opt(&k)
+	}
+	return k
+}
+
+// Name returns the name of the session key.
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Get returns the value of the key from the session data. If the key does not
+// exist, the configured default or the zero value of T is returned without
+// validation. Unless p implements Store, a key is considered missing if its
+// value is the zero value of T.
+func (k Key[T]) Get(p Provider) (T, error) {
+	store, isStore := p.(Store)
+	if isStore {
+		ok, err := store.Exists(k.name)
+		if err != nil {
+			return k.def, err
+		}
+		if !ok {
+			return k.def, nil
+		}
+	}
+
+	var (
+		v   T
+		err error
+	)
+	if k.delimiter != nil {
+		values, err := GetStrings(p, k.name, *k.delimiter)
+		if err != nil {
+			return k.def, err
+		}
+		v = any(values).(T)
+	} else {
+		v, err = Get[T](p, k.name)
+		if err != nil {
+			return k.def, err
+		}
+	}
+	if !isStore && reflect.ValueOf(&v).Elem().IsZero() {
+		return k.def, nil
+	}
+
+	if err := k.validate(v); err != nil {
+		return k.def, err
+	}
+	return v, nil
+}
+
+// Set validates the value and adds it to the session data.
+func (k Key[T]) Set(p Provider, value T) error {
+	if err := k.validate(value); err != nil {
+		return err
+	}
+	if k.delimiter != nil {
+		return SetStrings(p, k.name, any(value).([]string), *k.delimiter)
+	}
+	return Set(p, k.name, value)
+}
+
+// Exists reports whether the key exists in the session data. If p does not
+// implement Store, ErrUnsupported is returned.
+func (k Key[T]) Exists(p Provider) (bool, error) {
+	store, ok := p.(Store)
+	if !ok {
+		return false, ErrUnsupported
+	}
+	return store.Exists(k.name)
+}
+
+// Delete removes the key from the session data. If p does not implement Store,
+// ErrUnsupported is returned.
+func (k Key[T]) Delete(p Provider) error {
+	store, ok := p.(Store)
+	if !ok {
+		return ErrUnsupported
+	}
+	return store.Delete(k.name)
+}
+
+func (k Key[T]) validate(v T) error {
+	for _, validate := range k.validators {
+		if err := validate(v); err != nil {
+			return &ValidationError{Key: k.name, Err: err}
+		}
+	}
+	return nil
+}
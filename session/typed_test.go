@@ -0,0 +1,169 @@
+package session_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/strata-io/service-extension/orchestratortest"
+	"github.com/strata-io/service-extension/session"
+)
+
+// plainProvider hides the optional interfaces implemented by the wrapped
+// Provider.
+type plainProvider struct {
+	session.Provider
+}
+
+func newSession(t *testing.T, values map[string]any) session.Provider {
+	t.Helper()
+	sess, err := orchestratortest.New(orchestratortest.WithSessionValues(values)).Session()
+	if err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+	return sess
+}
+
+func roundTrip[T any](t *testing.T, p session.Provider, key string, value T, equal func(a, b T) bool) {
+	t.Helper()
+	if err := session.Set(p, key, value); err != nil {
+		t.Fatalf("Set(%s) error = %v", key, err)
+	}
+	got, err := session.Get[T](p, key)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", key, err)
+	}
+	if !equal(got, value) {
+		t.Errorf("Get(%s) = %v, want %v", key, got, value)
+	}
+}
+
+func eq[T comparable](a, b T) bool {
+	return a == b
+}
+
+func TestGetSet(t *testing.T) {
+	type profile struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	p := newSession(t, nil)
+
+	roundTrip(t, p, "string", "jdoe", eq[string])
+	roundTrip(t, p, "bool", true, eq[bool])
+	roundTrip(t, p, "int", 42, eq[int])
+	roundTrip(t, p, "float", 1.5, eq[float64])
+	roundTrip(t, p, "bytes", []byte{1, 2}, slices.Equal[[]byte])
+	roundTrip(t, p, "time", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), time.Time.Equal)
+	roundTrip(t, p, "profile", profile{Name: "John", Age: 42}, eq[profile])
+	roundTrip(t, p, "groups", []string{"admins", "users"}, slices.Equal[[]string])
+
+	if v, err := session.Get[int](p, "missing"); err != nil || v != 0 {
+		t.Errorf("Get() of missing key = %v, %v, want 0", v, err)
+	}
+}
+
+func TestGetSetStrings(t *testing.T) {
+	p := newSession(t, map[string]any{"ldap.memberOf": "admins,users", "empty": ""})
+	groups, err := session.GetStrings(p, "ldap.memberOf", ",")
+	if err != nil || !slices.Equal(groups, []string{"admins", "users"}) {
+		t.Errorf("GetStrings() = %v, %v, want [admins users]", groups, err)
+	}
+	if groups, _ := session.GetStrings(p, "empty", ","); groups != nil {
+		t.Errorf("GetStrings() of empty value = %v, want nil", groups)
+	}
+
+	if err := session.SetStrings(p, "roles", []string{"a", "b"}, "|"); err != nil {
+		t.Fatalf("SetStrings() error = %v", err)
+	}
+	if v, _ := p.GetString("roles"); v != "a|b" {
+		t.Errorf("stored value = %q, want a|b", v)
+	}
+}
+
+func TestKey(t *testing.T) {
+	errNegative := errors.New("must not be negative")
+	age := session.NewKey("profile.age",
+		session.WithDefault(18),
+		session.WithValidator(func(v int) error {
+			if v < 0 {
+				return errNegative
+			}
+			return nil
+		}),
+	)
+	p := newSession(t, map[string]any{"profile.age": 0, "invalid.age": -1})
+
+	if age.Name() != "profile.age" {
+		t.Errorf("Name() = %s", age.Name())
+	}
+	// A stored zero value is returned rather than the default.
+	if v, err := age.Get(p); err != nil || v != 0 {
+		t.Errorf("Get() = %v, %v, want 0", v, err)
+	}
+
+	if err := age.Set(p, -5); !errors.Is(err, errNegative) {
+		t.Errorf("Set() of invalid value error = %v, want the validator error", err)
+	}
+	var verr *session.ValidationError
+	invalid := session.NewKey("invalid.age", session.WithValidator(func(v int) error {
+		if v < 0 {
+			return errNegative
+		}
+		return nil
+	}))
+	if _, err := invalid.Get(p); !errors.As(err, &verr) || verr.Key != "invalid.age" {
+		t.Errorf("Get() of invalid value error = %v, want a *ValidationError", err)
+	}
+
+	if err := age.Delete(p); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if ok, err := age.Exists(p); err != nil || ok {
+		t.Errorf("Exists() after Delete = %t, %v, want false", ok, err)
+	}
+	if v, err := age.Get(p); err != nil || v != 18 {
+		t.Errorf("Get() of missing key = %v, %v, want the default 18", v, err)
+	}
+}
+
+func TestKeyWithDelimiter(t *testing.T) {
+	groups := session.NewKey("ldap.memberOf",
+		session.WithDelimiter(","),
+		session.WithDefault([]string{"everyone"}),
+	)
+	p := newSession(t, nil)
+
+	if v, _ := groups.Get(p); !slices.Equal(v, []string{"everyone"}) {
+		t.Errorf("Get() of missing key = %v, want the default", v)
+	}
+	if err := groups.Set(p, []string{"admins", "users"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if v, _ := p.GetString("ldap.memberOf"); v != "admins,users" {
+		t.Errorf("stored value = %q, want admins,users", v)
+	}
+	if v, _ := groups.Get(p); !slices.Equal(v, []string{"admins", "users"}) {
+		t.Errorf("Get() = %v, want [admins users]", v)
+	}
+}
+
+func TestKeyWithoutStore(t *testing.T) {
+	p := plainProvider{newSession(t, map[string]any{"set": true})}
+	flag := session.NewKey("unset", session.WithDefault(true))
+
+	// Without Store, a zero value is treated as missing.
+	if v, err := flag.Get(p); err != nil || !v {
+		t.Errorf("Get() of missing key = %v, %v, want the default", v, err)
+	}
+	if v, err := session.NewKey[bool]("set").Get(p); err != nil || !v {
+		t.Errorf("Get() = %v, %v, want true", v, err)
+	}
+	if _, err := flag.Exists(p); !errors.Is(err, session.ErrUnsupported) {
+		t.Errorf("Exists() error = %v, want ErrUnsupported", err)
+	}
+	if err := flag.Delete(p); !errors.Is(err, session.ErrUnsupported) {
+		t.Errorf("Delete() error = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,199 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// TraceParentHeader is the name of the W3C traceparent header.
+	TraceParentHeader = "traceparent"
+
+	// TraceStateHeader is the name of the W3C tracestate header.
+	TraceStateHeader = "tracestate"
+)
+
+// Inject sets the traceparent and tracestate headers of h from the span
+// context of the span held by ctx. Nothing is set if the span context is
+// invalid.
+//
+// Example:
+//
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+//	trace.Inject(ctx, req.Header)
+func Inject(ctx context.Context, h http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceParentHeader, FormatTraceParent(sc))
+	if sc.TraceState.Len() > 0 {
+		h.Set(TraceStateHeader, sc.TraceState.String())
+	} else {
+		h.Del(TraceStateHeader)
+	}
+}
+
+// Extract returns a copy of ctx holding the remote span context carried by the
+// traceparent and tracestate headers of h. If the traceparent header is
+// missing or malformed, ctx is returned unchanged. A malformed tracestate
+// header is ignored.
+//
+// Example:
+//
+//	ctx := trace.Extract(req.Context(), req.Header)
+//	ctx, span := tracer.Start(ctx, "callback", trace.WithSpanKind(trace.SpanKindServer))
+//	defer span.End()
+func Extract(ctx context.Context, h http.Header) context.Context {
+	sc, err := ParseTraceParent(h.Get(TraceParentHeader))
+	if err != nil {
+		return ctx
+	}
+	if ts, err := ParseTraceState(strings.Join(h.Values(TraceStateHeader), ",")); err == nil {
+		sc.TraceState = ts
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// FormatTraceParent returns the value of the traceparent header for sc.
+func FormatTraceParent(sc SpanContext) string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{byte(sc.TraceFlags)})
+}
+
+// ParseTraceParent parses the value of a traceparent header. Versions other
+// than 00 are parsed as described by the W3C specification for forward
+// compatibility. An error is returned if the value is malformed.
+func ParseTraceParent(s string) (SpanContext, error) {
+	// version "-" trace-id "-" parent-id "-" trace-flags
+	const size = 2 + 1 + 32 + 1 + 16 + 1 + 2
+	if len(s) < size || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return SpanContext{}, fmt.Errorf("invalid traceparent '%s'", s)
+	}
+	version, err := decodeHex(s[:2])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(s) != size) || (len(s) > size && s[size] != '-') {
+		return SpanContext{}, fmt.Errorf("invalid traceparent '%s'", s)
+	}
+
+	var sc SpanContext
+	traceID, err1 := decodeHex(s[3:35])
+	spanID, err2 := decodeHex(s[36:52])
+	flags, err3 := decodeHex(s[53:55])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return SpanContext{}, fmt.Errorf("invalid traceparent '%s'", s)
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.TraceFlags = TraceFlags(flags[0]) & FlagsSampled
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent '%s'", s)
+	}
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex, as required by the traceparent header.
+func decodeHex(s string) ([]byte, error) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return nil, hex.InvalidByteError(c)
+		}
+	}
+	return hex.DecodeString(s)
+}
+
+// NewTransport returns an http.RoundTripper that starts a client span with
+// tracer for every request, as a child of the span held by the request's
+// context, injects its span context into the request headers and then sends
+// the request with base. If base is nil, http.DefaultTransport is used. If
+// tracer is nil, the span context of the request's context is injected
+// without starting a span.
+//
+// Example:
+//
+//	client := &http.Client{Transport: trace.NewTransport(tracer, nil)}
+func NewTransport(tracer Tracer, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{tracer: tracer, base: base}
+}
+
+// InstrumentClient returns a copy of c whose transport is wrapped with
+// NewTransport. If c is already instrumented, it is returned unchanged. If c is
+// nil, a copy of http.DefaultClient is instrumented.
+//
+// Example:
+//
+//	client := trace.InstrumentClient(tracer, api.HTTP().DefaultClient())
+func InstrumentClient(tracer Tracer, c *http.Client) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	if _, ok := c.Transport.(*transport); ok {
+		return c
+	}
+	cp := *c
+	cp.Transport = NewTransport(tracer, c.Transport)
+	return &cp
+}
+
+type transport struct {
+	tracer Tracer
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	span := Span(nonRecordingSpan{})
+	if t.tracer != nil {
+		ctx, span = t.tracer.Start(ctx, "HTTP "+req.Method,
+			WithSpanKind(SpanKindClient),
+			WithAttributes(
+				String("http.request.method", req.Method),
+				String("url.full", redactURL(req)),
+				String("server.address", req.URL.Hostname()),
+			),
+		)
+		defer span.End()
+	}
+
+	// A RoundTripper must not modify the request, so the headers are injected
+	// into a clone.
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(StatusError, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(StatusError, strconv.Itoa(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// redactURL returns the URL of req as recorded on spans. Credentials and the
+// fragment are removed, and the values of query parameters are replaced with
+// "REDACTED", since they frequently carry tokens, codes or personal data.
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, p := range params {
+			if name, _, ok := strings.Cut(p, "="); ok {
+				params[i] = name + "=REDACTED"
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	return u.String()
+}
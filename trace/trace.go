@@ -0,0 +1,319 @@
+// Package trace lets service extensions create spans around their own work,
+// e.g. LDAP searches or calls to external authorization services, as children
+// of the span of the request being served. Trace context is propagated to
+// other services with the W3C traceparent and tracestate headers.
+//
+// The API mirrors the OpenTelemetry tracing API: trace and span IDs, span
+// kinds and status codes have the same representation, and Tracer.Start,
+// SpanFromContext and ContextWithSpan behave like their OpenTelemetry
+// counterparts, so spans recorded by the Orchestrator are exported together
+// with those of OpenTelemetry instrumented libraries.
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// IsValid reports whether the trace ID is not all zeros.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String returns the trace ID as 32 lowercase hex characters.
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// IsValid reports whether the span ID is not all zeros.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// String returns the span ID as 16 lowercase hex characters.
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// TraceFlags are the W3C trace flags of a span.
+type TraceFlags byte
+
+// FlagsSampled is set if the trace is sampled, i.e. its spans are recorded.
+const FlagsSampled TraceFlags = 0x01
+
+// IsSampled reports whether the sampled flag is set.
+func (f TraceFlags) IsSampled() bool {
+	return f&FlagsSampled != 0
+}
+
+// SpanContext is the part of a span that is propagated to child spans and
+// other services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	TraceFlags TraceFlags
+	TraceState TraceState
+
+	// Remote reports whether the span context was extracted from an incoming
+	// request rather than created locally.
+	Remote bool
+}
+
+// IsValid reports whether the span context has a valid trace and span ID.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled reports whether the trace is sampled.
+func (sc SpanContext) IsSampled() bool {
+	return sc.TraceFlags.IsSampled()
+}
+
+// SpanKind describes the relationship of a span to its parent and children.
+type SpanKind int
+
+const (
+	// SpanKindInternal is the kind of spans representing internal operations.
+	SpanKindInternal SpanKind = iota + 1
+	// SpanKindServer is the kind of spans handling incoming requests.
+	SpanKindServer
+	// SpanKindClient is the kind of spans making outgoing requests, e.g. an LDAP
+	// search or an HTTP request.
+	SpanKindClient
+	// SpanKindProducer is the kind of spans sending asynchronous messages.
+	SpanKindProducer
+	// SpanKindConsumer is the kind of spans receiving asynchronous messages.
+	SpanKindConsumer
+)
+
+// String returns the name of the span kind.
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindInternal:
+		return "internal"
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	case SpanKindProducer:
+		return "producer"
+	case SpanKindConsumer:
+		return "consumer"
+	default:
+		return fmt.Sprintf("SpanKind(%d)", int(k))
+	}
+}
+
+// StatusCode is the status of a span. The zero value means the status is
+// unset.
+type StatusCode int
+
+const (
+	// StatusError records that the operation of the span failed.
+	StatusError StatusCode = iota + 1
+	// StatusOK records that the operation of the span was explicitly marked as
+	// successful.
+	StatusOK
+)
+
+// String returns the name of the status code.
+func (c StatusCode) String() string {
+	switch c {
+	case 0:
+		return "unset"
+	case StatusError:
+		return "error"
+	case StatusOK:
+		return "ok"
+	default:
+		return fmt.Sprintf("StatusCode(%d)", int(c))
+	}
+}
+
+// Attribute is a key-value pair describing a span or event. Values are strings,
+// booleans, int64s or float64s.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string Attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean Attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer Attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Float64 returns a floating point Attribute.
+func Float64(key string, value float64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a single operation within a trace.
+type Span interface {
+	// SpanContext returns the span context of the span.
+	SpanContext() SpanContext
+
+	// IsRecording reports whether the span records its attributes, events and
+	// status. Spans of unsampled traces are not recorded.
+	IsRecording() bool
+
+	// SetName changes the name of the span.
+	SetName(name string)
+
+	// SetAttributes sets attributes of the span, replacing existing attributes
+	// with the same keys.
+	SetAttributes(attrs ...Attribute)
+
+	// AddEvent records an event that happened during the span.
+	AddEvent(name string, attrs ...Attribute)
+
+	// RecordError records err as an "exception" event. It does not change the
+	// status of the span.
+	RecordError(err error, attrs ...Attribute)
+
+	// SetStatus sets the status of the span. The description is only recorded
+	// for StatusError.
+	SetStatus(code StatusCode, description string)
+
+	// End completes the span. Calls after the first are ignored.
+	End()
+}
+
+// Tracer starts spans.
+//
+// Example:
+//
+//	tp, ok := api.(orchestrator.TracerProvider)
+//	if !ok {
+//		return conn.Search(searchReq)
+//	}
+//	ctx, span := tp.Tracer().Start(api.Context(), "ldap.search",
+//		trace.WithSpanKind(trace.SpanKindClient),
+//		trace.WithAttributes(trace.String("ldap.server", server)),
+//	)
+//	defer span.End()
+//	res, err := conn.Search(searchReq)
+//	if err != nil {
+//		span.RecordError(err)
+//		span.SetStatus(trace.StatusError, "search failed")
+//	}
+//	span.SetAttributes(trace.Int("ldap.entries", len(res.Entries)))
+type Tracer interface {
+	// Start starts a span that is a child of the span in ctx, if any, and returns
+	// a context holding the new span. The span must be ended with End.
+	Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span)
+}
+
+// SpanOptions are the options used to start a span.
+type SpanOptions struct {
+	// Kind is the kind of the span. Defaults to SpanKindInternal.
+	Kind SpanKind
+
+	// Attributes are the initial attributes of the span.
+	Attributes []Attribute
+
+	// NewRoot starts a new trace, ignoring the span in the context.
+	NewRoot bool
+
+	// Timestamp is the start time of the span. Defaults to the current time.
+	Timestamp time.Time
+}
+
+// SpanOption is an option used to start a span.
+type SpanOption func(*SpanOptions)
+
+// WithSpanKind configures the kind of a span.
+func WithSpanKind(kind SpanKind) SpanOption {
+	return func(o *SpanOptions) {
+		o.Kind = kind
+	}
+}
+
+// WithAttributes configures the initial attributes of a span.
+func WithAttributes(attrs ...Attribute) SpanOption {
+	return func(o *SpanOptions) {
+		o.Attributes = append(o.Attributes, attrs...)
+	}
+}
+
+// WithNewRoot configures a span to start a new trace.
+func WithNewRoot() SpanOption {
+	return func(o *SpanOptions) {
+		o.NewRoot = true
+	}
+}
+
+// WithTimestamp configures the start time of a span.
+func WithTimestamp(t time.Time) SpanOption {
+	return func(o *SpanOptions) {
+		o.Timestamp = t
+	}
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx holding span.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx holding a non-recording
+// span with the span context sc, marked as remote, e.g. to continue a trace
+// received from another service.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return ContextWithSpan(ctx, nonRecordingSpan{sc: sc})
+}
+
+// SpanFromContext returns the span held by ctx, e.g. the span of the request
+// being served in the context returned by Orchestrator.Context. If ctx holds no
+// span, a non-recording span with an invalid span context is returned.
+//
+// Example:
+//
+//	span := trace.SpanFromContext(api.Context())
+//	span.AddEvent("idp selected", trace.String("idp", idp))
+func SpanFromContext(ctx context.Context) Span {
+	if ctx != nil {
+		if span, ok := ctx.Value(spanKey{}).(Span); ok {
+			return span
+		}
+	}
+	return nonRecordingSpan{}
+}
+
+// SpanContextFromContext returns the span context of the span held by ctx.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	return SpanFromContext(ctx).SpanContext()
+}
+
+// nonRecordingSpan is a Span that only carries a span context.
+type nonRecordingSpan struct {
+	sc SpanContext
+}
+
+func (s nonRecordingSpan) SpanContext() SpanContext      { return s.sc }
+func (nonRecordingSpan) IsRecording() bool               { return false }
+func (nonRecordingSpan) SetName(string)                  {}
+func (nonRecordingSpan) SetAttributes(...Attribute)      {}
+func (nonRecordingSpan) AddEvent(string, ...Attribute)   {}
+func (nonRecordingSpan) RecordError(error, ...Attribute) {}
+func (nonRecordingSpan) SetStatus(StatusCode, string)    {}
+func (nonRecordingSpan) End()                            {}
@@ -0,0 +1,313 @@
+package trace
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Exporter receives ended spans, e.g. to send them to a tracing backend.
+type Exporter interface {
+	// ExportSpan exports an ended span. It is called synchronously by End and
+	// should not block.
+	ExportSpan(s SpanData)
+}
+
+// SpanData is a snapshot of an ended span.
+type SpanData struct {
+	Name        string
+	SpanContext SpanContext
+
+	// Parent is the span context of the parent span. It is invalid for root
+	// spans.
+	Parent SpanContext
+
+	Kind       SpanKind
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes []Attribute
+	Events     []Event
+	Status     Status
+}
+
+// Attribute returns the value of the attribute with the given key. The second
+// return value reports whether the attribute is set.
+func (s SpanData) Attribute(key string) (any, bool) {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Event is an event recorded on a span.
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []Attribute
+}
+
+// Status is the status of a span.
+type Status struct {
+	Code        StatusCode
+	Description string
+}
+
+// TracerOptions are the options used to configure a Tracer created with
+// NewTracer.
+type TracerOptions struct {
+	// Exporters receive ended spans.
+	Exporters []Exporter
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// TracerOption is an option used to configure a Tracer created with NewTracer.
+type TracerOption func(*TracerOptions)
+
+// WithExporter adds an exporter receiving ended spans.
+func WithExporter(e Exporter) TracerOption {
+	return func(o *TracerOptions) {
+		o.Exporters = append(o.Exporters, e)
+	}
+}
+
+// WithClock configures the function used to determine the current time.
+func WithClock(now func() time.Time) TracerOption {
+	return func(o *TracerOptions) {
+		o.Now = now
+	}
+}
+
+// NewTracer creates a Tracer that records spans in memory and passes them to
+// its exporters when they end. New traces are always sampled, while child
+// spans follow the sampling decision of their parent; spans of unsampled
+// traces are not recorded but still propagate their span context.
+//
+// Example:
+//
+//	exporter := trace.NewInMemoryExporter()
+//	tracer := trace.NewTracer(trace.WithExporter(exporter))
+func NewTracer(opts ...TracerOption) Tracer {
+	o := TracerOptions{Now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &tracer{opts: o}
+}
+
+type tracer struct {
+	opts TracerOptions
+}
+
+func (t *tracer) Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span) {
+	o := SpanOptions{Kind: SpanKindInternal}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Timestamp.IsZero() {
+		o.Timestamp = t.opts.Now()
+	}
+
+	var parent SpanContext
+	if !o.NewRoot {
+		parent = SpanContextFromContext(ctx)
+	}
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.TraceFlags = parent.TraceFlags
+		sc.TraceState = parent.TraceState
+	} else {
+		sc.TraceID = newTraceID()
+		sc.TraceFlags = FlagsSampled
+	}
+	if !sc.IsSampled() {
+		return ContextWithSpan(ctx, nonRecordingSpan{sc: sc}), nonRecordingSpan{sc: sc}
+	}
+
+	s := &recordingSpan{
+		tracer: t,
+		data: SpanData{
+			Name:        name,
+			SpanContext: sc,
+			Parent:      parent,
+			Kind:        o.Kind,
+			StartTime:   o.Timestamp,
+		},
+	}
+	s.SetAttributes(o.Attributes...)
+	return ContextWithSpan(ctx, s), s
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		for i := range id {
+			id[i] = byte(rand.Uint32())
+		}
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		for i := range id {
+			id[i] = byte(rand.Uint32())
+		}
+	}
+	return id
+}
+
+type recordingSpan struct {
+	tracer *tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *recordingSpan) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+func (s *recordingSpan) IsRecording() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.ended
+}
+
+func (s *recordingSpan) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Name = name
+	}
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	for _, a := range attrs {
+		i := slices.IndexFunc(s.data.Attributes, func(e Attribute) bool { return e.Key == a.Key })
+		if i >= 0 {
+			s.data.Attributes[i] = a
+		} else {
+			s.data.Attributes = append(s.data.Attributes, a)
+		}
+	}
+}
+
+func (s *recordingSpan) AddEvent(name string, attrs ...Attribute) {
+	s.addEvent(name, attrs)
+}
+
+func (s *recordingSpan) RecordError(err error, attrs ...Attribute) {
+	if err == nil {
+		return
+	}
+	s.addEvent("exception", append([]Attribute{String("exception.message", err.Error())}, attrs...))
+}
+
+func (s *recordingSpan) addEvent(name string, attrs []Attribute) {
+	now := s.tracer.opts.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Events = append(s.data.Events, Event{Name: name, Time: now, Attributes: slices.Clone(attrs)})
+	}
+}
+
+func (s *recordingSpan) SetStatus(code StatusCode, description string) {
+	if code != StatusError {
+		description = ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// An OK status is final and cannot be changed.
+	if !s.ended && s.data.Status.Code != StatusOK {
+		s.data.Status = Status{Code: code, Description: description}
+	}
+}
+
+func (s *recordingSpan) End() {
+	now := s.tracer.opts.Now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = now
+	data := s.data
+	s.mu.Unlock()
+
+	for _, e := range s.tracer.opts.Exporters {
+		e.ExportSpan(data)
+	}
+}
+
+// InMemoryExporter is an Exporter that keeps ended spans in memory, e.g. to
+// assert on the spans created by a service extension in tests.
+//
+// Example:
+//
+//	exporter := trace.NewInMemoryExporter()
+//	api := orchestratortest.New(orchestratortest.WithTracer(
+//		trace.NewTracer(trace.WithExporter(exporter)),
+//	))
+//	// ...
+//	for _, s := range exporter.Spans() {
+//		t.Log(s.Name, s.Status.Code)
+//	}
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates an empty InMemoryExporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan keeps s in memory.
+func (e *InMemoryExporter) ExportSpan(s SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, s)
+}
+
+// Spans returns a copy of all exported spans, in the order they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.spans)
+}
+
+// Span returns the first exported span with the given name. The second return
+// value reports whether such a span was exported.
+func (e *InMemoryExporter) Span(name string) (SpanData, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.spans {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return SpanData{}, false
+}
+
+// Reset discards all exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxTraceStateMembers = 32
+
+var (
+	traceStateKeyRE   = regexp.MustCompile(`^(?:[a-z][_0-9a-z\-*/]{0,255}|[a-z0-9][_0-9a-z\-*/]{0,240}@[a-z][_0-9a-z\-*/]{0,13})$`)
+	traceStateValueRE = regexp.MustCompile(`^[\x20-\x2b\x2d-\x3c\x3e-\x7e]{0,255}[\x21-\x2b\x2d-\x3c\x3e-\x7e]$`)
+)
+
+// TraceState is the vendor-specific trace information carried by the W3C
+// tracestate header. It is immutable; Insert and Delete return modified copies.
+type TraceState struct {
+	members []traceStateMember
+}
+
+type traceStateMember struct {
+	key, value string
+}
+
+// ParseTraceState parses the value of a tracestate header. An error is returned
+// if it is malformed.
+func ParseTraceState(s string) (TraceState, error) {
+	var ts TraceState
+	for _, part := range strings.Split(s, ",") {
+		part = strings.Trim(part, " \t")
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || !traceStateKeyRE.MatchString(key) || !traceStateValueRE.MatchString(value) {
+			return TraceState{}, fmt.Errorf("invalid tracestate member '%s'", part)
+		}
+		if _, exists := ts.lookup(key); exists {
+			return TraceState{}, fmt.Errorf("duplicate tracestate key '%s'", key)
+		}
+		ts.members = append(ts.members, traceStateMember{key: key, value: value})
+	}
+	if len(ts.members) > maxTraceStateMembers {
+		return TraceState{}, fmt.Errorf("tracestate has more than %d members", maxTraceStateMembers)
+	}
+	return ts, nil
+}
+
+// String returns the trace state in the format of the tracestate header.
+func (ts TraceState) String() string {
+	parts := make([]string, len(ts.members))
+	for i, m := range ts.members {
+		parts[i] = m.key + "=" + m.value
+	}
+	return strings.Join(parts, ",")
+}
+
+// Len returns the number of members of the trace state.
+func (ts TraceState) Len() int {
+	return len(ts.members)
+}
+
+// Get returns the value of the member with the given key, or an empty string
+// if there is none.
+func (ts TraceState) Get(key string) string {
+	v, _ := ts.lookup(key)
+	return v
+}
+
+// Insert returns a copy of the trace state with the member moved or added to
+// the front, as required when a vendor updates its value. An error is returned
+// if the key or value is malformed. If the trace state would exceed 32
+// members, the last member is dropped.
+func (ts TraceState) Insert(key, value string) (TraceState, error) {
+	if !traceStateKeyRE.MatchString(key) {
+		return ts, fmt.Errorf("invalid tracestate key '%s'", key)
+	}
+	if !traceStateValueRE.MatchString(value) {
+		return ts, fmt.Errorf("invalid tracestate value for key '%s'", key)
+	}
+	members := make([]traceStateMember, 0, len(ts.members)+1)
+	members = append(members, traceStateMember{key: key, value: value})
+	for _, m := range ts.members {
+		if m.key != key {
+			members = append(members, m)
+		}
+	}
+	if len(members) > maxTraceStateMembers {
+		members = members[:maxTraceStateMembers]
+	}
+	return TraceState{members: members}, nil
+}
+
+// Delete returns a copy of the trace state without the member with the given
+// key.
+func (ts TraceState) Delete(key string) TraceState {
+	members := make([]traceStateMember, 0, len(ts.members))
+	for _, m := range ts.members {
+		if m.key != key {
+			members = append(members, m)
+		}
+	}
+	return TraceState{members: members}
+}
+
+func (ts TraceState) lookup(key string) (string, bool) {
+	for _, m := range ts.members {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+	return "", false
+}